@@ -0,0 +1,48 @@
+// Command leaderboard regenerates the Redis leaderboards from the ratings in
+// Postgres, e.g. after Redis has been flushed.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/leaderboard"
+	"github.com/Adi-ty/chess/internal/rating"
+	"github.com/Adi-ty/chess/internal/store"
+)
+
+func main() {
+	pgDB, err := store.Open()
+	if err != nil {
+		fmt.Println("Error opening database:", err)
+		os.Exit(1)
+	}
+	defer pgDB.Close()
+
+	redisDB, err := store.OpenRedis()
+	if err != nil {
+		fmt.Println("Error opening Redis:", err)
+		os.Exit(1)
+	}
+	defer redisDB.Close()
+
+	ctx := context.Background()
+	ratingStore := store.NewPostgresRatingStore(pgDB)
+	board := leaderboard.New(redisDB)
+	since := time.Now().Add(-leaderboard.InactiveAfter)
+
+	for _, category := range rating.Categories {
+		ratings, err := ratingStore.ListActiveRatings(ctx, string(category), since)
+		if err != nil {
+			fmt.Printf("Error loading %s ratings: %v\n", category, err)
+			os.Exit(1)
+		}
+		if err := board.Rebuild(ctx, string(category), ratings); err != nil {
+			fmt.Printf("Error rebuilding %s leaderboard: %v\n", category, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rebuilt %s leaderboard from %d ratings\n", category, len(ratings))
+	}
+}
@@ -0,0 +1,92 @@
+// Command puzzles imports puzzles from a CSV file into Postgres. Puzzles
+// already imported are updated, and those whose solution does not play out
+// from their position are skipped.
+//
+// Usage:
+//
+//	puzzles [-setup-move] puzzles.csv
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Adi-ty/chess/internal/puzzle"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/migrations"
+)
+
+func main() {
+	setupMove := flag.Bool("setup-move", false, "play the first move of each solution to reach the puzzle, as in the Lichess puzzle database")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: puzzles [-setup-move] puzzles.csv")
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Println("Error opening puzzles:", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	pgDB, err := store.Open()
+	if err != nil {
+		fmt.Println("Error opening database:", err)
+		os.Exit(1)
+	}
+	defer pgDB.Close()
+
+	if err := store.MigrateFS(pgDB, migrations.FS, "."); err != nil {
+		fmt.Println("Error migrating database:", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	puzzleStore := store.NewPostgresPuzzleStore(pgDB)
+	imported, skipped := 0, 0
+
+	skip := func(line int, err error) {
+		fmt.Printf("Skipping line %d: %v\n", line, err)
+		skipped++
+	}
+	err = puzzle.ReadCSV(file, func(rec puzzle.Record) error {
+		if *setupMove && len(rec.Solution) > 0 {
+			fen, err := puzzle.Advance(rec.FEN, rec.Solution[0])
+			if err != nil {
+				fmt.Printf("Skipping puzzle %s: %v\n", rec.ID, err)
+				skipped++
+				return nil
+			}
+			rec.FEN, rec.Solution = fen, rec.Solution[1:]
+		}
+		if err := puzzle.Validate(rec.FEN, rec.Solution); err != nil {
+			fmt.Printf("Skipping puzzle %s: %v\n", rec.ID, err)
+			skipped++
+			return nil
+		}
+
+		deviation := rec.Deviation
+		if deviation <= 0 {
+			deviation = store.DefaultPuzzleDeviation
+		}
+		imported++
+		return puzzleStore.UpsertPuzzle(ctx, &store.Puzzle{
+			ID:        rec.ID,
+			FEN:       rec.FEN,
+			Solution:  rec.Solution,
+			Rating:    rec.Rating,
+			Deviation: deviation,
+			Themes:    rec.Themes,
+		})
+	}, skip)
+	if err != nil {
+		fmt.Println("Error importing puzzles:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Imported %d puzzles, skipped %d\n", imported, skipped)
+}
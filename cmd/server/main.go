@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Adi-ty/chess/internal/app"
 	"github.com/Adi-ty/chess/internal/auth"
@@ -24,8 +30,28 @@ func main() {
 		Addr: ":8080",
 		Handler: handler,
 	}
+	defer app.Close()
+
+	// Stop taking requests on SIGINT or SIGTERM, and release resources once
+	// the requests in flight are done
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Println("Error shutting down server:", err)
+		}
+	}()
+
 	err = server.ListenAndServe()
-	if err != nil {
+	if !errors.Is(err, http.ErrServerClosed) {
 		fmt.Println("Error starting server:", err)
+		return
 	}
+	<-shutdown
+	app.Logger.Println("Server Stopped")
 }
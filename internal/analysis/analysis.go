@@ -0,0 +1,182 @@
+// Package analysis reviews finished games with an engine: it scores every
+// position, grades each move by how much it lost against the engine's choice
+// and sums that up per player.
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/variant"
+	"github.com/notnil/chess"
+)
+
+// Move classifications, by centipawn loss.
+const (
+	ClassBest       = "best"
+	ClassGood       = "good"
+	ClassInaccuracy = "inaccuracy"
+	ClassMistake    = "mistake"
+	ClassBlunder    = "blunder"
+)
+
+const (
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+
+	// evalCap bounds evaluations before losses are taken, so that choosing a
+	// slower mate or a won endgame over a mate does not count as a blunder.
+	evalCap = 1000
+)
+
+// Analyse replays moves of a game of v from fen and evaluates every position
+// along the way.
+func Analyse(ctx context.Context, ev Evaluator, v variant.Variant, fen string, moves []string) (*store.GameAnalysis, error) {
+	board, err := variant.New(v, fen)
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make([]Evaluation, 0, len(moves)+1)
+	turns := make([]chess.Color, 0, len(moves))
+	for i := 0; ; i++ {
+		eval, err := evaluate(ctx, ev, board.Game())
+		if err != nil {
+			return nil, fmt.Errorf("evaluating ply %d: %w", i, err)
+		}
+		evals = append(evals, eval)
+		if i == len(moves) {
+			break
+		}
+
+		turns = append(turns, board.Position().Turn())
+		if err := board.Move(moves[i]); err != nil {
+			return nil, fmt.Errorf("replaying ply %d: %w", i+1, err)
+		}
+	}
+
+	result := &store.GameAnalysis{Moves: make([]store.MoveAnalysis, 0, len(moves))}
+	var white, black tally
+	for i, played := range moves {
+		before, after := evals[i], evals[i+1]
+		moverIsWhite := turns[i] == chess.White
+
+		// Both scores from the mover's point of view.
+		best := capped(before)
+		got := -capped(after)
+		loss := max(best-got, 0)
+		if played == before.BestMove {
+			loss = 0
+		}
+
+		m := store.MoveAnalysis{
+			Ply:            i + 1,
+			Color:          "black",
+			Move:           played,
+			EvalCP:         after.CP,
+			EvalMate:       after.Mate,
+			BestMove:       before.BestMove,
+			CPLoss:         loss,
+			Classification: classify(loss),
+		}
+		// Stored evaluations are from White's point of view.
+		if moverIsWhite {
+			m.Color = "white"
+			m.EvalCP, m.EvalMate = -m.EvalCP, -m.EvalMate
+		}
+		result.Moves = append(result.Moves, m)
+
+		t := &black
+		if moverIsWhite {
+			t = &white
+		}
+		t.add(m, moveAccuracy(winPercent(best), winPercent(got)))
+	}
+	result.White = white.summary()
+	result.Black = black.summary()
+
+	return result, nil
+}
+
+// evaluate asks ev about the current position, answering for finished
+// positions itself since engines have nothing to search there.
+func evaluate(ctx context.Context, ev Evaluator, board *chess.Game) (Evaluation, error) {
+	switch board.Position().Status() {
+	case chess.Checkmate:
+		return Evaluation{CP: -evalCap}, nil
+	case chess.Stalemate:
+		return Evaluation{}, nil
+	}
+	return ev.Evaluate(ctx, board)
+}
+
+// capped turns an evaluation into centipawns within evalCap.
+func capped(e Evaluation) int {
+	switch {
+	case e.Mate > 0:
+		return evalCap
+	case e.Mate < 0:
+		return -evalCap
+	}
+	return min(max(e.CP, -evalCap), evalCap)
+}
+
+func classify(loss int) string {
+	switch {
+	case loss >= blunderLoss:
+		return ClassBlunder
+	case loss >= mistakeLoss:
+		return ClassMistake
+	case loss >= inaccuracyLoss:
+		return ClassInaccuracy
+	case loss == 0:
+		return ClassBest
+	}
+	return ClassGood
+}
+
+// winPercent is the mover's chance of winning, in percent, for an
+// evaluation in centipawns.
+func winPercent(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy maps the drop in winning chances caused by a move onto a 0 to
+// 100 scale, so a move that keeps the evaluation scores 100.
+func moveAccuracy(before, after float64) float64 {
+	acc := 103.1668*math.Exp(-0.04354*(before-after)) - 3.1669
+	return min(max(acc, 0), 100)
+}
+
+type tally struct {
+	moves    int
+	loss     int
+	accuracy float64
+	counts   map[string]int
+}
+
+func (t *tally) add(m store.MoveAnalysis, accuracy float64) {
+	if t.counts == nil {
+		t.counts = make(map[string]int)
+	}
+	t.moves++
+	t.loss += m.CPLoss
+	t.accuracy += accuracy
+	t.counts[m.Classification]++
+}
+
+func (t *tally) summary() store.PlayerAnalysis {
+	if t.moves == 0 {
+		return store.PlayerAnalysis{}
+	}
+	return store.PlayerAnalysis{
+		ACPL:         int(math.Round(float64(t.loss) / float64(t.moves))),
+		Accuracy:     math.Round(t.accuracy/float64(t.moves)*10) / 10,
+		Inaccuracies: t.counts[ClassInaccuracy],
+		Mistakes:     t.counts[ClassMistake],
+		Blunders:     t.counts[ClassBlunder],
+	}
+}
@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"context"
+
+	"github.com/Adi-ty/chess/internal/engine"
+	"github.com/Adi-ty/chess/internal/uci"
+	"github.com/notnil/chess"
+)
+
+// Evaluation is an engine's verdict on a position from the side to move's
+// point of view. Mate, when non-zero, is the number of moves to mate and is
+// negative when the side to move is getting mated.
+type Evaluation struct {
+	CP       int
+	Mate     int
+	BestMove string
+}
+
+// Evaluator scores the current position of a board.
+type Evaluator interface {
+	Evaluate(ctx context.Context, board *chess.Game) (Evaluation, error)
+}
+
+// EngineEvaluator evaluates with the built-in engine.
+type EngineEvaluator struct {
+	engine *engine.Engine
+}
+
+func NewEngineEvaluator(e *engine.Engine) *EngineEvaluator {
+	return &EngineEvaluator{engine: e}
+}
+
+func (e *EngineEvaluator) Evaluate(ctx context.Context, board *chess.Game) (Evaluation, error) {
+	mv, score, err := e.engine.Search(ctx, board)
+	if err != nil {
+		return Evaluation{}, err
+	}
+	eval := Evaluation{CP: score, BestMove: mv.String()}
+	if mate, ok := engine.MateIn(score); ok {
+		eval.CP, eval.Mate = 0, mate
+	}
+	return eval, nil
+}
+
+// UCIEvaluator evaluates with a pooled external engine.
+type UCIEvaluator struct {
+	pool   *uci.Pool
+	limits uci.Limits
+}
+
+func NewUCIEvaluator(pool *uci.Pool, limits uci.Limits) *UCIEvaluator {
+	return &UCIEvaluator{pool: pool, limits: limits}
+}
+
+func (e *UCIEvaluator) Evaluate(ctx context.Context, board *chess.Game) (Evaluation, error) {
+	result, err := e.pool.Search(ctx, uci.PositionOf(board), e.limits)
+	if err != nil {
+		return Evaluation{}, err
+	}
+	return Evaluation{
+		CP:       result.Info.Score.CP,
+		Mate:     result.Info.Score.Mate,
+		BestMove: result.BestMove,
+	}, nil
+}
@@ -17,6 +17,7 @@ type AuthHandler struct {
 	googleOAuth *auth.GoogleOAuth
 	jwtService *auth.JWTService
 	userStore store.UserStore
+	ratingStore store.RatingStore
 }
 
 func NewAuthHandler(
@@ -24,12 +25,14 @@ func NewAuthHandler(
 	googleOAuth *auth.GoogleOAuth,
 	jwtService *auth.JWTService,
 	userStore store.UserStore,
+	ratingStore store.RatingStore,
 ) *AuthHandler {
 	return &AuthHandler{
 		logger: logger,
 		googleOAuth: googleOAuth,
 		jwtService: jwtService,
 		userStore: userStore,
+		ratingStore: ratingStore,
 	}
 }
 
@@ -138,8 +141,16 @@ func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ratings, err := h.ratingStore.GetRatingsByUserID(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Printf("Failed to get ratings: %v", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(struct {
+		*store.User
+		Ratings []*store.Rating `json:"ratings"`
+	}{user, ratings})
 }
 
 func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Adi-ty/chess/internal/auth"
+	"github.com/Adi-ty/chess/internal/gamemanager"
+)
+
+type ChallengeHandler struct {
+	logger      *log.Logger
+	gamemanager *gamemanager.GameManager
+	frontendURL string
+}
+
+func NewChallengeHandler(logger *log.Logger, gm *gamemanager.GameManager, frontendURL string) *ChallengeHandler {
+	return &ChallengeHandler{
+		logger:      logger,
+		gamemanager: gm,
+		frontendURL: frontendURL,
+	}
+}
+
+type createChallengeRequest struct {
+	OpponentID  string `json:"opponent_id"`
+	Color       string `json:"color"`
+	TimeControl string `json:"time_control"`
+	Rated       bool   `json:"rated"`
+	Variant     string `json:"variant"`
+	FEN         string `json:"fen"`
+}
+
+type challengeResponse struct {
+	*gamemanager.Challenge
+	URL string `json:"url"`
+}
+
+func (h *ChallengeHandler) HandleCreateChallenge(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req createChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	challenge, err := h.gamemanager.CreateChallenge(userCtx.UserID, gamemanager.ChallengeOptions{
+		OpponentID:  req.OpponentID,
+		Color:       req.Color,
+		TimeControl: req.TimeControl,
+		Rated:       req.Rated,
+		Variant:     req.Variant,
+		FEN:         req.FEN,
+	})
+	if err != nil {
+		writeError(w, challengeErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, h.response(challenge))
+}
+
+// HandleGetChallenge serves an open challenge to anyone with its link, and
+// a direct one only to its two players.
+func (h *ChallengeHandler) HandleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	challenge, err := h.gamemanager.GetChallenge(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if !challenge.Open() {
+		userCtx := auth.GetUserFromContext(r.Context())
+		if userCtx == nil || (userCtx.UserID != challenge.ChallengerID && userCtx.UserID != challenge.OpponentID) {
+			writeError(w, http.StatusNotFound, gamemanager.ErrChallengeNotFound.Error())
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, h.response(challenge))
+}
+
+func (h *ChallengeHandler) HandleAcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	game, err := h.gamemanager.AcceptChallenge(userCtx.UserID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, challengeErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"game_id": game.ID})
+}
+
+func (h *ChallengeHandler) HandleDeclineChallenge(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.gamemanager.DeclineChallenge(userCtx.UserID, r.PathValue("id")); err != nil {
+		writeError(w, challengeErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "challenge declined"})
+}
+
+func (h *ChallengeHandler) response(challenge *gamemanager.Challenge) challengeResponse {
+	return challengeResponse{
+		Challenge: challenge,
+		URL:       h.frontendURL + "/challenge/" + challenge.ID,
+	}
+}
+
+func challengeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gamemanager.ErrChallengeNotFound), errors.Is(err, gamemanager.ErrOpponentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, gamemanager.ErrNotYourChallenge):
+		return http.StatusForbidden
+	case errors.Is(err, gamemanager.ErrPlayerBusy), errors.Is(err, gamemanager.ErrPlayerOffline):
+		return http.StatusConflict
+	case errors.Is(err, gamemanager.ErrChallengeFailed):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
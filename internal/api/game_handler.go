@@ -0,0 +1,447 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/auth"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/variant"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type GameHandler struct {
+	logger        *log.Logger
+	gameStore     store.GameStore
+	userStore     store.UserStore
+	analysisStore store.AnalysisStore
+}
+
+func NewGameHandler(logger *log.Logger, gameStore store.GameStore, userStore store.UserStore, analysisStore store.AnalysisStore) *GameHandler {
+	return &GameHandler{
+		logger:        logger,
+		gameStore:     gameStore,
+		userStore:     userStore,
+		analysisStore: analysisStore,
+	}
+}
+
+type playerResponse struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+type gameResponse struct {
+	ID               string          `json:"id"`
+	White            *playerResponse `json:"white"`
+	Black            *playerResponse `json:"black"`
+	Status           string          `json:"status"`
+	Outcome          string          `json:"outcome,omitempty"`
+	Method           string          `json:"method,omitempty"`
+	BaseSeconds      int             `json:"base_seconds"`
+	IncrementSeconds int             `json:"increment_seconds"`
+	Rated            bool            `json:"rated"`
+	RatingCategory   string          `json:"rating_category,omitempty"`
+	SeriesID         string          `json:"series_id,omitempty"`
+	RematchOf        string          `json:"rematch_of,omitempty"`
+	Variant          string          `json:"variant"`
+	InitialFEN       string          `json:"initial_fen,omitempty"`
+	DaysPerMove      int             `json:"days_per_move,omitempty"`
+	MoveDeadline     *time.Time      `json:"move_deadline,omitempty"`
+	StartedAt        string          `json:"started_at"`
+	EndedAt          string          `json:"ended_at,omitempty"`
+}
+
+type moveResponse struct {
+	MoveNumber  int    `json:"move_number"`
+	Move        string `json:"move"`
+	WhiteTimeMs int64  `json:"white_time_ms"`
+	BlackTimeMs int64  `json:"black_time_ms"`
+	PlayedAt    string `json:"played_at"`
+}
+
+type gameDetailResponse struct {
+	gameResponse
+	Moves []moveResponse `json:"moves"`
+	FEN   string         `json:"fen"`
+}
+
+type activeGameResponse struct {
+	gameResponse
+	FEN  string `json:"fen"`
+	Turn string `json:"turn"`
+}
+
+type gameListResponse struct {
+	Games      []gameResponse `json:"games"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// HandleGetGame serves /games/{id}. The mux cannot match a suffix inside a
+// path segment, so /games/{id}.pgn is routed here as well.
+func (h *GameHandler) HandleGetGame(w http.ResponseWriter, r *http.Request) {
+	id, isPGN := strings.CutSuffix(r.PathValue("id"), ".pgn")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "game not found")
+		return
+	}
+	if isPGN {
+		h.servePGN(w, r, id)
+		return
+	}
+
+	game, err := h.gameStore.GetGameByID(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get game %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get game")
+		return
+	}
+	if game == nil {
+		writeError(w, http.StatusNotFound, "game not found")
+		return
+	}
+
+	moves, err := h.gameStore.GetMovesByGameID(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get moves for game %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get game")
+		return
+	}
+
+	v, err := variant.Parse(game.Variant)
+	if err != nil {
+		v = variant.Standard
+	}
+	board, err := variant.New(v, game.InitialFEN)
+	if err != nil {
+		h.logger.Printf("Failed to set up game %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get game")
+		return
+	}
+	detail := gameDetailResponse{
+		gameResponse: h.gameResponse(r.Context(), game, map[string]*playerResponse{}),
+		Moves:        make([]moveResponse, 0, len(moves)),
+	}
+	for _, m := range moves {
+		if err := board.Move(m.Move); err != nil {
+			h.logger.Printf("Failed to replay move %s of game %s", m.Move, id)
+			break
+		}
+		detail.Moves = append(detail.Moves, moveResponse{
+			MoveNumber:  m.MoveNumber,
+			Move:        m.Move,
+			WhiteTimeMs: m.WhiteTimeMs,
+			BlackTimeMs: m.BlackTimeMs,
+			PlayedAt:    time.UnixMicro(int64(m.CreatedAt * 1e6)).UTC().Format(time.RFC3339Nano),
+		})
+	}
+	detail.FEN = board.FEN()
+
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (h *GameHandler) servePGN(w http.ResponseWriter, r *http.Request, id string) {
+	pgn, err := h.gameStore.GetGamePGN(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get PGN for game %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get game")
+		return
+	}
+	if pgn == "" {
+		writeError(w, http.StatusNotFound, "PGN not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+id+`.pgn"`)
+	io.WriteString(w, pgn)
+}
+
+// HandleGetAnalysis serves the engine analysis of a finished game. While the
+// analysis is still being computed it answers 202 with just the status.
+func (h *GameHandler) HandleGetAnalysis(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "game not found")
+		return
+	}
+
+	analysis, err := h.analysisStore.GetGameAnalysis(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get analysis for game %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get analysis")
+		return
+	}
+	if analysis == nil {
+		writeError(w, http.StatusNotFound, "analysis not available")
+		return
+	}
+
+	status := http.StatusOK
+	if analysis.Status == store.AnalysisPending {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, analysis)
+}
+
+// HandleListGames serves a page of a user's games, newest first. The response
+// carries a next_cursor to pass back as ?cursor= for the following page.
+func (h *GameHandler) HandleListGames(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	filter, err := parseGameFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.UserID = userID
+	pageSize := filter.Limit
+	filter.Limit++
+
+	games, err := h.gameStore.ListGamesByUserID(r.Context(), filter)
+	if err != nil {
+		h.logger.Printf("Failed to list games for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, "failed to list games")
+		return
+	}
+
+	resp := gameListResponse{Games: make([]gameResponse, 0, len(games))}
+	if len(games) > pageSize {
+		games = games[:pageSize]
+		last := games[pageSize-1]
+		resp.NextCursor = encodeCursor(last.StartedAt, last.ID)
+	}
+
+	players := map[string]*playerResponse{}
+	for _, game := range games {
+		resp.Games = append(resp.Games, h.gameResponse(r.Context(), game, players))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleListActiveGames serves the caller's ongoing correspondence games,
+// oldest first, each with its current position and the side to move.
+func (h *GameHandler) HandleListActiveGames(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	games, err := h.gameStore.ListActiveGamesByUserID(r.Context(), userCtx.UserID)
+	if err != nil {
+		h.logger.Printf("Failed to list active games for user %s: %v", userCtx.UserID, err)
+		writeError(w, http.StatusInternalServerError, "failed to list games")
+		return
+	}
+
+	resp := make([]activeGameResponse, 0, len(games))
+	players := map[string]*playerResponse{}
+	for _, game := range games {
+		if game.DaysPerMove == 0 {
+			continue
+		}
+		board, err := h.replay(r.Context(), game)
+		if err != nil {
+			h.logger.Printf("Failed to replay game %s: %v", game.ID, err)
+			continue
+		}
+		resp = append(resp, activeGameResponse{
+			gameResponse: h.gameResponse(r.Context(), game, players),
+			FEN:          board.FEN(),
+			Turn:         strings.ToLower(board.Position().Turn().Name()),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"games": resp})
+}
+
+// replay sets up a game's board and plays its stored moves on it.
+func (h *GameHandler) replay(ctx context.Context, game *store.Game) (*variant.Board, error) {
+	moves, err := h.gameStore.GetMovesByGameID(ctx, game.ID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := variant.Parse(game.Variant)
+	if err != nil {
+		v = variant.Standard
+	}
+	played := make([]string, 0, len(moves))
+	for _, m := range moves {
+		played = append(played, m.Move)
+	}
+	return variant.Replay(v, game.InitialFEN, played)
+}
+
+func parseGameFilter(r *http.Request) (store.GameFilter, error) {
+	q := r.URL.Query()
+	filter := store.GameFilter{
+		Status:     q.Get("status"),
+		Color:      q.Get("color"),
+		Outcome:    q.Get("outcome"),
+		OpponentID: q.Get("opponent"),
+		Limit:      defaultPageSize,
+	}
+
+	switch filter.Status {
+	case "", "in_progress", "completed", "abandoned", "aborted":
+	default:
+		return filter, errors.New("invalid status")
+	}
+	switch filter.Color {
+	case "", "white", "black":
+	default:
+		return filter, errors.New("color must be white or black")
+	}
+	switch filter.Outcome {
+	case "", "win", "loss", "draw":
+	default:
+		return filter, errors.New("outcome must be win, loss or draw")
+	}
+	if filter.OpponentID != "" {
+		if _, err := uuid.Parse(filter.OpponentID); err != nil {
+			return filter, errors.New("invalid opponent")
+		}
+	}
+
+	var err error
+	if filter.Since, err = parseDate(q.Get("since")); err != nil {
+		return filter, errors.New("invalid since date")
+	}
+	if filter.Until, err = parseDate(q.Get("until")); err != nil {
+		return filter, errors.New("invalid until date")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return filter, errors.New("limit must be between 1 and 100")
+		}
+		filter.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		filter.AfterStartedAt, filter.AfterID, err = decodeCursor(v)
+		if err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+	}
+
+	return filter, nil
+}
+
+// parseDate accepts either an RFC 3339 timestamp or a plain date.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func encodeCursor(startedAt string, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(startedAt + "," + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	startedAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return time.Time{}, "", err
+	}
+	t, err := time.Parse(time.RFC3339Nano, startedAt)
+	return t, id, err
+}
+
+func (h *GameHandler) gameResponse(ctx context.Context, game *store.Game, players map[string]*playerResponse) gameResponse {
+	return gameResponse{
+		ID:               game.ID,
+		White:            h.player(ctx, game.WhiteUserID, players),
+		Black:            h.player(ctx, game.BlackUserID, players),
+		Status:           game.Status,
+		Outcome:          game.Outcome,
+		Method:           game.Method,
+		BaseSeconds:      game.BaseSeconds,
+		IncrementSeconds: game.IncrementSeconds,
+		Rated:            game.Rated,
+		RatingCategory:   game.RatingCategory,
+		SeriesID:         game.SeriesID,
+		RematchOf:        game.RematchOf,
+		Variant:          game.Variant,
+		InitialFEN:       game.InitialFEN,
+		DaysPerMove:      game.DaysPerMove,
+		MoveDeadline:     game.MoveDeadline,
+		StartedAt:        game.StartedAt,
+		EndedAt:          game.EndedAt.String,
+	}
+}
+
+// player looks up a player's public details, caching them in players so a
+// page of games against the same opponent costs one lookup.
+func (h *GameHandler) player(ctx context.Context, userID string, players map[string]*playerResponse) *playerResponse {
+	if p, ok := players[userID]; ok {
+		return p
+	}
+	p := &playerResponse{ID: userID}
+	if user, err := h.userStore.GetUserByID(ctx, userID); err == nil {
+		p.DisplayName = user.DisplayName
+		p.AvatarURL = user.AvatarURL
+	} else if !errors.Is(err, store.ErrUserNotFound) {
+		h.logger.Printf("Failed to get user %s: %v", userID, err)
+	}
+	players[userID] = p
+	return p
+}
+
+// HandleExportPGN streams every finished game of a user as one PGN file.
+func (h *GameHandler) HandleExportPGN(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+userID+`.pgn"`)
+
+	flusher, _ := w.(http.Flusher)
+	err := h.gameStore.ExportPGNByUserID(r.Context(), userID, func(pgn string) error {
+		if _, err := io.WriteString(w, pgn+"\n"); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, so all we can do is cut the export short.
+		h.logger.Printf("Failed to export PGN for user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Adi-ty/chess/internal/auth"
+	"github.com/Adi-ty/chess/internal/leaderboard"
+	"github.com/Adi-ty/chess/internal/rating"
+	"github.com/Adi-ty/chess/internal/store"
+)
+
+const (
+	defaultLeaderboardSize = 50
+	// maxLeaderboardPage keeps the offset of a page well within range. At
+	// the largest page size it reaches the millionth player.
+	maxLeaderboardPage = 10000
+)
+
+type LeaderboardHandler struct {
+	logger      *log.Logger
+	leaderboard *leaderboard.Leaderboard
+	userStore   store.UserStore
+}
+
+func NewLeaderboardHandler(logger *log.Logger, board *leaderboard.Leaderboard, userStore store.UserStore) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		logger:      logger,
+		leaderboard: board,
+		userStore:   userStore,
+	}
+}
+
+type leaderboardEntry struct {
+	Rank        int64  `json:"rank"`
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Rating      int    `json:"rating"`
+}
+
+type leaderboardResponse struct {
+	Category string             `json:"category"`
+	Page     int                `json:"page"`
+	Total    int64              `json:"total"`
+	Entries  []leaderboardEntry `json:"entries"`
+	Me       *leaderboardEntry  `json:"me,omitempty"`
+}
+
+// HandleGetLeaderboard serves one page of a category's leaderboard. Logged-in
+// callers also get their own rank, if they are ranked.
+func (h *LeaderboardHandler) HandleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	category, ok := rating.ParseCategory(r.PathValue("category"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown rating category")
+		return
+	}
+
+	var err error
+
+	page, limit := 1, defaultLeaderboardSize
+	if v := r.URL.Query().Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 || page > maxLeaderboardPage {
+			writeError(w, http.StatusBadRequest, "page must be between 1 and 10000")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxPageSize {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+	}
+
+	entries, total, err := h.leaderboard.Page(r.Context(), string(category), int64((page-1)*limit), int64(limit))
+	if err != nil {
+		h.logger.Printf("Failed to read %s leaderboard: %v", category, err)
+		writeError(w, http.StatusInternalServerError, "failed to get leaderboard")
+		return
+	}
+
+	var me *leaderboard.Entry
+	if userCtx := auth.GetUserFromContext(r.Context()); userCtx != nil {
+		if me, err = h.leaderboard.Rank(r.Context(), string(category), userCtx.UserID); err != nil {
+			h.logger.Printf("Failed to get %s rank of %s: %v", category, userCtx.UserID, err)
+		}
+	}
+
+	ids := make([]string, 0, len(entries)+1)
+	for _, e := range entries {
+		ids = append(ids, e.UserID)
+	}
+	if me != nil {
+		ids = append(ids, me.UserID)
+	}
+	users, err := h.userStore.GetUsersByIDs(r.Context(), ids)
+	if err != nil {
+		h.logger.Printf("Failed to get leaderboard users: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to get leaderboard")
+		return
+	}
+
+	resp := leaderboardResponse{
+		Category: string(category),
+		Page:     page,
+		Total:    total,
+		Entries:  make([]leaderboardEntry, len(entries)),
+	}
+	for i, e := range entries {
+		resp.Entries[i] = newLeaderboardEntry(e, users)
+	}
+	if me != nil {
+		entry := newLeaderboardEntry(*me, users)
+		resp.Me = &entry
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func newLeaderboardEntry(e leaderboard.Entry, users map[string]*store.User) leaderboardEntry {
+	entry := leaderboardEntry{
+		Rank:   e.Rank,
+		UserID: e.UserID,
+		Rating: int(math.Round(e.Rating)),
+	}
+	if user, ok := users[e.UserID]; ok {
+		entry.DisplayName = user.DisplayName
+		entry.AvatarURL = user.AvatarURL
+	}
+	return entry
+}
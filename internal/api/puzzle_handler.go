@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Adi-ty/chess/internal/auth"
+	"github.com/Adi-ty/chess/internal/puzzle"
+	"github.com/Adi-ty/chess/internal/rating"
+	"github.com/Adi-ty/chess/internal/store"
+)
+
+type PuzzleHandler struct {
+	logger      *log.Logger
+	puzzleStore store.PuzzleStore
+	ratingStore store.RatingStore
+}
+
+func NewPuzzleHandler(logger *log.Logger, puzzleStore store.PuzzleStore, ratingStore store.RatingStore) *PuzzleHandler {
+	return &PuzzleHandler{
+		logger:      logger,
+		puzzleStore: puzzleStore,
+		ratingStore: ratingStore,
+	}
+}
+
+type puzzleResponse struct {
+	*store.Puzzle
+	// Color is the side the solver plays.
+	Color string `json:"color"`
+}
+
+type solvePuzzleRequest struct {
+	Moves []string `json:"moves"`
+}
+
+// solvePuzzleResponse carries the opponent's reply while the solution goes
+// on, and once the attempt is over the solution and the new rating.
+type solvePuzzleResponse struct {
+	Correct  bool                 `json:"correct"`
+	Solved   bool                 `json:"solved"`
+	Reply    string               `json:"reply,omitempty"`
+	Solution []string             `json:"solution,omitempty"`
+	Attempt  *store.PuzzleAttempt `json:"attempt,omitempty"`
+}
+
+// HandleNextPuzzle serves a puzzle the caller has not tried yet, close to
+// their puzzle rating and with the ?theme= if one is given. The puzzles
+// served to them before that they have not solved count as failed.
+func (h *PuzzleHandler) HandleNextPuzzle(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.puzzleStore.FailServedPuzzles(r.Context(), userCtx.UserID); err != nil {
+		h.logger.Printf("Failed to rate unsolved puzzles of %s: %v", userCtx.UserID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get puzzle")
+		return
+	}
+	rt, err := h.ratingStore.GetRating(r.Context(), userCtx.UserID, string(rating.Puzzle))
+	if err != nil {
+		h.logger.Printf("Failed to get puzzle rating for %s: %v", userCtx.UserID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get puzzle")
+		return
+	}
+
+	theme := strings.TrimSpace(r.URL.Query().Get("theme"))
+	p, err := h.puzzleStore.NextPuzzle(r.Context(), userCtx.UserID, rt.Rating, theme)
+	if err != nil {
+		h.logger.Printf("Failed to pick a puzzle for %s: %v", userCtx.UserID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get puzzle")
+		return
+	}
+	if p == nil {
+		writeError(w, http.StatusNotFound, "no puzzles left")
+		return
+	}
+	if err := h.puzzleStore.ServePuzzle(r.Context(), userCtx.UserID, p.ID); err != nil {
+		h.logger.Printf("Failed to serve puzzle %s to %s: %v", p.ID, userCtx.UserID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get puzzle")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"puzzle": puzzleResponse{Puzzle: p, Color: sideToMove(p.FEN)},
+		"rating": rt,
+	})
+}
+
+// HandleSolvePuzzle checks the caller's moves so far, without the
+// opponent's replies. While they are right and the solution goes on it
+// answers with the opponent's reply. A wrong move fails the puzzle and the
+// last right one solves it; either way the attempt is rated and the
+// puzzle cannot be tried again.
+func (h *PuzzleHandler) HandleSolvePuzzle(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req solvePuzzleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	id := r.PathValue("id")
+	p, err := h.puzzleStore.GetPuzzle(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get puzzle %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to check solution")
+		return
+	}
+	if p == nil {
+		writeError(w, http.StatusNotFound, "puzzle not found")
+		return
+	}
+
+	attempt, err := h.puzzleStore.GetPuzzleAttempt(r.Context(), userCtx.UserID, id)
+	if err != nil {
+		h.logger.Printf("Failed to get attempt of %s at puzzle %s: %v", userCtx.UserID, id, err)
+		writeError(w, http.StatusInternalServerError, "failed to check solution")
+		return
+	}
+	if attempt != nil && attempt.Finished {
+		writeError(w, http.StatusConflict, store.ErrPuzzleAttempted.Error())
+		return
+	}
+
+	verdict, err := puzzle.Check(p.FEN, p.Solution, req.Moves)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if verdict.Correct && !verdict.Solved {
+		writeJSON(w, http.StatusOK, solvePuzzleResponse{Correct: true, Reply: verdict.Reply})
+		return
+	}
+
+	attempt, err = h.puzzleStore.RecordPuzzleAttempt(r.Context(), userCtx.UserID, id, verdict.Solved)
+	if errors.Is(err, store.ErrPuzzleAttempted) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Printf("Failed to record attempt of %s at puzzle %s: %v", userCtx.UserID, id, err)
+		writeError(w, http.StatusInternalServerError, "failed to check solution")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, solvePuzzleResponse{
+		Correct:  verdict.Correct,
+		Solved:   verdict.Solved,
+		Solution: p.Solution,
+		Attempt:  attempt,
+	})
+}
+
+// sideToMove reads whose move it is from a FEN.
+func sideToMove(fen string) string {
+	if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+		return "black"
+	}
+	return "white"
+}
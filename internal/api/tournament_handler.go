@@ -0,0 +1,386 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/auth"
+	"github.com/Adi-ty/chess/internal/gamemanager"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/tournament"
+	"github.com/google/uuid"
+)
+
+type TournamentHandler struct {
+	logger          *log.Logger
+	gamemanager     *gamemanager.GameManager
+	tournamentStore store.TournamentStore
+	gameStore       store.GameStore
+}
+
+func NewTournamentHandler(logger *log.Logger, gm *gamemanager.GameManager, tournamentStore store.TournamentStore, gameStore store.GameStore) *TournamentHandler {
+	return &TournamentHandler{
+		logger:          logger,
+		gamemanager:     gm,
+		tournamentStore: tournamentStore,
+		gameStore:       gameStore,
+	}
+}
+
+type createTournamentRequest struct {
+	Name            string     `json:"name"`
+	Format          string     `json:"format"`
+	TimeControl     string     `json:"time_control"`
+	Rated           bool       `json:"rated"`
+	Variant         string     `json:"variant"`
+	Rounds          int        `json:"rounds"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Tiebreak        string     `json:"tiebreak"`
+	StartsAt        *time.Time `json:"starts_at"`
+}
+
+type standingResponse struct {
+	tournament.Standing
+	Withdrawn bool `json:"withdrawn,omitempty"`
+}
+
+type arenaStandingResponse struct {
+	tournament.ArenaStanding
+	Withdrawn bool `json:"withdrawn,omitempty"`
+}
+
+type knockoutStandingResponse struct {
+	tournament.KnockoutStanding
+	Withdrawn bool `json:"withdrawn,omitempty"`
+}
+
+type roundResponse struct {
+	*store.TournamentRound
+	Pairings []tournament.Pairing `json:"pairings"`
+}
+
+// tournamentDetailResponse carries []arenaStandingResponse for arenas,
+// []knockoutStandingResponse for knockouts and []standingResponse for the
+// others.
+type tournamentDetailResponse struct {
+	*store.Tournament
+	Standings any             `json:"standings"`
+	Rounds    []roundResponse `json:"rounds"`
+}
+
+// HandleListTournaments lists tournaments, optionally only those with the
+// given ?status=.
+func (h *TournamentHandler) HandleListTournaments(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.TournamentCreated, store.TournamentInProgress, store.TournamentFinished:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+
+	tournaments, err := h.tournamentStore.ListTournaments(r.Context(), status)
+	if err != nil {
+		h.logger.Printf("Failed to list tournaments: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to list tournaments")
+		return
+	}
+	if tournaments == nil {
+		tournaments = []*store.Tournament{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"tournaments": tournaments})
+}
+
+func (h *TournamentHandler) HandleCreateTournament(w http.ResponseWriter, r *http.Request) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req createTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	t, err := h.gamemanager.CreateTournament(userCtx.UserID, gamemanager.TournamentOptions{
+		Name:        req.Name,
+		Format:      req.Format,
+		TimeControl: req.TimeControl,
+		Rated:       req.Rated,
+		Variant:     req.Variant,
+		Rounds:      req.Rounds,
+		StartsAt:    req.StartsAt,
+
+		DurationMinutes: req.DurationMinutes,
+		Tiebreak:        req.Tiebreak,
+	})
+	if err != nil {
+		writeError(w, tournamentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// HandleGetTournament serves a tournament with its standings and every
+// round's pairings. An arena's games all belong to its one round.
+func (h *TournamentHandler) HandleGetTournament(w http.ResponseWriter, r *http.Request) {
+	t, players, pairings, ok := h.loadTournament(w, r)
+	if !ok {
+		return
+	}
+	rounds, err := h.tournamentStore.ListTournamentRounds(r.Context(), t.ID)
+	if err != nil {
+		h.logger.Printf("Failed to list rounds of tournament %s: %v", t.ID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get tournament")
+		return
+	}
+
+	resp := tournamentDetailResponse{
+		Tournament: t,
+		Rounds:     roundResponses(rounds, pairings),
+	}
+	ranked, withdrawn := contenders(players, pairings)
+	switch t.Format {
+	case gamemanager.TournamentArena:
+		standings := make([]arenaStandingResponse, 0, len(ranked))
+		for _, s := range tournament.ArenaStandings(ranked, pairings) {
+			standings = append(standings, arenaStandingResponse{ArenaStanding: s, Withdrawn: withdrawn[s.ID]})
+		}
+		resp.Standings = standings
+	case gamemanager.TournamentSingleElimination, gamemanager.TournamentDoubleElimination:
+		standings := make([]knockoutStandingResponse, 0, len(ranked))
+		for _, s := range bracket(t, players, pairings).Standings() {
+			standings = append(standings, knockoutStandingResponse{KnockoutStanding: s, Withdrawn: withdrawn[s.ID]})
+		}
+		resp.Standings = standings
+	default:
+		standings := make([]standingResponse, 0, len(ranked))
+		for _, s := range tournament.Standings(ranked, pairings) {
+			standings = append(standings, standingResponse{Standing: s, Withdrawn: withdrawn[s.ID]})
+		}
+		resp.Standings = standings
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleGetCrosstable serves the crosstable of a round robin, every player
+// against every other in the order of the standings.
+func (h *TournamentHandler) HandleGetCrosstable(w http.ResponseWriter, r *http.Request) {
+	t, players, pairings, ok := h.loadTournament(w, r)
+	if !ok {
+		return
+	}
+	if t.Format != gamemanager.TournamentRoundRobin {
+		writeError(w, http.StatusNotFound, "only round robins have a crosstable")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"crosstable": tournament.Crosstable(seeded(players), pairings)})
+}
+
+// HandleGetBracket serves the bracket of a knockout with every match played
+// or to play. It is drawn when the tournament starts.
+func (h *TournamentHandler) HandleGetBracket(w http.ResponseWriter, r *http.Request) {
+	t, players, pairings, ok := h.loadTournament(w, r)
+	if !ok {
+		return
+	}
+	if t.Format != gamemanager.TournamentSingleElimination && t.Format != gamemanager.TournamentDoubleElimination {
+		writeError(w, http.StatusNotFound, "only knockouts have a bracket")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"bracket": bracket(t, players, pairings)})
+}
+
+// HandleExportPGN streams every finished game of a tournament as one PGN
+// file, round by round.
+func (h *TournamentHandler) HandleExportPGN(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "tournament not found")
+		return
+	}
+	t, err := h.tournamentStore.GetTournament(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get tournament %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to export tournament")
+		return
+	}
+	if t == nil {
+		writeError(w, http.StatusNotFound, "tournament not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+id+`.pgn"`)
+
+	flusher, _ := w.(http.Flusher)
+	err = h.gameStore.ExportPGNByTournamentID(r.Context(), id, func(pgn string) error {
+		if _, err := io.WriteString(w, pgn+"\n"); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, so all we can do is cut the export short.
+		h.logger.Printf("Failed to export PGN for tournament %s: %v", id, err)
+	}
+}
+
+// loadTournament fetches the tournament in the path with its players and
+// pairings, writing the error response if it cannot.
+func (h *TournamentHandler) loadTournament(w http.ResponseWriter, r *http.Request) (*store.Tournament, []*store.TournamentPlayer, []tournament.Pairing, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "tournament not found")
+		return nil, nil, nil, false
+	}
+
+	t, err := h.tournamentStore.GetTournament(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to get tournament %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get tournament")
+		return nil, nil, nil, false
+	}
+	if t == nil {
+		writeError(w, http.StatusNotFound, "tournament not found")
+		return nil, nil, nil, false
+	}
+
+	players, err := h.tournamentStore.ListTournamentPlayers(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to list players of tournament %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get tournament")
+		return nil, nil, nil, false
+	}
+	pairings, err := h.tournamentStore.ListTournamentPairings(r.Context(), id)
+	if err != nil {
+		h.logger.Printf("Failed to list pairings of tournament %s: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to get tournament")
+		return nil, nil, nil, false
+	}
+	return t, players, pairings, true
+}
+
+// seeded returns the seeded players of a round robin or knockout in seed
+// order, none before it starts.
+func seeded(players []*store.TournamentPlayer) []tournament.Player {
+	players = slices.DeleteFunc(slices.Clone(players), func(p *store.TournamentPlayer) bool { return p.Seed == 0 })
+	slices.SortFunc(players, func(a, b *store.TournamentPlayer) int { return a.Seed - b.Seed })
+
+	field := make([]tournament.Player, 0, len(players))
+	for _, p := range players {
+		field = append(field, p.Player)
+	}
+	return field
+}
+
+// bracket plays out a knockout from its pairings. Before it starts the
+// bracket has no matches.
+func bracket(t *store.Tournament, players []*store.TournamentPlayer, pairings []tournament.Pairing) *tournament.Bracket {
+	double := t.Format == gamemanager.TournamentDoubleElimination
+	field := seeded(players)
+	if len(field) < 2 {
+		return &tournament.Bracket{Double: double, Tiebreak: tournament.Tiebreak(t.Tiebreak), Matches: []*tournament.Match{}}
+	}
+	return tournament.Knockout(field, double, tournament.Tiebreak(t.Tiebreak), pairings)
+}
+
+// contenders returns the players to rank, leaving out those who withdrew
+// without playing a game, and which of them withdrew.
+func contenders(players []*store.TournamentPlayer, pairings []tournament.Pairing) ([]tournament.Player, map[string]bool) {
+	paired := make(map[string]bool)
+	for _, p := range pairings {
+		paired[p.White] = true
+		paired[p.Black] = true
+	}
+
+	withdrawn := make(map[string]bool)
+	var ranked []tournament.Player
+	for _, p := range players {
+		if p.Withdrawn && !paired[p.ID] {
+			continue
+		}
+		withdrawn[p.ID] = p.Withdrawn
+		ranked = append(ranked, p.Player)
+	}
+	return ranked, withdrawn
+}
+
+func roundResponses(rounds []*store.TournamentRound, pairings []tournament.Pairing) []roundResponse {
+	resp := make([]roundResponse, 0, len(rounds))
+	for _, round := range rounds {
+		rr := roundResponse{TournamentRound: round, Pairings: []tournament.Pairing{}}
+		for _, p := range pairings {
+			if p.Round == round.Round {
+				rr.Pairings = append(rr.Pairings, p)
+			}
+		}
+		resp = append(resp, rr)
+	}
+	return resp
+}
+
+func (h *TournamentHandler) HandleJoinTournament(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.gamemanager.JoinTournament, "joined")
+}
+
+func (h *TournamentHandler) HandleWithdrawFromTournament(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.gamemanager.WithdrawFromTournament, "withdrawn")
+}
+
+func (h *TournamentHandler) HandleStartTournament(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.gamemanager.StartTournament, "started")
+}
+
+// handleAction runs action for the caller on the tournament in the path.
+func (h *TournamentHandler) handleAction(w http.ResponseWriter, r *http.Request, action func(userID, tournamentID string) error, done string) {
+	userCtx := auth.GetUserFromContext(r.Context())
+	if userCtx == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "tournament not found")
+		return
+	}
+
+	if err := action(userCtx.UserID, id); err != nil {
+		writeError(w, tournamentErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "tournament " + done})
+}
+
+func tournamentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gamemanager.ErrTournamentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, gamemanager.ErrNotOrganizer):
+		return http.StatusForbidden
+	case errors.Is(err, gamemanager.ErrTournamentStarted), errors.Is(err, gamemanager.ErrTournamentFinished),
+		errors.Is(err, gamemanager.ErrNotRegistered), errors.Is(err, gamemanager.ErrTooFewPlayers),
+		errors.Is(err, gamemanager.ErrTournamentFull):
+		return http.StatusConflict
+	case errors.Is(err, gamemanager.ErrTournamentUnavailable):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/google/uuid"
+)
+
+type UserHandler struct {
+	logger      *log.Logger
+	userStore   store.UserStore
+	ratingStore store.RatingStore
+	statsStore  store.StatsStore
+}
+
+func NewUserHandler(logger *log.Logger, userStore store.UserStore, ratingStore store.RatingStore, statsStore store.StatsStore) *UserHandler {
+	return &UserHandler{
+		logger:      logger,
+		userStore:   userStore,
+		ratingStore: ratingStore,
+		statsStore:  statsStore,
+	}
+}
+
+type profileResponse struct {
+	ID          string           `json:"id"`
+	DisplayName string           `json:"display_name"`
+	AvatarURL   string           `json:"avatar_url,omitempty"`
+	JoinedAt    time.Time        `json:"joined_at"`
+	Ratings     []*store.Rating  `json:"ratings"`
+	Stats       *store.UserStats `json:"stats"`
+}
+
+// HandleGetUser serves a user's public profile. Statistics come from the
+// cached aggregate and are only computed here for users who have not
+// finished a game since the cache was introduced.
+func (h *UserHandler) HandleGetUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if _, err := uuid.Parse(userID); err != nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		h.logger.Printf("Failed to get user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+
+	ratings, err := h.ratingStore.GetRatingsByUserID(r.Context(), userID)
+	if err != nil {
+		h.logger.Printf("Failed to get ratings for %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+
+	stats, err := h.statsStore.GetUserStats(r.Context(), userID)
+	if err == nil && stats == nil {
+		stats, err = h.statsStore.RefreshUserStats(r.Context(), userID)
+	}
+	if err != nil {
+		h.logger.Printf("Failed to get stats for %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, profileResponse{
+		ID:          user.ID,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		JoinedAt:    user.CreatedAt,
+		Ratings:     ratings,
+		Stats:       stats,
+	})
+}
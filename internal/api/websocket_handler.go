@@ -59,4 +59,22 @@ func (h *WebSocketHandler) WsHandler(w http.ResponseWriter, r *http.Request) {
     }
 
     h.gamemanager.AddUser(conn, userID)
+}
+
+// SpectateHandler upgrades a read-only connection that watches one game.
+// Spectating does not require an account.
+func (h *WebSocketHandler) SpectateHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := r.URL.Query().Get("game_id")
+	if gameID == "" {
+		http.Error(w, "missing game_id", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Printf("Upgrade error: %v", err)
+		return
+	}
+
+	go h.gamemanager.AddSpectator(conn, gameID)
 }
\ No newline at end of file
@@ -1,15 +1,21 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
 
+	"github.com/Adi-ty/chess/internal/analysis"
 	"github.com/Adi-ty/chess/internal/api"
 	"github.com/Adi-ty/chess/internal/auth"
+	"github.com/Adi-ty/chess/internal/chat"
 	"github.com/Adi-ty/chess/internal/config"
+	"github.com/Adi-ty/chess/internal/engine"
 	"github.com/Adi-ty/chess/internal/gamemanager"
+	"github.com/Adi-ty/chess/internal/leaderboard"
 	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/uci"
 	"github.com/Adi-ty/chess/internal/worker"
 	"github.com/Adi-ty/chess/migrations"
 	"github.com/redis/go-redis/v9"
@@ -20,10 +26,18 @@ type Application struct {
 	Config *config.Config
 	AuthHandler *api.AuthHandler
 	WebSocketHandler *api.WebSocketHandler
+	ChallengeHandler *api.ChallengeHandler
+	GameHandler      *api.GameHandler
+	UserHandler      *api.UserHandler
+	LeaderboardHandler *api.LeaderboardHandler
+	TournamentHandler  *api.TournamentHandler
+	PuzzleHandler      *api.PuzzleHandler
 	JWTService       *auth.JWTService
 	DB *sql.DB
 	redisClient *redis.Client
 	worker *worker.Worker
+	analysisWorker *worker.AnalysisWorker
+	enginePool *uci.Pool
 }
 
 func NewApplication() (*Application, error) {
@@ -49,9 +63,57 @@ func NewApplication() (*Application, error) {
 	// Stores
 	userStore := store.NewPostgresUserStore(pgDB)
 	gameStore := store.NewPostgresGameStore(pgDB)
+	ratingStore := store.NewPostgresRatingStore(pgDB)
+	chatStore := store.NewPostgresChatStore(pgDB)
+	statsStore := store.NewPostgresStatsStore(pgDB)
+	analysisStore := store.NewPostgresAnalysisStore(pgDB)
+	tournamentStore := store.NewPostgresTournamentStore(pgDB)
+	puzzleStore := store.NewPostgresPuzzleStore(pgDB)
 
 	// Services
-	gm := gamemanager.NewGameManager(gameStore, redisDB)
+	gm := gamemanager.NewGameManager(gameStore, ratingStore, chatStore, userStore, statsStore, analysisStore, tournamentStore, redisDB, cfg.FirstMoveTimeout)
+	// Chat is only filtered when a blocklist is configured
+	if len(cfg.ChatBlocklist) > 0 {
+		gm.SetChatFilter(chat.NewWordFilter(cfg.ChatBlocklist))
+	}
+	gm.SetSiteURL(cfg.FrontendURL)
+	gm.SetMaxLiveGames(cfg.MaxLiveGames)
+	go gm.RunMatchmaker()
+	go gm.RunDeadlineScheduler()
+	go gm.RunTournamentScheduler()
+	go gm.RunArenaMatchmaker()
+
+	// External engine, when configured, plays as another computer player
+	var enginePool *uci.Pool
+	if cfg.UCIEnginePath != "" {
+		enginePool = uci.NewPool(uci.Config{
+			Path:    cfg.UCIEnginePath,
+			Args:    cfg.UCIEngineArgs,
+			Options: cfg.UCIEngineOptions,
+			Size:    cfg.UCIEnginePoolSize,
+		})
+		botUser, err := userStore.CreateOrUpdate(context.Background(), &store.User{
+			Email:       "uci-engine@bots.local",
+			DisplayName: cfg.UCIEngineName,
+			Provider:    "bot",
+			ProviderID:  "uci",
+		})
+		if err != nil {
+			return nil, err
+		}
+		gm.RegisterBot(botUser.ID, uci.NewBot(enginePool, uci.Limits{MoveTime: cfg.UCIEngineMoveTime}))
+		logger.Printf("UCI engine %s plays as user %s", cfg.UCIEnginePath, botUser.ID)
+	}
+
+	// Finished games are analysed by the external engine when there is one,
+	// and by the strongest built-in level otherwise
+	var evaluator analysis.Evaluator
+	if enginePool != nil {
+		evaluator = analysis.NewUCIEvaluator(enginePool, uci.Limits{MoveTime: cfg.AnalysisMoveTime})
+	} else {
+		strongest := engine.Levels[len(engine.Levels)-1]
+		evaluator = analysis.NewEngineEvaluator(engine.New(engine.Level{Depth: strongest.Depth, MoveTime: cfg.AnalysisMoveTime}))
+	}
 
 	jwtService := auth.NewJWTService(cfg.JWTSecret)
 	googleOauth := auth.NewGoogleOAuth(&auth.GoogleConfig{
@@ -61,23 +123,52 @@ func NewApplication() (*Application, error) {
 	})
 
 	// Handlers
-	authHandler := api.NewAuthHandler(logger, googleOauth, jwtService, userStore)
+	authHandler := api.NewAuthHandler(logger, googleOauth, jwtService, userStore, ratingStore)
 	websocketHandler := api.NewWebSocketHandler(logger, gm, jwtService)
+	challengeHandler := api.NewChallengeHandler(logger, gm, cfg.FrontendURL)
+	gameHandler := api.NewGameHandler(logger, gameStore, userStore, analysisStore)
+	userHandler := api.NewUserHandler(logger, userStore, ratingStore, statsStore)
+	leaderboardHandler := api.NewLeaderboardHandler(logger, leaderboard.New(redisDB), userStore)
+	tournamentHandler := api.NewTournamentHandler(logger, gm, tournamentStore, gameStore)
+	puzzleHandler := api.NewPuzzleHandler(logger, puzzleStore, ratingStore)
 
 	// Start worker go-routine
 	wk := worker.NewWorker(redisDB, gameStore)
 	go wk.Start()
+	aw := worker.NewAnalysisWorker(redisDB, analysisStore, evaluator)
+	go aw.Start()
 
 	app := &Application{
 		Logger: logger,
 		Config: cfg,
 		AuthHandler: authHandler,
 		WebSocketHandler: websocketHandler,
+		ChallengeHandler: challengeHandler,
+		GameHandler: gameHandler,
+		UserHandler: userHandler,
+		LeaderboardHandler: leaderboardHandler,
+		TournamentHandler: tournamentHandler,
+		PuzzleHandler: puzzleHandler,
 		JWTService: jwtService,
 		DB: pgDB,
 		redisClient: redisDB,
 		worker: wk,
+		analysisWorker: aw,
+		enginePool: enginePool,
 	}
 
 	return app, nil
-}
\ No newline at end of file
+}
+// Close releases the application's resources: the engine processes, Redis
+// and the database.
+func (app *Application) Close() {
+	if app.enginePool != nil {
+		app.enginePool.Close()
+	}
+	if err := app.redisClient.Close(); err != nil {
+		app.Logger.Printf("Failed to close Redis: %v", err)
+	}
+	if err := app.DB.Close(); err != nil {
+		app.Logger.Printf("Failed to close database: %v", err)
+	}
+}
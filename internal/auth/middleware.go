@@ -17,18 +17,7 @@ type UserContext struct {
 
 func (j *JWTService) Middleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        var tokenString string
-
-        if cookie, err := r.Cookie("auth_token"); err == nil {
-            tokenString = cookie.Value
-        }
-
-        if tokenString == "" {
-            authHeader := r.Header.Get("Authorization")
-            if strings.HasPrefix(authHeader, "Bearer ") {
-                tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-            }
-        }
+        tokenString := tokenFromRequest(r)
 
         if tokenString == "" {
             http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
@@ -50,6 +39,33 @@ func (j *JWTService) Middleware(next http.Handler) http.Handler {
     })
 }
 
+// OptionalMiddleware identifies the caller when they send a valid token but
+// lets anonymous requests through as well.
+func (j *JWTService) OptionalMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if claims, err := j.ValidateToken(tokenFromRequest(r)); err == nil {
+            r = r.WithContext(context.WithValue(r.Context(), UserContextKey, &UserContext{
+                UserID: claims.UserID,
+                Email:  claims.Email,
+            }))
+        }
+
+        next.ServeHTTP(w, r)
+    })
+}
+
+func tokenFromRequest(r *http.Request) string {
+    if cookie, err := r.Cookie("auth_token"); err == nil && cookie.Value != "" {
+        return cookie.Value
+    }
+
+    authHeader := r.Header.Get("Authorization")
+    if strings.HasPrefix(authHeader, "Bearer ") {
+        return strings.TrimPrefix(authHeader, "Bearer ")
+    }
+    return ""
+}
+
 func CORSMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter screens chat lines before they are delivered. It returns the text
+// to deliver, which may be censored, and whether the line may be delivered
+// at all. Rejected lines are still stored for moderators to review.
+type Filter interface {
+	Filter(text string) (string, bool)
+}
+
+// NopFilter lets everything through unchanged.
+type NopFilter struct{}
+
+func (NopFilter) Filter(text string) (string, bool) {
+	return text, true
+}
+
+// WordFilter masks blocklisted words with asterisks. A word only matches
+// on its own, not inside a longer word, in any script and any case.
+type WordFilter struct {
+	pattern *regexp.Regexp
+}
+
+// NewWordFilter filters the given words. With none it lets everything
+// through, so filtering is opt-in.
+func NewWordFilter(words []string) *WordFilter {
+	var quoted []string
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return &WordFilter{}
+	}
+	// Longer words first, so that a word is not passed over because a
+	// shorter one it starts with matched first.
+	sort.SliceStable(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return &WordFilter{pattern: regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)}
+}
+
+// Filter masks every blocklisted word in text. RE2's \b only knows ASCII
+// letters, so the word boundaries are checked here instead.
+func (f *WordFilter) Filter(text string) (string, bool) {
+	if f.pattern == nil {
+		return text, true
+	}
+
+	var b strings.Builder
+	last := 0
+	for start := 0; start < len(text); {
+		loc := f.pattern.FindStringIndex(text[start:])
+		if loc == nil {
+			break
+		}
+		i, j := start+loc[0], start+loc[1]
+		if j == i || !wordBoundary(text, i) || !wordBoundary(text, j) {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			start = i + size
+			continue
+		}
+		b.WriteString(text[last:i])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[i:j])))
+		last, start = j, j
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
+}
+
+// wordBoundary reports whether a word may start or end at byte i of text:
+// the characters on either side of i are not both part of a word.
+func wordBoundary(text string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	after, _ := utf8.DecodeRuneInString(text[i:])
+	return i == 0 || i == len(text) || !wordRune(before) || !wordRune(after)
+}
+
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
@@ -0,0 +1,85 @@
+package chat
+
+import "testing"
+
+func TestWordFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		text  string
+		want  string
+	}{
+		{
+			name:  "no words",
+			words: nil,
+			text:  "you ass",
+			want:  "you ass",
+		},
+		{
+			name:  "blank words are ignored",
+			words: []string{" ", ""},
+			text:  "you ass",
+			want:  "you ass",
+		},
+		{
+			name:  "whole word",
+			words: []string{"ass"},
+			text:  "you ass",
+			want:  "you ***",
+		},
+		{
+			name:  "any case",
+			words: []string{"ass"},
+			text:  "ASS and Ass",
+			want:  "*** and ***",
+		},
+		{
+			name:  "not inside a longer word",
+			words: []string{"ass"},
+			text:  "class assessment bass",
+			want:  "class assessment bass",
+		},
+		{
+			name:  "next to punctuation",
+			words: []string{"ass"},
+			text:  "(ass), ass!",
+			want:  "(***), ***!",
+		},
+		{
+			name:  "longer words first",
+			words: []string{"dumb", "dumbo"},
+			text:  "dumbo",
+			want:  "*****",
+		},
+		{
+			name:  "other scripts",
+			words: []string{"дурак"},
+			text:  "ты Дурак, не дураки",
+			want:  "ты *****, не дураки",
+		},
+		{
+			name:  "one asterisk per character",
+			words: []string{"café"},
+			text:  "café",
+			want:  "****",
+		},
+		{
+			name:  "regexp characters are literal",
+			words: []string{"a.b"},
+			text:  "axb a.b",
+			want:  "axb ***",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NewWordFilter(tt.words).Filter(tt.text)
+			if !ok {
+				t.Fatal("line rejected")
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
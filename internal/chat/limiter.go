@@ -0,0 +1,35 @@
+package chat
+
+import (
+	"time"
+)
+
+const (
+	MaxLength = 140
+
+	rateLimit  = 5
+	rateWindow = 10 * time.Second
+)
+
+// Limiter allows at most rateLimit lines per rateWindow. It is not safe for
+// concurrent use; each connection owns one.
+type Limiter struct {
+	sent []time.Time
+}
+
+func (l *Limiter) Allow(now time.Time) bool {
+	cutoff := now.Add(-rateWindow)
+	kept := l.sent[:0]
+	for _, t := range l.sent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	l.sent = kept
+
+	if len(l.sent) >= rateLimit {
+		return false
+	}
+	l.sent = append(l.sent, now)
+	return true
+}
@@ -0,0 +1,34 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{time.Second, true},
+		{2 * time.Second, true},
+		{3 * time.Second, true},
+		{4 * time.Second, true},
+		{5 * time.Second, false},
+		{9 * time.Second, false},
+		// The first line drops out of the window after ten seconds.
+		{10 * time.Second, true},
+		{10 * time.Second, false},
+		{11 * time.Second, true},
+		{30 * time.Second, true},
+	}
+
+	var l Limiter
+	for _, tt := range tests {
+		if got := l.Allow(start.Add(tt.after)); got != tt.want {
+			t.Errorf("Allow after %s = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+}
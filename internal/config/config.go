@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +15,26 @@ type Config struct {
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURI  string
+	FrontendURL        string
+	FirstMoveTimeout   time.Duration
+	ChatBlocklist      []string
+
+	// MaxLiveGames is how many live games a player may have going at once.
+	// Zero or less means no limit.
+	MaxLiveGames int
+
+	// UCIEnginePath enables an external UCI engine as a computer opponent
+	// when set.
+	UCIEnginePath     string
+	UCIEngineArgs     []string
+	UCIEngineOptions  map[string]string
+	UCIEngineName     string
+	UCIEnginePoolSize int
+	UCIEngineMoveTime time.Duration
+
+	// AnalysisMoveTime is how long the engine spends on each position when
+	// analysing a finished game.
+	AnalysisMoveTime time.Duration
 }
 
 func LoadConfig() *Config {
@@ -25,5 +48,74 @@ func LoadConfig() *Config {
 		GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
 		GoogleClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
 		GoogleRedirectURI:  os.Getenv("GOOGLE_REDIRECT_URI"),
+		FrontendURL:        stringEnv("FRONTEND_URL", "http://localhost:3000"),
+		FirstMoveTimeout:   durationEnv("FIRST_MOVE_TIMEOUT", 30*time.Second),
+		ChatBlocklist:      listEnv("CHAT_BLOCKLIST"),
+		MaxLiveGames:       intEnv("MAX_LIVE_GAMES", 2),
+		UCIEnginePath:      os.Getenv("UCI_ENGINE_PATH"),
+		UCIEngineArgs:      listEnv("UCI_ENGINE_ARGS"),
+		UCIEngineOptions:   mapEnv("UCI_ENGINE_OPTIONS"),
+		UCIEngineName:      stringEnv("UCI_ENGINE_NAME", "UCI Engine"),
+		UCIEnginePoolSize:  intEnv("UCI_ENGINE_POOL_SIZE", 2),
+		UCIEngineMoveTime:  durationEnv("UCI_ENGINE_MOVETIME", time.Second),
+		AnalysisMoveTime:   durationEnv("ANALYSIS_MOVETIME", 500*time.Millisecond),
+	}
+}
+
+func stringEnv(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// listEnv reads a comma separated list from the environment.
+func listEnv(key string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// mapEnv reads comma separated name=value pairs from the environment.
+func mapEnv(key string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range listEnv(key) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		m[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return m
+}
+
+func intEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// durationEnv reads a duration such as "30s" from the environment, falling
+// back to def when the variable is unset or malformed.
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, v, def)
+		return def
 	}
+	return d
 }
\ No newline at end of file
@@ -0,0 +1,212 @@
+// Package engine is a small alpha-beta chess engine used for the built-in
+// computer opponent.
+package engine
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+
+	"github.com/notnil/chess"
+)
+
+var ErrNoMoves = errors.New("no legal moves")
+
+const (
+	mateScore = 100000
+	infinity  = 1 << 30
+
+	// MateThreshold separates mate scores from material evaluations.
+	MateThreshold = mateScore - 1000
+
+	// quiescenceDepth bounds the capture-only search at the leaves.
+	quiescenceDepth = 4
+)
+
+type Engine struct {
+	level Level
+}
+
+func New(level Level) *Engine {
+	return &Engine{level: level}
+}
+
+func (e *Engine) Level() Level {
+	return e.level
+}
+
+// BestMove searches the current position of board by iterative deepening
+// until it reaches the level's depth or runs out of time, whichever comes
+// first. ctx can shorten the time budget further.
+func (e *Engine) BestMove(ctx context.Context, board *chess.Game) (*chess.Move, error) {
+	mv, _, err := e.Search(ctx, board)
+	return mv, err
+}
+
+// Search is BestMove that also returns the move's score in centipawns from
+// the side to move's point of view. Mate scores are beyond MateThreshold.
+func (e *Engine) Search(ctx context.Context, board *chess.Game) (*chess.Move, int, error) {
+	pos := board.Position()
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		return nil, 0, ErrNoMoves
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.level.MoveTime)
+	defer cancel()
+
+	s := &search{ctx: ctx}
+	orderMoves(pos, moves)
+	best, bestScore := moves[0], 0
+
+	for depth := 1; depth <= e.level.Depth; depth++ {
+		scores := make(map[*chess.Move]int, len(moves))
+		alpha := -infinity
+		for _, mv := range moves {
+			score := -s.negamax(pos.Update(mv), depth-1, 1, -infinity, -alpha)
+			if s.aborted {
+				return best, bestScore, nil
+			}
+			scores[mv] = score
+			// Anything more than twice the noise below the best move can
+			// never be picked, so it only needs an upper bound.
+			if bound := score - 2*e.level.Noise; bound > alpha {
+				alpha = bound
+			}
+		}
+
+		sort.SliceStable(moves, func(i, j int) bool { return scores[moves[i]] > scores[moves[j]] })
+		best = e.pick(moves, scores)
+		bestScore = scores[best]
+	}
+
+	return best, bestScore, nil
+}
+
+// MateIn converts a score returned by Search into the number of moves to
+// mate, negative when the side to move is getting mated. ok is false for
+// ordinary scores.
+func MateIn(score int) (moves int, ok bool) {
+	switch {
+	case score > MateThreshold:
+		return (mateScore - score + 1) / 2, true
+	case score < -MateThreshold:
+		return -(mateScore + score + 1) / 2, true
+	}
+	return 0, false
+}
+
+// pick chooses the move with the best score after adding the level's noise.
+func (e *Engine) pick(moves []*chess.Move, scores map[*chess.Move]int) *chess.Move {
+	best, bestScore := moves[0], -infinity
+	for _, mv := range moves {
+		score := scores[mv]
+		if e.level.Noise > 0 {
+			score += rand.Intn(2*e.level.Noise+1) - e.level.Noise
+		}
+		if score > bestScore {
+			best, bestScore = mv, score
+		}
+	}
+	return best
+}
+
+type search struct {
+	ctx     context.Context
+	nodes   int
+	aborted bool
+}
+
+func (s *search) negamax(pos *chess.Position, depth, ply, alpha, beta int) int {
+	if s.stop() {
+		return 0
+	}
+
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		if pos.Status() == chess.Checkmate {
+			return -mateScore + ply
+		}
+		return 0
+	}
+	if pos.HalfMoveClock() >= 100 {
+		return 0
+	}
+	if depth == 0 {
+		return s.quiesce(pos, quiescenceDepth, alpha, beta)
+	}
+
+	orderMoves(pos, moves)
+	for _, mv := range moves {
+		score := -s.negamax(pos.Update(mv), depth-1, ply+1, -beta, -alpha)
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// quiesce only follows captures so that the static evaluation is never taken
+// in the middle of an exchange.
+func (s *search) quiesce(pos *chess.Position, depth, alpha, beta int) int {
+	if s.stop() {
+		return 0
+	}
+
+	standPat := evaluate(pos)
+	if standPat >= beta || depth == 0 {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	moves := pos.ValidMoves()
+	orderMoves(pos, moves)
+	for _, mv := range moves {
+		if !mv.HasTag(chess.Capture) && mv.Promo() == chess.NoPieceType {
+			break
+		}
+		score := -s.quiesce(pos.Update(mv), depth-1, -beta, -alpha)
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+func (s *search) stop() bool {
+	s.nodes++
+	if s.nodes%512 == 0 && s.ctx.Err() != nil {
+		s.aborted = true
+	}
+	return s.aborted
+}
+
+// orderMoves puts promotions and captures first, most valuable victim and
+// least valuable attacker first, which makes alpha-beta cut off sooner.
+func orderMoves(pos *chess.Position, moves []*chess.Move) {
+	board := pos.Board()
+	priority := func(mv *chess.Move) int {
+		p := 0
+		if mv.Promo() != chess.NoPieceType {
+			p += pieceValues[mv.Promo()]
+		}
+		if mv.HasTag(chess.Capture) {
+			victim := pieceValues[board.Piece(mv.S2()).Type()]
+			if mv.HasTag(chess.EnPassant) {
+				victim = pieceValues[chess.Pawn]
+			}
+			p += 10*victim - pieceValues[board.Piece(mv.S1()).Type()]/10 + 1
+		}
+		return p
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return priority(moves[i]) > priority(moves[j]) })
+}
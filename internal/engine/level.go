@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidLevel = errors.New("level must be between 1 and 8")
+
+// Level controls how well the engine plays. Depth caps the search,
+// MoveTime caps the thinking time per move and Noise, in centipawns, is
+// added to the score of every candidate move so weaker levels miss things.
+type Level struct {
+	Number   int
+	Depth    int
+	MoveTime time.Duration
+	Noise    int
+}
+
+var Levels = []Level{
+	{Number: 1, Depth: 1, MoveTime: 50 * time.Millisecond, Noise: 300},
+	{Number: 2, Depth: 1, MoveTime: 100 * time.Millisecond, Noise: 150},
+	{Number: 3, Depth: 2, MoveTime: 200 * time.Millisecond, Noise: 80},
+	{Number: 4, Depth: 2, MoveTime: 300 * time.Millisecond, Noise: 40},
+	{Number: 5, Depth: 3, MoveTime: 500 * time.Millisecond, Noise: 20},
+	{Number: 6, Depth: 3, MoveTime: time.Second, Noise: 10},
+	{Number: 7, Depth: 4, MoveTime: 2 * time.Second, Noise: 5},
+	{Number: 8, Depth: 5, MoveTime: 3 * time.Second, Noise: 0},
+}
+
+func LevelFor(n int) (Level, error) {
+	if n < 1 || n > len(Levels) {
+		return Level{}, ErrInvalidLevel
+	}
+	return Levels[n-1], nil
+}
+
+// PlayerID is the user ID of the built-in computer player of a level. The
+// users themselves are created by a migration so games against the computer
+// can be stored like any other.
+func PlayerID(level int) string {
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", level)
+}
@@ -0,0 +1,68 @@
+package gamemanager
+
+import (
+	"errors"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+var (
+	ErrCannotAbort = errors.New("the game can only be aborted before both players have moved")
+)
+
+// Abort cancels the game without a result. It is only allowed until both
+// sides have made their first move.
+func (g *Game) Abort(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.tournamentID != "" {
+		return ErrTournamentAbort
+	}
+	if !g.abortable() {
+		return ErrCannotAbort
+	}
+
+	g.endGame(gm, GameStatusAborted, chess.NoOutcome.String(), MethodAbort)
+	return nil
+}
+
+func (g *Game) abortable() bool {
+	return g.moveNumber < 2
+}
+
+// startFirstMoveTimer aborts the game if the first move is not made within the
+// manager's first move window. Correspondence games get their move deadline
+// instead. Tournament games always have a window, so that a player who does
+// not turn up cannot hold up the round. The caller must hold g.mu.
+func (g *Game) startFirstMoveTimer(gm *GameManager) {
+	timeout := gm.firstMoveTimeout
+	if timeout <= 0 && g.tournamentID != "" {
+		timeout = tournamentFirstMoveTimeout
+	}
+	if timeout <= 0 || g.moveNumber > 0 || g.settings.TimeControl.Correspondence() {
+		return
+	}
+
+	g.stopFirstMoveTimer()
+	g.firstMoveTimer = time.AfterFunc(timeout, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if g.status != GameStatusInProgress || g.moveNumber > 0 {
+			return
+		}
+		g.endGame(gm, GameStatusAborted, chess.NoOutcome.String(), MethodNoFirstMove)
+	})
+}
+
+func (g *Game) stopFirstMoveTimer() {
+	if g.firstMoveTimer != nil {
+		g.firstMoveTimer.Stop()
+		g.firstMoveTimer = nil
+	}
+}
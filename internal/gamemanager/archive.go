@@ -0,0 +1,181 @@
+package gamemanager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Adi-ty/chess/internal/pgn"
+	"github.com/Adi-ty/chess/internal/queue"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/variant"
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/opening"
+)
+
+var (
+	ecoBook     *opening.BookECO
+	ecoBookOnce sync.Once
+)
+
+// SetSiteURL sets the address recorded in the Site header of exported games.
+func (gm *GameManager) SetSiteURL(url string) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.siteURL = url
+}
+
+// archive returns the work of storing the PGN and opening of a finished
+// game, refreshing both players' statistics and queueing the game for
+// engine analysis. What it needs of the game is gathered now, so that the
+// work can run in the background once the result is stored. The caller must
+// hold g.mu.
+func (g *Game) archive(gm *GameManager, status GameStatus, outcome string, method string) func() {
+	category := string(g.settings.RatingCategory())
+	kind := strings.ToUpper(category[:1]) + category[1:]
+	if _, ok := variantCategories[g.settings.Variant]; ok {
+		kind = g.settings.Variant.Name()
+	}
+	event := "Casual " + kind + " game"
+	if g.settings.Rated {
+		event = "Rated " + kind + " game"
+	}
+	site := "?"
+	if gm.siteURL != "" {
+		site = gm.siteURL + "/game/" + g.ID
+	}
+
+	// The board is never modified once the game is over.
+	board := g.board
+	date := g.startTime.UTC().Format("2006.01.02")
+	timeControl := g.settings.TimeControl.PGN()
+
+	return func() {
+		record := &store.GameRecord{PlyCount: len(board.Moves())}
+		if board.Variant() == variant.Standard {
+			ecoBookOnce.Do(func() { ecoBook = opening.NewBookECO() })
+			if o := ecoBook.Find(board.Game().Moves()); o != nil {
+				record.ECO = o.Code()
+				record.Opening = o.Title()
+			}
+		}
+
+		event, round := event, "-"
+		if g.tournamentID != "" {
+			event, round = gm.tournamentTags(g.tournamentID, g.ID, event)
+		}
+
+		tags := []pgn.Tag{
+			{Key: "Event", Value: event},
+			{Key: "Site", Value: site},
+			{Key: "Date", Value: date},
+			{Key: "Round", Value: round},
+			{Key: "White", Value: gm.displayName(g.WhiteUserID)},
+			{Key: "Black", Value: gm.displayName(g.BlackUserID)},
+			{Key: "Result", Value: outcome},
+			{Key: "TimeControl", Value: timeControl},
+			{Key: "Termination", Value: termination(method)},
+		}
+		tags = append(tags, variantTags(board)...)
+		if record.ECO != "" {
+			tags = append(tags, pgn.Tag{Key: "ECO", Value: record.ECO}, pgn.Tag{Key: "Opening", Value: record.Opening})
+		}
+		record.PGN = pgn.Encode(tags, board, outcome)
+
+		if err := gm.gameStore.UpdateGameRecord(context.Background(), g.ID, record); err != nil {
+			log.Printf("Failed to store record of game %s: %v", g.ID, err)
+		}
+
+		for _, userID := range []string{g.WhiteUserID, g.BlackUserID} {
+			if _, err := gm.statsStore.RefreshUserStats(context.Background(), userID); err != nil {
+				log.Printf("Failed to refresh stats for %s: %v", userID, err)
+			}
+		}
+
+		// The engines only know standard rules.
+		if status != GameStatusAborted && record.PlyCount > 0 && board.Variant().StandardRules() {
+			gm.requestAnalysis(g.ID, board)
+		}
+	}
+}
+
+// requestAnalysis marks the game's analysis pending and queues it for the
+// analysis worker.
+func (gm *GameManager) requestAnalysis(gameID string, board *variant.Board) {
+	payload := queue.AnalysisPayload{
+		GameID:  gameID,
+		Variant: string(board.Variant()),
+		FEN:     board.StartFEN(),
+		Moves:   board.Moves(),
+	}
+
+	if err := gm.analysisStore.SetAnalysisStatus(context.Background(), gameID, store.AnalysisPending); err != nil {
+		log.Printf("Failed to request analysis of game %s: %v", gameID, err)
+		return
+	}
+	if err := queue.EnqueueAnalysis(gm.redisClient, payload); err != nil {
+		log.Printf("Failed to enqueue analysis of game %s: %v", gameID, err)
+	}
+}
+
+// tournamentTags are the Event and Round of a tournament game: the
+// tournament's name and the round and board, followed by the game number
+// for the tiebreaks of a knockout match. Arena games have no round. The
+// event falls back to the given one if the tournament cannot be found.
+func (gm *GameManager) tournamentTags(tournamentID, gameID, event string) (string, string) {
+	t, err := gm.getTournament(tournamentID)
+	if err != nil {
+		return event, "-"
+	}
+	if t.Format == TournamentArena {
+		return t.Name, "-"
+	}
+	pairing, err := gm.tournamentStore.GetTournamentPairing(context.Background(), gameID)
+	if err != nil {
+		log.Printf("Failed to fetch pairing of game %s for PGN: %v", gameID, err)
+		return t.Name, "-"
+	}
+	if pairing == nil {
+		return t.Name, "-"
+	}
+	round := fmt.Sprintf("%d.%d", pairing.Round, pairing.Board)
+	if pairing.Game > 1 {
+		round += fmt.Sprintf(".%d", pairing.Game)
+	}
+	return t.Name, round
+}
+
+func (gm *GameManager) displayName(userID string) string {
+	user, err := gm.userStore.GetUserByID(context.Background(), userID)
+	if err != nil {
+		log.Printf("Failed to fetch user %s for PGN: %v", userID, err)
+		return "?"
+	}
+	return user.DisplayName
+}
+
+// variantTags are the headers other software needs to replay a game that is
+// not standard chess from the initial position.
+func variantTags(board *variant.Board) []pgn.Tag {
+	if board.Variant() == variant.Standard {
+		return nil
+	}
+	tags := []pgn.Tag{{Key: "Variant", Value: board.Variant().Name()}}
+	if board.StartFEN() == chess.StartingPosition().String() {
+		return tags
+	}
+	return append(tags, pgn.Tag{Key: "SetUp", Value: "1"}, pgn.Tag{Key: "FEN", Value: board.StartFEN()})
+}
+
+// termination maps how a game ended to the standard PGN Termination values.
+func termination(method string) string {
+	switch method {
+	case MethodTimeout:
+		return "time forfeit"
+	case MethodDisconnect, MethodAbort, MethodNoFirstMove:
+		return "abandoned"
+	}
+	return "normal"
+}
@@ -0,0 +1,346 @@
+package gamemanager
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/matchmaking"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/tournament"
+)
+
+var (
+	ErrArenaCorrespondence = errors.New("arena tournaments need a clock")
+	ErrBerserkNotAllowed   = errors.New("berserk is only allowed in arena games with a base time")
+	ErrBerserkTooLate      = errors.New("you can only go berserk before your first move")
+	ErrAlreadyBerserk      = errors.New("you have already gone berserk")
+)
+
+// arenaPoolConfig pairs arena players more loosely than the lobby: staying
+// in the pool costs them points. Players are kept away from the opponent
+// they just played for a while, and then paired with them again rather
+// than left waiting.
+var arenaPoolConfig = matchmaking.Config{
+	InitialWindow: 200,
+	WindowGrowth:  25,
+	MaxWindow:     2000,
+	AvoidFor:      15 * time.Second,
+}
+
+// arena is a running arena tournament and the pool its idle players wait
+// in. The pool is the tournament's own: arena players never meet lobby
+// seeks.
+type arena struct {
+	tournament *store.Tournament
+	settings   GameSettings
+	pool       *matchmaking.Pool[string]
+}
+
+// arenaEnd is when a running arena stops pairing. Games still being played
+// then count when they finish.
+func arenaEnd(t *store.Tournament) time.Time {
+	started := time.Now()
+	if t.StartedAt != nil {
+		started = *t.StartedAt
+	}
+	return started.Add(time.Duration(t.DurationMinutes) * time.Minute)
+}
+
+// startArena opens the arena and puts every connected player in its pool.
+// The caller must hold gm.tournamentMu.
+func (gm *GameManager) startArena(t *store.Tournament) error {
+	ctx := context.Background()
+	registered, err := gm.tournamentStore.ListTournamentPlayers(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	if len(contenders(registered, nil)) < 2 {
+		return ErrTooFewPlayers
+	}
+
+	if err := gm.tournamentStore.StartTournamentRound(ctx, t.ID, 1, nil); err != nil {
+		log.Printf("Failed to start arena %s: %v", t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	started, err := gm.getTournament(t.ID)
+	if err != nil {
+		return err
+	}
+	a, err := gm.openArena(started)
+	if err != nil {
+		return err
+	}
+
+	gm.pushArenaStandings(started, registered, nil, false)
+	gm.fillArena(a, registered, nil)
+
+	log.Printf("Arena %s started for %d minutes", t.ID, t.DurationMinutes)
+	return nil
+}
+
+// openArena returns the running arena, setting up its pool if it has none
+// yet, as after a restart. The caller must hold gm.tournamentMu.
+func (gm *GameManager) openArena(t *store.Tournament) (*arena, error) {
+	if a := gm.arenas[t.ID]; a != nil {
+		return a, nil
+	}
+	settings, err := tournamentSettings(t)
+	if err != nil {
+		return nil, err
+	}
+	settings.Arena = true
+	a := &arena{
+		tournament: t,
+		settings:   settings,
+		pool:       matchmaking.NewPool[string](arenaPoolConfig),
+	}
+	gm.arenas[t.ID] = a
+	return a, nil
+}
+
+// advanceArena closes the arena when its time is up and finishes it once
+// the last game has ended. Until then it puts players who are connected
+// but neither playing nor waiting back in the pool, such as those who
+// reconnected. The caller must hold gm.tournamentMu.
+func (gm *GameManager) advanceArena(t *store.Tournament, now time.Time) {
+	ctx := context.Background()
+	pairings, err := gm.tournamentStore.ListTournamentPairings(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to fetch pairings of tournament %s: %v", t.ID, err)
+		return
+	}
+
+	if !now.Before(arenaEnd(t)) {
+		delete(gm.arenas, t.ID)
+		if !playing(pairings) {
+			gm.finishTournament(t)
+		}
+		return
+	}
+
+	a, err := gm.openArena(t)
+	if err != nil {
+		log.Printf("Failed to open arena %s: %v", t.ID, err)
+		return
+	}
+	registered, err := gm.tournamentStore.ListTournamentPlayers(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", t.ID, err)
+		return
+	}
+	gm.fillArena(a, registered, pairings)
+}
+
+// arenaGameOver pushes the new standings and pairs both players again
+// straight away, keeping them apart from each other for a while. Once time
+// is up the last game to end finishes the arena. The caller must hold
+// gm.tournamentMu.
+func (gm *GameManager) arenaGameOver(t *store.Tournament, result tournament.Pairing, pairings []tournament.Pairing) {
+	ended := !time.Now().Before(arenaEnd(t))
+	if ended && !playing(pairings) {
+		gm.finishTournament(t)
+		return
+	}
+
+	registered, err := gm.tournamentStore.ListTournamentPlayers(context.Background(), t.ID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", t.ID, err)
+		return
+	}
+	gm.pushArenaStandings(t, registered, pairings, false)
+
+	a := gm.arenas[t.ID]
+	if ended || a == nil {
+		return
+	}
+	active := make(map[string]tournament.Player)
+	for _, p := range registered {
+		if !p.Withdrawn {
+			active[p.ID] = p.Player
+		}
+	}
+	for _, p := range pairings {
+		if p.GameID != result.GameID {
+			continue
+		}
+		for _, side := range [][2]string{{p.White, p.Black}, {p.Black, p.White}} {
+			if player, ok := active[side[0]]; ok {
+				gm.enterArena(a, player, side[1])
+			}
+		}
+	}
+}
+
+// fillArena puts every player who has not withdrawn and is not playing an
+// arena game into the pool. The caller must hold gm.tournamentMu.
+func (gm *GameManager) fillArena(a *arena, registered []*store.TournamentPlayer, pairings []tournament.Pairing) {
+	busy := make(map[string]bool)
+	for _, p := range pairings {
+		if !p.Finished() {
+			busy[p.White] = true
+			busy[p.Black] = true
+		}
+	}
+	for _, p := range registered {
+		if !p.Withdrawn && !busy[p.ID] && !a.pool.Contains(p.ID) {
+			gm.enterArena(a, p.Player, "")
+		}
+	}
+}
+
+// enterArena queues a connected player in the arena's pool, avoiding
+// opponent for a while, and starts their game if someone is waiting for
+// them. The caller must hold gm.tournamentMu.
+func (gm *GameManager) enterArena(a *arena, player tournament.Player, avoid string) {
+	gm.mu.RLock()
+	connected := gm.isConnected(player.ID)
+	gm.mu.RUnlock()
+	if !connected {
+		return
+	}
+
+	now := time.Now()
+	match, err := a.pool.Add(&matchmaking.Seek[string]{
+		UserID:    player.ID,
+		Key:       a.tournament.ID,
+		Rating:    player.Rating,
+		CreatedAt: now,
+		Avoid:     avoid,
+	}, now)
+	if err != nil || match == nil {
+		return
+	}
+	gm.startArenaGame(a, match)
+}
+
+// RunArenaMatchmaker pairs the players waiting in every running arena
+// whose rating windows have widened enough to match.
+func (gm *GameManager) RunArenaMatchmaker() {
+	ticker := time.NewTicker(matchmakingInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		gm.tournamentMu.Lock()
+		for _, a := range gm.arenas {
+			matches := a.pool.Sweep(now)
+			for i := range matches {
+				gm.startArenaGame(a, &matches[i])
+			}
+		}
+		gm.tournamentMu.Unlock()
+	}
+}
+
+// startArenaGame starts the game of a match with random colors and adds it
+// to the arena's pairings. If one of the players disconnected in the
+// meantime the other goes back to the pool. The caller must hold
+// gm.tournamentMu.
+func (gm *GameManager) startArenaGame(a *arena, match *matchmaking.Match[string]) {
+	gm.mu.Lock()
+	var present []*matchmaking.Seek[string]
+	for _, seek := range []*matchmaking.Seek[string]{match.First, match.Second} {
+		if gm.isConnected(seek.UserID) {
+			present = append(present, seek)
+		}
+	}
+	if len(present) < 2 {
+		gm.mu.Unlock()
+		for _, seek := range present {
+			if next, err := a.pool.Add(seek, time.Now()); err == nil && next != nil {
+				gm.startArenaGame(a, next)
+			}
+		}
+		return
+	}
+
+	white, black := match.First.UserID, match.Second.UserID
+	if rand.Intn(2) == 0 {
+		white, black = black, white
+	}
+	game := gm.startGame(white, black, a.settings, nil, a.tournament.ID)
+	gm.mu.Unlock()
+
+	pairing := tournament.Pairing{Round: 1, Game: 1, White: white, Black: black, GameID: game.ID, Result: tournament.Pending}
+	if err := gm.tournamentStore.AddTournamentPairing(context.Background(), a.tournament.ID, &pairing); err != nil {
+		log.Printf("Failed to store arena game %s of tournament %s: %v", game.ID, a.tournament.ID, err)
+	}
+	gm.announceRound(a.tournament, []tournament.Pairing{pairing})
+}
+
+// pushArenaStandings sends the arena's standings to every player still in
+// it who is connected.
+func (gm *GameManager) pushArenaStandings(t *store.Tournament, registered []*store.TournamentPlayer, pairings []tournament.Pairing, finished bool) {
+	msg := OutgoingArenaStandings{
+		Type:         ARENA_STANDINGS,
+		TournamentID: t.ID,
+		EndsAt:       arenaEnd(t).UnixMilli(),
+		Finished:     finished,
+		Standings:    tournament.ArenaStandings(contenders(registered, pairings), pairings),
+	}
+
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	for _, p := range registered {
+		if p.Withdrawn {
+			continue
+		}
+		if session, ok := gm.sessions[p.ID]; ok && session.Conn != nil {
+			session.Conn.WriteJSON(msg)
+		}
+	}
+}
+
+// playing reports whether any game of the pairings is still going on.
+func playing(pairings []tournament.Pairing) bool {
+	for _, p := range pairings {
+		if !p.Finished() {
+			return true
+		}
+	}
+	return false
+}
+
+// Berserk halves the player's clock and takes away their increment, for an
+// extra point if they win. Arena players may go berserk before their first
+// move.
+func (g *Game) Berserk(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if !g.settings.Arena || g.settings.TimeControl.Base == 0 {
+		return ErrBerserkNotAllowed
+	}
+	white := session.UserID == g.WhiteUserID
+	if (white && g.moveNumber > 0) || (!white && g.moveNumber > 1) {
+		return ErrBerserkTooLate
+	}
+	if g.clock.berserked(white) {
+		return ErrAlreadyBerserk
+	}
+
+	now := time.Now()
+	running := g.clock.running() && g.clock.whiteRunning == white
+	g.clock.berserk(white, now)
+	if running {
+		g.startClock(gm, now)
+	}
+	if err := gm.gameStore.SetGameBerserk(context.Background(), g.ID, white); err != nil {
+		log.Printf("Failed to store berserk of game %s: %v", g.ID, err)
+	}
+
+	color := ColorBlack
+	if white {
+		color = ColorWhite
+	}
+	whiteMs, blackMs := g.clock.millis(now)
+	g.publish(gm, OutgoingBerserk{Type: BERSERK, GameID: g.ID, Color: color, WhiteTime: whiteMs, BlackTime: blackMs})
+	return nil
+}
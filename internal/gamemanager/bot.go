@@ -0,0 +1,144 @@
+package gamemanager
+
+import (
+	"context"
+	"errors"
+	"log"
+	mrand "math/rand"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/engine"
+	"github.com/notnil/chess"
+)
+
+const (
+	// botMinThink is the least time a bot is given to think about a move.
+	botMinThink = 20 * time.Millisecond
+	// botMovesLeft is how many moves the bot assumes it still has to make
+	// when budgeting its clock.
+	botMovesLeft = 40
+)
+
+var (
+	ErrNotABot    = errors.New("opponent is not a computer player")
+	ErrBotVariant = errors.New("computer players only play variants with standard rules")
+)
+
+// Bot picks moves for a computer player. Bots play as ordinary users: their
+// games are created, persisted and rated by the same code as human games.
+type Bot interface {
+	BestMove(ctx context.Context, board *chess.Game) (*chess.Move, error)
+}
+
+// RegisterBot makes userID a computer player driven by bot.
+func (gm *GameManager) RegisterBot(userID string, bot Bot) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.bots[userID] = bot
+}
+
+// PlayComputer starts a casual game against the computer player botID, such
+// as engine.PlayerID(level) for the built-in engine. color is the color the
+// user wants to play.
+func (gm *GameManager) PlayComputer(session *PlayerSession, botID string, color string, settings GameSettings) error {
+	var userIsWhite bool
+	switch color {
+	case ColorWhite:
+		userIsWhite = true
+	case ColorBlack:
+	case "", ColorRandom:
+		userIsWhite = mrand.Intn(2) == 0
+	default:
+		return ErrInvalidColor
+	}
+	if !settings.Variant.StandardRules() {
+		return ErrBotVariant
+	}
+
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	if _, ok := gm.bots[botID]; !ok {
+		return ErrNotABot
+	}
+	if gm.busy(session.UserID, settings) {
+		return ErrPlayerBusy
+	}
+	gm.pool.Cancel(session.UserID)
+
+	white, black := session.UserID, botID
+	if !userIsWhite {
+		white, black = black, white
+	}
+	settings.Rated = false
+	gm.startGame(white, black, settings, nil, "")
+	return nil
+}
+
+// handlePlayComputer plays the registered bot given as opponent_id, or else
+// the built-in engine at the requested level.
+func (gm *GameManager) handlePlayComputer(session *PlayerSession, message IncomingMessage) {
+	botID := message.OpponentID
+	if botID == "" {
+		if _, err := engine.LevelFor(message.Level); err != nil {
+			session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+			return
+		}
+		botID = engine.PlayerID(message.Level)
+	}
+
+	settings, err := ParseGameSettings(message.TimeControl, false, message.Variant, message.FEN)
+	if err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+		return
+	}
+	if err := gm.PlayComputer(session, botID, message.Color, settings); err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+	}
+}
+
+// isBot reports whether userID is the computer side of this game.
+func (g *Game) isBot(userID string) bool {
+	return g.bot != nil && userID == g.botID
+}
+
+// scheduleBotMove starts the bot thinking if it is its turn. The move is
+// played through the same path as a human move, and dropped if the position
+// changed in the meantime, e.g. after a takeback, or if the game was replaced
+// by a restored copy, whose own bot plays instead. The caller must hold g.mu.
+func (g *Game) scheduleBotMove(gm *GameManager) {
+	if g.status != GameStatusInProgress || g.bot == nil {
+		return
+	}
+	pos := g.board.Position()
+	botIsWhite := pos.Turn() == chess.White
+	if (botIsWhite && g.WhiteUserID != g.botID) || (!botIsWhite && g.BlackUserID != g.botID) {
+		return
+	}
+
+	budget := g.clock.remaining(botIsWhite, time.Now())/botMovesLeft + g.clock.increment/2
+	if budget < botMinThink {
+		budget = botMinThink
+	}
+	board := g.board.Game().Clone()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), budget)
+		defer cancel()
+
+		mv, err := g.bot.BestMove(ctx, board)
+		if err != nil {
+			log.Printf("Bot %s failed to move in game %s: %v", g.botID, g.ID, err)
+			return
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.superseded || g.status != GameStatusInProgress || g.board.Position() != pos {
+			return
+		}
+		if err := g.makeMove(gm, g.botID, mv.String()); err != nil {
+			log.Printf("Bot %s played an illegal move in game %s: %v", g.botID, g.ID, err)
+		}
+	}()
+}
@@ -0,0 +1,278 @@
+package gamemanager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	mrand "math/rand"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrChallengeNotFound = errors.New("challenge not found or expired")
+	ErrChallengeSelf     = errors.New("you cannot challenge yourself")
+	ErrOpponentNotFound  = errors.New("opponent not found")
+	ErrChallengeBot      = errors.New("computer players cannot be challenged, play them instead")
+	ErrChallengeFailed   = errors.New("failed to create challenge")
+	ErrNotYourChallenge  = errors.New("this challenge is not addressed to you")
+	ErrInvalidColor      = errors.New("color must be white, black or random")
+	ErrPlayerBusy        = errors.New("player is already in as many live games as allowed")
+	ErrPlayerOffline     = errors.New("both players must be connected to start the game")
+)
+
+const (
+	ColorWhite  = "white"
+	ColorBlack  = "black"
+	ColorRandom = "random"
+
+	challengeTTL = 10 * time.Minute
+)
+
+// Challenge is a game offer to a specific user, or to anyone holding the
+// link when OpponentID is empty.
+type Challenge struct {
+	ID           string    `json:"id"`
+	ChallengerID string    `json:"challenger_id"`
+	OpponentID   string    `json:"opponent_id,omitempty"`
+	Color        string    `json:"color"`
+	TimeControl  string    `json:"time_control"`
+	Rated        bool      `json:"rated"`
+	Variant      string    `json:"variant"`
+	FEN          string    `json:"fen,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+
+	settings GameSettings
+}
+
+func (c *Challenge) Open() bool {
+	return c.OpponentID == ""
+}
+
+type ChallengeOptions struct {
+	OpponentID  string
+	Color       string
+	TimeControl string
+	Rated       bool
+	Variant     string
+	FEN         string
+}
+
+// CreateChallenge stores a new challenge and, for direct challenges, delivers
+// it to the opponent if they are connected. The opponent must be another
+// user, and not a computer player.
+func (gm *GameManager) CreateChallenge(challengerID string, opts ChallengeOptions) (*Challenge, error) {
+	if opts.OpponentID == challengerID {
+		return nil, ErrChallengeSelf
+	}
+	if err := gm.checkOpponent(opts.OpponentID); err != nil {
+		return nil, err
+	}
+
+	color := opts.Color
+	if color == "" {
+		color = ColorRandom
+	}
+	if color != ColorWhite && color != ColorBlack && color != ColorRandom {
+		return nil, ErrInvalidColor
+	}
+
+	settings, err := ParseGameSettings(opts.TimeControl, opts.Rated, opts.Variant, opts.FEN)
+	if err != nil {
+		return nil, err
+	}
+	tc := settings.TimeControl
+
+	now := time.Now()
+	ttl := challengeTTL
+	if tc.Correspondence() {
+		ttl = correspondenceChallengeTTL
+	}
+	challenge := &Challenge{
+		ID:           newChallengeToken(),
+		ChallengerID: challengerID,
+		OpponentID:   opts.OpponentID,
+		Color:        color,
+		TimeControl:  tc.String(),
+		Rated:        opts.Rated,
+		Variant:      string(settings.Variant),
+		FEN:          settings.FEN,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+		settings:     settings,
+	}
+
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	if _, ok := gm.bots[challenge.OpponentID]; ok {
+		return nil, ErrChallengeBot
+	}
+	gm.pruneChallenges(now)
+	gm.challenges[challenge.ID] = challenge
+
+	if !challenge.Open() {
+		if session, ok := gm.sessions[challenge.OpponentID]; ok && session.Conn != nil {
+			session.Conn.WriteJSON(OutgoingChallenge{Type: CHALLENGE, Challenge: challenge})
+		}
+	}
+
+	log.Printf("Challenge %s created by %s (opponent: %q, %s)", challenge.ID, challengerID, challenge.OpponentID, tc)
+	return challenge, nil
+}
+
+// checkOpponent makes sure the opponent of a direct challenge is a user.
+func (gm *GameManager) checkOpponent(opponentID string) error {
+	if opponentID == "" {
+		return nil
+	}
+	if _, err := uuid.Parse(opponentID); err != nil {
+		return ErrOpponentNotFound
+	}
+	if _, err := gm.userStore.GetUserByID(context.Background(), opponentID); err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return ErrOpponentNotFound
+		}
+		log.Printf("Failed to get opponent: %v", err)
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+func (gm *GameManager) GetChallenge(id string) (*Challenge, error) {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	challenge, ok := gm.challenges[id]
+	if !ok || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+	return challenge, nil
+}
+
+// AcceptChallenge starts the game between the challenger and userID. Both
+// players must be connected and free, except in correspondence games, which
+// the challenger can pick up whenever they next look in.
+func (gm *GameManager) AcceptChallenge(userID string, id string) (*Game, error) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	challenge, ok := gm.challenges[id]
+	if !ok || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+	if userID == challenge.ChallengerID {
+		return nil, ErrChallengeSelf
+	}
+	if !challenge.Open() && userID != challenge.OpponentID {
+		return nil, ErrNotYourChallenge
+	}
+
+	for _, playerID := range []string{challenge.ChallengerID, userID} {
+		if !gm.isConnected(playerID) && !challenge.settings.TimeControl.Correspondence() {
+			return nil, ErrPlayerOffline
+		}
+		if gm.busy(playerID, challenge.settings) {
+			return nil, ErrPlayerBusy
+		}
+	}
+
+	delete(gm.challenges, id)
+	gm.pool.Cancel(challenge.ChallengerID)
+	gm.pool.Cancel(userID)
+
+	white, black := challenge.ChallengerID, userID
+	switch challenge.Color {
+	case ColorBlack:
+		white, black = black, white
+	case ColorRandom:
+		if mrand.Intn(2) == 0 {
+			white, black = black, white
+		}
+	}
+
+	return gm.startGame(white, black, challenge.settings, nil, ""), nil
+}
+
+func (gm *GameManager) DeclineChallenge(userID string, id string) error {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	challenge, ok := gm.challenges[id]
+	if !ok {
+		return ErrChallengeNotFound
+	}
+
+	switch {
+	case userID == challenge.ChallengerID:
+		// Withdrawing your own challenge.
+	case !challenge.Open() && userID == challenge.OpponentID:
+		if session, ok := gm.sessions[challenge.ChallengerID]; ok && session.Conn != nil {
+			session.Conn.WriteJSON(OutgoingChallengeUpdate{Type: CHALLENGE_DECLINED, ChallengeID: id})
+		}
+	default:
+		return ErrNotYourChallenge
+	}
+
+	delete(gm.challenges, id)
+	return nil
+}
+
+// sendPendingChallenges delivers direct challenges that arrived while the
+// user was offline. The caller must hold gm.mu.
+func (gm *GameManager) sendPendingChallenges(session *PlayerSession) {
+	now := time.Now()
+	for _, challenge := range gm.challenges {
+		if challenge.OpponentID == session.UserID && now.Before(challenge.ExpiresAt) {
+			session.Conn.WriteJSON(OutgoingChallenge{Type: CHALLENGE, Challenge: challenge})
+		}
+	}
+}
+
+// pruneChallenges drops expired challenges. The caller must hold gm.mu.
+func (gm *GameManager) pruneChallenges(now time.Time) {
+	for id, challenge := range gm.challenges {
+		if now.After(challenge.ExpiresAt) {
+			delete(gm.challenges, id)
+		}
+	}
+}
+
+func (gm *GameManager) handleCreateChallenge(session *PlayerSession, message IncomingMessage) {
+	challenge, err := gm.CreateChallenge(session.UserID, ChallengeOptions{
+		OpponentID:  message.OpponentID,
+		Color:       message.Color,
+		TimeControl: message.TimeControl,
+		Rated:       message.Rated,
+		Variant:     message.Variant,
+		FEN:         message.FEN,
+	})
+	if err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+		return
+	}
+	session.Conn.WriteJSON(OutgoingChallengeUpdate{Type: CHALLENGE_CREATED, ChallengeID: challenge.ID})
+}
+
+func (gm *GameManager) handleAcceptChallenge(session *PlayerSession, challengeID string) {
+	if _, err := gm.AcceptChallenge(session.UserID, challengeID); err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+	}
+}
+
+func (gm *GameManager) handleDeclineChallenge(session *PlayerSession, challengeID string) {
+	if err := gm.DeclineChallenge(session.UserID, challengeID); err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+	}
+}
+
+func newChallengeToken() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,160 @@
+package gamemanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Adi-ty/chess/internal/chat"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/notnil/chess"
+)
+
+// Players and spectators chat in separate rooms on the game channel: players
+// never see the spectator room and spectators never see the player room.
+const (
+	RoomPlayer    = "player"
+	RoomSpectator = "spectator"
+)
+
+var (
+	ErrChatEmpty       = errors.New("chat message cannot be empty")
+	ErrChatTooLong     = errors.New("chat message is too long")
+	ErrChatRateLimited = errors.New("you are sending messages too quickly")
+	ErrChatMuted       = errors.New("your chat is muted")
+	ErrChatBlocked     = errors.New("message was blocked")
+	ErrNotInRoom       = errors.New("you are not playing or spectating this game")
+)
+
+// SetChatFilter replaces the filter chat lines pass through before delivery.
+func (gm *GameManager) SetChatFilter(filter chat.Filter) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.chatFilter = filter
+}
+
+// Chat posts text to the game's player room if session is playing gameID or
+// to its spectator room if session is watching it. Every line is stored,
+// including the ones the filter blocks, so that moderators can review them.
+func (gm *GameManager) Chat(session *PlayerSession, gameID, text string) error {
+	if session.chatMuted.Load() {
+		return ErrChatMuted
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ErrChatEmpty
+	}
+	if utf8.RuneCountInString(text) > chat.MaxLength {
+		return ErrChatTooLong
+	}
+
+	gm.mu.RLock()
+	game, exists := gm.games[gameID]
+	filter := gm.chatFilter
+	gm.mu.RUnlock()
+	if !exists {
+		return ErrGameNotFound
+	}
+
+	room := RoomSpectator
+	if game.colorOf(session.UserID) != chess.NoColor {
+		room = RoomPlayer
+	} else if _, watching := session.spectating[gameID]; !watching {
+		return ErrNotInRoom
+	}
+
+	now := time.Now()
+	if !session.chatLimiter.Allow(now) {
+		return ErrChatRateLimited
+	}
+
+	filtered, allowed := filter.Filter(text)
+	err := gm.chatStore.InsertChatMessage(context.Background(), &store.ChatMessage{
+		GameID:    gameID,
+		UserID:    session.UserID,
+		Room:      room,
+		Body:      text,
+		Blocked:   !allowed,
+		CreatedAt: now,
+	})
+	if err != nil {
+		log.Printf("Failed to store chat message for game %s: %v", gameID, err)
+	}
+	if !allowed {
+		return ErrChatBlocked
+	}
+
+	game.publish(gm, OutgoingChat{
+		Type:   CHAT,
+		GameID: gameID,
+		Room:   room,
+		UserID: session.UserID,
+		Text:   filtered,
+		SentAt: now.UnixMilli(),
+	})
+	return nil
+}
+
+// SetChatMuted turns chat off or back on for a user. A muted user neither
+// sends nor receives chat in any game.
+func (gm *GameManager) SetChatMuted(session *PlayerSession, muted bool) error {
+	if err := gm.chatStore.SetChatMuted(context.Background(), session.UserID, muted); err != nil {
+		return err
+	}
+	session.chatMuted.Store(muted)
+	return nil
+}
+
+func (gm *GameManager) handleChat(session *PlayerSession, message IncomingMessage) {
+	gameID := message.GameID
+	if gameID == "" {
+		gm.mu.RLock()
+		game, err := gm.gameFor(session, "")
+		gm.mu.RUnlock()
+		if err != nil {
+			session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+			return
+		}
+		gameID = game.ID
+	}
+	if err := gm.Chat(session, gameID, message.Text); err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: err.Error()})
+	}
+}
+
+func (gm *GameManager) handleMuteChat(session *PlayerSession, muted bool) {
+	if err := gm.SetChatMuted(session, muted); err != nil {
+		log.Printf("Failed to update chat mute for %s: %v", session.UserID, err)
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "failed to update chat setting"})
+		return
+	}
+	session.Conn.WriteJSON(OutgoingChatMuted{Type: CHAT_MUTED, Muted: muted})
+}
+
+// deliverable reports whether a payload from the game channel should be
+// forwarded to a member of room. session is nil for anonymous spectators.
+// Spectate markers are never forwarded.
+func deliverable(payload string, room string, session *PlayerSession) bool {
+	var envelope struct {
+		Type string `json:"type"`
+		Room string `json:"room"`
+	}
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		return true
+	}
+	if envelope.Type == SPECTATE_MARKER {
+		return false
+	}
+	if envelope.Type != CHAT {
+		return true
+	}
+	if session != nil && session.chatMuted.Load() {
+		return false
+	}
+	return envelope.Room == room
+}
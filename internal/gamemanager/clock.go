@@ -0,0 +1,238 @@
+package gamemanager
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTimeControl = errors.New("invalid time control, expected minutes+increment e.g. 3+2, or days per move e.g. 3d")
+)
+
+const (
+	maxBase        = 180 * time.Minute
+	maxIncrement   = 180 * time.Second
+	maxDaysPerMove = 14
+)
+
+// DefaultTimeControl is used when init_game does not specify one.
+var DefaultTimeControl = TimeControl{Base: 10 * time.Minute}
+
+// TimeControl is either a clock, Base plus Increment per move, or for
+// correspondence games a number of days for each move.
+type TimeControl struct {
+	Base        time.Duration
+	Increment   time.Duration
+	DaysPerMove int
+}
+
+// ParseTimeControl parses the "minutes+seconds" form used by clients, e.g.
+// "3+2" for three minutes with a two second increment or "0.5+0" for 30s,
+// and the "days" form of correspondence games, e.g. "3d".
+func ParseTimeControl(s string) (TimeControl, error) {
+	if s == "" {
+		return DefaultTimeControl, nil
+	}
+
+	if daysStr, ok := strings.CutSuffix(strings.TrimSpace(s), "d"); ok {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxDaysPerMove {
+			return TimeControl{}, ErrInvalidTimeControl
+		}
+		return TimeControl{DaysPerMove: days}, nil
+	}
+
+	baseStr, incStr, ok := strings.Cut(strings.TrimSpace(s), "+")
+	if !ok {
+		return TimeControl{}, ErrInvalidTimeControl
+	}
+
+	minutes, err := strconv.ParseFloat(baseStr, 64)
+	if err != nil || minutes < 0 {
+		return TimeControl{}, ErrInvalidTimeControl
+	}
+	seconds, err := strconv.Atoi(incStr)
+	if err != nil || seconds < 0 {
+		return TimeControl{}, ErrInvalidTimeControl
+	}
+
+	tc := TimeControl{
+		Base:      time.Duration(minutes * float64(time.Minute)),
+		Increment: time.Duration(seconds) * time.Second,
+	}
+	if tc.Base > maxBase || tc.Increment > maxIncrement || tc.Base+tc.Increment <= 0 {
+		return TimeControl{}, ErrInvalidTimeControl
+	}
+
+	return tc, nil
+}
+
+// Correspondence reports whether moves have a deadline in days rather than
+// a clock.
+func (tc TimeControl) Correspondence() bool {
+	return tc.DaysPerMove > 0
+}
+
+// MoveTime is how long a correspondence player has for each move.
+func (tc TimeControl) MoveTime() time.Duration {
+	return time.Duration(tc.DaysPerMove) * 24 * time.Hour
+}
+
+func (tc TimeControl) String() string {
+	if tc.Correspondence() {
+		return strconv.Itoa(tc.DaysPerMove) + "d"
+	}
+	return strconv.FormatFloat(tc.Base.Minutes(), 'f', -1, 64) + "+" + strconv.Itoa(int(tc.Increment.Seconds()))
+}
+
+// PGN returns the time control in the PGN TimeControl tag format ("180+2").
+// Correspondence games have no clock, which PGN writes as "-".
+func (tc TimeControl) PGN() string {
+	if tc.Correspondence() {
+		return "-"
+	}
+	return fmt.Sprintf("%d+%d", int(tc.Base.Seconds()), int(tc.Increment.Seconds()))
+}
+
+// clock tracks the remaining time of both players. It is not safe for
+// concurrent use; Game guards it with its own mutex.
+type clock struct {
+	white     time.Duration
+	black     time.Duration
+	increment time.Duration
+
+	// whiteBerserk and blackBerserk mark a side that gave up half its time
+	// and its increment.
+	whiteBerserk bool
+	blackBerserk bool
+
+	// whiteRunning tells whose time turnStart refers to. turnStart is zero
+	// while the clock is stopped (before White's first move and after the
+	// game ends).
+	whiteRunning bool
+	turnStart    time.Time
+	timer        *time.Timer
+}
+
+func newClock(tc TimeControl) *clock {
+	return &clock{
+		white:     tc.Base,
+		black:     tc.Base,
+		increment: tc.Increment,
+	}
+}
+
+func (c *clock) running() bool {
+	return !c.turnStart.IsZero()
+}
+
+func (c *clock) remaining(white bool, now time.Time) time.Duration {
+	r := c.black
+	if white {
+		r = c.white
+	}
+	if c.running() && white == c.whiteRunning {
+		r -= now.Sub(c.turnStart)
+	}
+	if r < 0 {
+		return 0
+	}
+	return r
+}
+
+// flagged reports whether the running side has run out of time.
+func (c *clock) flagged(now time.Time) bool {
+	return c.running() && c.remaining(c.whiteRunning, now) <= 0
+}
+
+// punch stops the running side's clock, charging the elapsed time and adding
+// the increment.
+func (c *clock) punch(now time.Time) {
+	if !c.running() {
+		return
+	}
+	increment := c.increment
+	if c.berserked(c.whiteRunning) {
+		increment = 0
+	}
+	c.set(c.whiteRunning, c.remaining(c.whiteRunning, now)+increment)
+	c.halt()
+}
+
+// berserk halves the side's remaining time and takes away its increment. If
+// the side's clock is running it goes on from now, and the caller has to
+// start it again to re-arm the flag.
+func (c *clock) berserk(white bool, now time.Time) {
+	remaining := c.remaining(white, now)
+	if c.running() && c.whiteRunning == white {
+		c.turnStart = now
+	}
+	c.set(white, remaining/2)
+	if white {
+		c.whiteBerserk = true
+	} else {
+		c.blackBerserk = true
+	}
+}
+
+// restoreBerserk marks the sides of a restored game that went berserk. A
+// berserk side gets no increment, so it never has more than half the base
+// time: if it has, it went berserk after its time was stored and the time
+// is halved.
+func (c *clock) restoreBerserk(white, black bool, base time.Duration) {
+	c.whiteBerserk, c.blackBerserk = white, black
+	if white && c.white > base/2 {
+		c.white /= 2
+	}
+	if black && c.black > base/2 {
+		c.black /= 2
+	}
+}
+
+func (c *clock) berserked(white bool) bool {
+	if white {
+		return c.whiteBerserk
+	}
+	return c.blackBerserk
+}
+
+// start begins the turn of the given side at since and arms onFlag to fire
+// when its time runs out.
+func (c *clock) start(white bool, since time.Time, onFlag func()) {
+	c.halt()
+	c.whiteRunning = white
+	c.turnStart = since
+	c.timer = time.AfterFunc(c.remaining(white, time.Now()), onFlag)
+}
+
+// stop freezes both clocks, charging the running side for its elapsed time.
+func (c *clock) stop(now time.Time) {
+	if c.running() {
+		c.set(c.whiteRunning, c.remaining(c.whiteRunning, now))
+	}
+	c.halt()
+}
+
+func (c *clock) set(white bool, d time.Duration) {
+	if white {
+		c.white = d
+	} else {
+		c.black = d
+	}
+}
+
+func (c *clock) halt() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.turnStart = time.Time{}
+}
+
+// millis returns the current remaining time of White and Black in milliseconds.
+func (c *clock) millis(now time.Time) (int64, int64) {
+	return c.remaining(true, now).Milliseconds(), c.remaining(false, now).Milliseconds()
+}
@@ -0,0 +1,170 @@
+package gamemanager
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/notnil/chess"
+)
+
+const (
+	// deadlineInterval is how often overdue correspondence games are looked
+	// for. Deadlines are days apart, so a minute late is on time enough.
+	deadlineInterval = time.Minute
+
+	// correspondenceChallengeTTL is how long a correspondence challenge
+	// waits for an opponent who may only look in once a day.
+	correspondenceChallengeTTL = 7 * 24 * time.Hour
+)
+
+// RunDeadlineScheduler periodically ends the correspondence games whose
+// player to move let the deadline pass. Overdue games are found in the
+// store, so games nobody has opened since the server started are timed out
+// as well. gm.mu is only held to look games up and put restored ones in
+// place; the store and Redis are used without it.
+func (gm *GameManager) RunDeadlineScheduler() {
+	ticker := time.NewTicker(deadlineInterval)
+	defer ticker.Stop()
+
+	retries := make(deadlineRetries)
+	for now := range ticker.C {
+		overdue, err := gm.gameStore.ListOverdueGames(context.Background(), now)
+		if err != nil {
+			log.Printf("Failed to fetch overdue games: %v", err)
+			continue
+		}
+
+		live := make(map[string]*Game, len(overdue))
+		gm.mu.RLock()
+		for _, dbGame := range overdue {
+			if game, exists := gm.games[dbGame.ID]; exists {
+				live[dbGame.ID] = game
+			}
+		}
+		gm.mu.RUnlock()
+
+		listed := make(map[string]bool, len(overdue))
+		for _, dbGame := range overdue {
+			listed[dbGame.ID] = true
+
+			game := live[dbGame.ID]
+			if game != nil && game.resultPending() {
+				continue
+			}
+			if game != nil && !game.IsActive() {
+				// Ended here already, so storing the result failed.
+				game = nil
+				if !retries.due(dbGame.ID, now) {
+					continue
+				}
+				retries.failed(dbGame.ID, now)
+			} else if _, failing := retries[dbGame.ID]; failing && !retries.due(dbGame.ID, now) {
+				continue
+			}
+
+			if game == nil {
+				if game, err = gm.adoptGame(dbGame); err != nil {
+					log.Printf("Failed to restore overdue game %s: %v", dbGame.ID, err)
+					retries.failed(dbGame.ID, now)
+					continue
+				}
+			}
+
+			game.mu.Lock()
+			game.expireDeadline(gm, now)
+			game.mu.Unlock()
+		}
+
+		for id := range retries {
+			if !listed[id] {
+				delete(retries, id)
+			}
+		}
+	}
+}
+
+// adoptGame loads an overdue game from the store and puts it in place of
+// any copy in memory, unless a live one turned up in the meantime.
+func (gm *GameManager) adoptGame(dbGame *store.Game) (*Game, error) {
+	game, moves, err := gm.loadGame(dbGame)
+	if err != nil {
+		return nil, err
+	}
+
+	gm.mu.Lock()
+	if current, exists := gm.games[dbGame.ID]; exists && current.IsActive() {
+		gm.mu.Unlock()
+		return current, nil
+	}
+	pubsub := gm.installGame(game)
+	gm.mu.Unlock()
+
+	if pubsub != nil {
+		gm.listen(game.ID, pubsub)
+	}
+	game.resume(gm, moves)
+	return game, nil
+}
+
+// deadlineRetries backs off overdue games that could not be ended, so that
+// a failing store is not asked again every minute.
+type deadlineRetries map[string]deadlineRetry
+
+type deadlineRetry struct {
+	next time.Time
+	wait time.Duration
+}
+
+// maxDeadlineRetryWait caps the backoff of a game that keeps failing.
+const maxDeadlineRetryWait = time.Hour
+
+// due reports whether the game may be tried again.
+func (r deadlineRetries) due(gameID string, now time.Time) bool {
+	return !now.Before(r[gameID].next)
+}
+
+// failed puts the game off for twice as long as last time.
+func (r deadlineRetries) failed(gameID string, now time.Time) {
+	wait := min(2*r[gameID].wait, maxDeadlineRetryWait)
+	if wait == 0 {
+		wait = deadlineInterval
+	}
+	r[gameID] = deadlineRetry{next: now.Add(wait), wait: wait}
+}
+
+// setDeadline gives the player to move until deadline and stores it. The
+// caller must hold g.mu.
+func (g *Game) setDeadline(gm *GameManager, deadline time.Time) {
+	g.deadline = deadline
+	if err := gm.gameStore.SetMoveDeadline(context.Background(), g.ID, deadline); err != nil {
+		log.Printf("Failed to store move deadline of game %s: %v", g.ID, err)
+	}
+}
+
+// expireDeadline ends a correspondence game whose player to move missed the
+// deadline: as a loss, or without a result if the game never got going. It
+// reports whether the game ended. The caller must hold g.mu.
+func (g *Game) expireDeadline(gm *GameManager, now time.Time) bool {
+	if g.status != GameStatusInProgress || g.deadline.IsZero() || now.Before(g.deadline) {
+		return false
+	}
+
+	if g.abortable() {
+		g.endGame(gm, GameStatusAborted, chess.NoOutcome.String(), MethodNoFirstMove)
+	} else {
+		turn := g.board.Position().Turn()
+		g.endGame(gm, GameStatusCompleted, lossFor(turn).String(), MethodTimeout)
+	}
+	return true
+}
+
+// deadlineMillis is the move deadline as a Unix time in milliseconds, or
+// zero for games with a clock.
+func (g *Game) deadlineMillis() int64 {
+	if g.deadline.IsZero() {
+		return 0
+	}
+	return g.deadline.UnixMilli()
+}
@@ -0,0 +1,111 @@
+package gamemanager
+
+import (
+	"errors"
+
+	"github.com/notnil/chess"
+)
+
+var (
+	ErrNoDrawOffer        = errors.New("there is no draw offer to respond to")
+	ErrDrawAlreadyOffered = errors.New("a draw has already been offered")
+	ErrNoDrawClaim        = errors.New("no draw can be claimed in this position")
+)
+
+// Resign ends the game as a loss for the resigning player.
+func (g *Game) Resign(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+
+	g.board.Resign(g.colorOf(session.UserID))
+	g.endGame(gm, GameStatusCompleted, g.board.Outcome().String(), g.board.Method())
+	return nil
+}
+
+// OfferDraw forwards a draw offer to the opponent. The offer stands until the
+// opponent answers it or makes a move.
+func (g *Game) OfferDraw(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.drawOffer != "" {
+		return ErrDrawAlreadyOffered
+	}
+
+	if g.isBot(g.opponentOf(session.UserID)) {
+		// The computer plays on.
+		g.sendTo(gm, session.UserID, OutgoingNotice{Type: DRAW_DECLINED, GameID: g.ID})
+		return nil
+	}
+
+	g.drawOffer = session.UserID
+	g.sendTo(gm, g.opponentOf(session.UserID), OutgoingNotice{Type: DRAW_OFFER, GameID: g.ID})
+	return nil
+}
+
+func (g *Game) AcceptDraw(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.drawOffer == "" || g.drawOffer == session.UserID {
+		return ErrNoDrawOffer
+	}
+
+	if err := g.board.Draw(chess.DrawOffer); err != nil {
+		return err
+	}
+	g.drawOffer = ""
+	g.endGame(gm, GameStatusCompleted, g.board.Outcome().String(), g.board.Method())
+	return nil
+}
+
+func (g *Game) DeclineDraw(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.drawOffer == "" || g.drawOffer == session.UserID {
+		return ErrNoDrawOffer
+	}
+
+	g.sendTo(gm, g.drawOffer, OutgoingNotice{Type: DRAW_DECLINED, GameID: g.ID})
+	g.drawOffer = ""
+	return nil
+}
+
+// ClaimDraw ends the game by threefold repetition or the fifty-move rule if
+// either applies to the current position.
+func (g *Game) ClaimDraw(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+
+	for _, method := range g.board.EligibleDraws() {
+		if method != chess.ThreefoldRepetition && method != chess.FiftyMoveRule {
+			continue
+		}
+		if err := g.board.Draw(method); err != nil {
+			return err
+		}
+		g.drawOffer = ""
+		g.endGame(gm, GameStatusCompleted, g.board.Outcome().String(), g.board.Method())
+		return nil
+	}
+
+	return ErrNoDrawClaim
+}
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/Adi-ty/chess/internal/queue"
+	"github.com/Adi-ty/chess/internal/rating"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/tournament"
+	"github.com/Adi-ty/chess/internal/variant"
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 	"github.com/notnil/chess"
 )
 
@@ -20,6 +24,7 @@ const (
 	GameStatusInProgress GameStatus = "in_progress"
 	GameStatusCompleted  GameStatus = "completed"
 	GameStatusAbandoned  GameStatus = "abandoned"
+	GameStatusAborted    GameStatus = "aborted"
 )
 
 var (
@@ -28,6 +33,18 @@ var (
 	ErrInvalidMove = errors.New("invalid move format")
 	ErrNotInGame   = errors.New("you are not in this game")
 	ErrEmptyMove   = errors.New("move cannot be empty")
+	ErrNoGame      = errors.New("you are not in a game")
+
+	ErrGameIDRequired = errors.New("you are playing several games, say which one with game_id")
+
+	ErrUnratedVariant = errors.New("games from a position cannot be rated")
+)
+
+const (
+	MethodTimeout     = "timeout"
+	MethodDisconnect  = "disconnect"
+	MethodAbort       = "abort"
+	MethodNoFirstMove = "no_first_move"
 )
 
 type Game struct {
@@ -36,27 +53,147 @@ type Game struct {
 	WhiteUserID string
 	BlackUserID string
 
-	board     *chess.Game
+	board     *variant.Board
 	status    GameStatus
 
 	moveNumber int
 
+	settings GameSettings
+	clock    *clock
+
+	// deadline is when the player to move loses a correspondence game,
+	// which has no clock.
+	deadline time.Time
+
+	firstMoveTimer *time.Timer
+
+	// drawOffer and takeback hold the user ID of the player with a standing
+	// draw offer or takeback request.
+	drawOffer string
+	takeback  string
+
+	// seriesID links rematches: it is the ID of the first game of the
+	// series. rematchOffer is the player offering a rematch after the end.
+	seriesID     string
+	rematchOf    string
+	rematchOffer string
+
+	// tournamentID is the tournament the game is played in, if any.
+	tournamentID string
+
+	// viewers is the number of connections spectating the game.
+	viewers int
+
+	// bot plays for botID when one side is a computer player.
+	bot   Bot
+	botID string
+
+	// superseded is set when the game is replaced in memory by a copy
+	// restored from the store. Nothing may be played on it any more.
+	superseded bool
+
+	// storingResult is set from the end of the game until its result is
+	// stored.
+	storingResult bool
+
 	startTime time.Time
 	endTime   time.Time
 
-	disconnected map[string]time.Time 
+	// disconnected holds the players away from a live game, and since when.
+	// It includes those who were not connected when it started.
+	disconnected map[string]time.Time
 
 	mu        sync.RWMutex
 }
 
-func StartNewGame(whiteUserID, blackUserID string) *Game {
+// GameSettings are the options both players agreed to when the game was
+// created.
+type GameSettings struct {
+	TimeControl TimeControl
+	Rated       bool
+	// Variant is the rule set and FEN the starting position. A Chess960
+	// game without a FEN gets a random starting position.
+	Variant variant.Variant
+	FEN     string
+	// Armageddon games give Black less time and draw odds. They only
+	// settle knockout matches.
+	Armageddon bool
+	// Arena games are played in an arena tournament, whose players may go
+	// berserk.
+	Arena bool
+}
+
+// newClock returns the clock the game starts with, on which Black has less
+// time in armageddon.
+func (s GameSettings) newClock() *clock {
+	c := newClock(s.TimeControl)
+	if s.Armageddon {
+		c.black -= armageddonHandicap
+	}
+	return c
+}
+
+// ParseGameSettings validates the options a player asked for.
+func ParseGameSettings(timeControl string, rated bool, variantName string, fen string) (GameSettings, error) {
+	tc, err := ParseTimeControl(timeControl)
+	if err != nil {
+		return GameSettings{}, err
+	}
+	v, err := variant.Parse(variantName)
+	if err != nil {
+		return GameSettings{}, err
+	}
+	if err := variant.Validate(v, fen); err != nil {
+		return GameSettings{}, err
+	}
+	if rated && v == variant.FromPosition {
+		return GameSettings{}, ErrUnratedVariant
+	}
+	return GameSettings{TimeControl: tc, Rated: rated, Variant: v, FEN: fen}, nil
+}
+
+// variantCategories are the rating pools of variants that are not rated
+// alongside standard chess.
+var variantCategories = map[variant.Variant]rating.Category{
+	variant.KingOfTheHill: rating.KingOfTheHill,
+	variant.ThreeCheck:    rating.ThreeCheck,
+	variant.RacingKings:   rating.RacingKings,
+	variant.Horde:         rating.Horde,
+}
+
+// RatingCategory is the rating pool the game counts towards.
+func (s GameSettings) RatingCategory() rating.Category {
+	if category, ok := variantCategories[s.Variant]; ok {
+		return category
+	}
+	if s.TimeControl.Correspondence() {
+		return rating.Correspondence
+	}
+	return rating.CategoryFor(s.TimeControl.Base, s.TimeControl.Increment)
+}
+
+func StartNewGame(whiteUserID, blackUserID string, settings GameSettings) *Game {
+	// Settings are validated when they are chosen, so this only fails if a
+	// variant was added without a way to set it up.
+	board, err := variant.New(settings.Variant, settings.FEN)
+	if err != nil {
+		log.Printf("Failed to set up %s game, playing standard chess: %v", settings.Variant, err)
+		board, _ = variant.New(variant.Standard, "")
+	}
+
+	clock := settings.newClock()
+
+	id := uuid.New().String()
 	return &Game{
-		ID:        uuid.New().String(),
+		ID:        id,
 		WhiteUserID: whiteUserID,
 		BlackUserID: blackUserID,
-		board:     chess.NewGame(),
+		board:     board,
 		status:    GameStatusInProgress,
 		moveNumber: 0,
+		settings:  settings,
+		clock:     clock,
+		seriesID:  id,
 		startTime: time.Now(),
 		disconnected: make(map[string]time.Time),
 	}
@@ -66,6 +203,11 @@ func (g *Game) MakeMove(session *PlayerSession, move string, gm *GameManager) er
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	return g.makeMove(gm, session.UserID, move)
+}
+
+// makeMove plays move for userID. The caller must hold g.mu.
+func (g *Game) makeMove(gm *GameManager, userID string, move string) error {
 	if g.status != GameStatusInProgress {
 		return ErrGameEnded
 	}
@@ -74,71 +216,194 @@ func (g *Game) MakeMove(session *PlayerSession, move string, gm *GameManager) er
 		return ErrEmptyMove
 	}
 
-	if session.UserID != g.WhiteUserID && session.UserID != g.BlackUserID {
+	if userID != g.WhiteUserID && userID != g.BlackUserID {
 		return ErrNotInGame
 	}
 
 	turn := g.board.Position().Turn()
-	if (turn == chess.White && session.UserID != g.WhiteUserID) || (turn == chess.Black && session.UserID != g.BlackUserID) {
+	if (turn == chess.White && userID != g.WhiteUserID) || (turn == chess.Black && userID != g.BlackUserID) {
 		return ErrNotYourTurn
 	}
 
-	mv, err := chess.UCINotation{}.Decode(g.board.Position(), move)
-	if err != nil {
-		return ErrInvalidMove
+	now := time.Now()
+	if g.clock.flagged(now) {
+		// The flag fell before the timer goroutine got the lock.
+		g.endGame(gm, GameStatusCompleted, lossFor(turn).String(), MethodTimeout)
+		return nil
+	}
+	if g.expireDeadline(gm, now) {
+		return nil
 	}
 
-	if err := g.board.Move(mv); err != nil {
+	if err := g.board.Move(move); err != nil {
 		return ErrInvalidMove
 	}
+	g.clock.punch(now)
 
-	outcome := g.board.Outcome()
-	if outcome != chess.NoOutcome {
-		g.status = GameStatusCompleted
-		g.endTime = time.Now()
-
-		err := gm.gameStore.UpdateGameStatus(context.Background(), g.ID, string(GameStatusCompleted), outcome.String(), g.board.Method().String(), g.endTime.Format(time.RFC3339))
-		if err != nil {
-			log.Printf("Failed to update game status in store: %v", err)
-		}
-
-		gameOverMsg := OutgoingGameOver{
-			Type:    GAME_OVER,
-			Outcome: outcome.String(),
-			Method:  g.board.Method().String(),
-		}
-
-		g.safeSend(gm.sessions[g.WhiteUserID].Conn, gameOverMsg)
-		g.safeSend(gm.sessions[g.BlackUserID].Conn, gameOverMsg)
-		return nil
+	if g.drawOffer != "" && g.drawOffer != userID {
+		g.drawOffer = ""
 	}
+	g.takeback = ""
 
 	g.moveNumber++
+	g.stopFirstMoveTimer()
+	whiteMs, blackMs := g.clock.millis(now)
     payload := queue.MovePayload{
         GameID:     g.ID,
-        UserID:     session.UserID,
+        UserID:     userID,
         MoveNumber: g.moveNumber,
         Move:       move,
-        CreatedAt:  float64(time.Now().Unix()),
+        WhiteTimeMs: whiteMs,
+        BlackTimeMs: blackMs,
+        CreatedAt:  float64(now.Unix()),
     }
     if err := queue.EnqueueMove(gm.redisClient, payload); err != nil {
         log.Printf("Failed to enqueue move: %v", err)
     }
 
-	moveMsg := OutgoingMove{Type: MOVE, Move: move}
-	jsonData, _ := json.Marshal(moveMsg)
-    gm.redisClient.Publish(context.Background(), "game:"+g.ID, jsonData)
-	// g.safeSend(gm.sessions[g.WhiteUserID].Conn, moveMsg)
-	// g.safeSend(gm.sessions[g.BlackUserID].Conn, moveMsg)
+	outcome := g.board.Outcome()
+	if outcome != chess.NoOutcome {
+		g.publish(gm, OutgoingMove{Type: MOVE, GameID: g.ID, Move: move, WhiteTime: whiteMs, BlackTime: blackMs})
+		g.endGame(gm, GameStatusCompleted, outcome.String(), g.board.Method())
+		return nil
+	}
+
+	g.startClock(gm, now)
+	g.publish(gm, OutgoingMove{Type: MOVE, GameID: g.ID, Move: move, WhiteTime: whiteMs, BlackTime: blackMs, Deadline: g.deadlineMillis()})
+	g.scheduleBotMove(gm)
 
 	return nil
 }
 
+// startClock starts the clock of the side to move, or in correspondence
+// games its move deadline. Clocks only run once the first move has been
+// made.
+func (g *Game) startClock(gm *GameManager, since time.Time) {
+	if g.settings.TimeControl.Correspondence() {
+		g.setDeadline(gm, since.Add(g.settings.TimeControl.MoveTime()))
+		return
+	}
+	if g.moveNumber == 0 {
+		return
+	}
+	moveNumber := g.moveNumber
+	g.clock.start(g.board.Position().Turn() == chess.White, since, func() {
+		g.handleFlag(gm, moveNumber)
+	})
+}
+
+// handleFlag ends the game on time if the side to move still has not moved
+// since moveNumber.
+func (g *Game) handleFlag(gm *GameManager, moveNumber int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.status != GameStatusInProgress || g.moveNumber != moveNumber {
+		return
+	}
+
+	turn := g.board.Position().Turn()
+	if g.clock.remaining(turn == chess.White, time.Now()) > 0 {
+		return
+	}
+
+	g.endGame(gm, GameStatusCompleted, lossFor(turn).String(), MethodTimeout)
+}
+
+// lossFor is the outcome of a game lost by color.
+func lossFor(color chess.Color) chess.Outcome {
+	if color == chess.White {
+		return chess.BlackWon
+	}
+	return chess.WhiteWon
+}
+
+// endGame stops the clock and ends the game in memory. Storing the result
+// (rating rated games), broadcasting game_over and archiving the game follow
+// in the background: callers hold g.mu and often gm.mu, which must not wait
+// on the store's transaction. The caller must hold g.mu.
+func (g *Game) endGame(gm *GameManager, status GameStatus, outcome string, method string) {
+	g.status = status
+	g.endTime = time.Now()
+	g.clock.stop(g.endTime)
+	g.stopFirstMoveTimer()
+	g.storingResult = true
+
+	endedAt := g.endTime.Format(time.RFC3339)
+	archive := g.archive(gm, status, outcome, method)
+	var result tournament.Pairing
+	if g.tournamentID != "" {
+		result = g.tournamentResult(outcome)
+	}
+
+	go func() {
+		update, err := gm.gameStore.UpdateGameStatus(context.Background(), g.ID, string(status), outcome, method, endedAt)
+		if err != nil {
+			log.Printf("Failed to update game status in store: %v", err)
+		}
+		if update != nil {
+			for _, r := range []*store.Rating{update.White, update.Black} {
+				if err := gm.leaderboard.Record(context.Background(), r); err != nil {
+					log.Printf("Failed to update leaderboard for %s: %v", r.UserID, err)
+				}
+			}
+		}
+		g.mu.Lock()
+		g.storingResult = false
+		g.mu.Unlock()
+
+		g.publish(gm, OutgoingGameOver{
+			Type:    GAME_OVER,
+			GameID:  g.ID,
+			Outcome: outcome,
+			Method:  method,
+			Ratings: ratingChanges(update),
+		})
+
+		go archive()
+		if g.tournamentID != "" {
+			gm.tournamentGameOver(g.tournamentID, result)
+		}
+	}()
+}
+
+// resultPending reports whether the game has ended here and its result is
+// still on its way to the store, which lists it as in progress until then.
+func (g *Game) resultPending() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.status != GameStatusInProgress && g.storingResult
+}
+
+func ratingChanges(update *store.RatingUpdate) map[string]OutgoingRatingChange {
+	if update == nil {
+		return nil
+	}
+	return map[string]OutgoingRatingChange{
+		"white": {Rating: int(math.Round(update.White.Rating)), Diff: update.WhiteDiff, Provisional: update.White.Provisional},
+		"black": {Rating: int(math.Round(update.Black.Rating)), Diff: update.BlackDiff, Provisional: update.Black.Provisional},
+	}
+}
+
+// publish fans msg out to everyone listening on the game's channel.
+func (g *Game) publish(gm *GameManager, msg interface{}) {
+	jsonData, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal message for game %s: %v", g.ID, err)
+		return
+	}
+	if err := gm.redisClient.Publish(context.Background(), "game:"+g.ID, jsonData).Err(); err != nil {
+		log.Printf("Failed to publish to game %s: %v", g.ID, err)
+	}
+}
+
+// HandleDisconnect abandons the game if userID stays away for 15 seconds.
+// Correspondence players may come and go as they like.
 func (g *Game) HandleDisconnect(userID string, gm *GameManager) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if g.status != GameStatusInProgress {
+	if g.status != GameStatusInProgress || g.settings.TimeControl.Correspondence() {
 		return
 	}
 	g.disconnected[userID] = time.Now()
@@ -159,41 +424,68 @@ func (g *Game) HandleDisconnect(userID string, gm *GameManager) {
 		}
 
 		if g.status == GameStatusInProgress {
-			g.status = GameStatusAbandoned
-			g.endTime = time.Now()
-
-			err := gm.gameStore.UpdateGameStatus(context.Background(), g.ID, string(g.status), string(GameStatusAbandoned), "disconnect", g.endTime.Format(time.RFC3339))
-			if err != nil {
-				log.Printf("Failed to update game status in store: %v", err)
+			// A tournament game cannot be called off, so leaving one early
+			// loses it like leaving it later.
+			if g.abortable() && g.tournamentID == "" {
+				g.endGame(gm, GameStatusAborted, chess.NoOutcome.String(), MethodDisconnect)
+			} else {
+				g.endGame(gm, GameStatusAbandoned, lossFor(g.colorOf(userID)).String(), MethodDisconnect)
 			}
 
-			abandonMsg := OutgoingGameOver{
-				Type:    GAME_OVER,
-				Outcome: "abandoned",
-				Method:  "disconnect",
-			}
-			
-			g.safeSend(gm.sessions[g.WhiteUserID].Conn, abandonMsg)
-			g.safeSend(gm.sessions[g.BlackUserID].Conn, abandonMsg)
-
 			if whiteSess, exists := gm.sessions[g.WhiteUserID]; exists {
-                whiteSess.GameID = ""
+                whiteSess.leave(g.ID)
             }
             if blackSess, exists := gm.sessions[g.BlackUserID]; exists {
-                blackSess.GameID = ""
+                blackSess.leave(g.ID)
             }
 		}
 	}()
 }
 
 
+// checkPlayer verifies that the game is still running and that session is
+// one of its players. The caller must hold g.mu.
+func (g *Game) checkPlayer(session *PlayerSession) error {
+	if g.status != GameStatusInProgress {
+		return ErrGameEnded
+	}
+	if session.UserID != g.WhiteUserID && session.UserID != g.BlackUserID {
+		return ErrNotInGame
+	}
+	return nil
+}
+
+func (g *Game) colorOf(userID string) chess.Color {
+	switch userID {
+	case g.WhiteUserID:
+		return chess.White
+	case g.BlackUserID:
+		return chess.Black
+	}
+	return chess.NoColor
+}
+
+func (g *Game) opponentOf(userID string) string {
+	if userID == g.WhiteUserID {
+		return g.BlackUserID
+	}
+	return g.WhiteUserID
+}
+
+// sendTo writes msg directly to one player, bypassing the game channel.
+func (g *Game) sendTo(gm *GameManager, userID string, msg interface{}) {
+	if session, ok := gm.sessions[userID]; ok {
+		g.safeSend(session.Conn, msg)
+	}
+}
+
 func (g *Game) IsActive() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 	return g.status == GameStatusInProgress
 }
 
-func (g *Game) safeSend(conn *websocket.Conn, msg interface{}) {
+func (g *Game) safeSend(conn *Conn, msg interface{}) {
 	if conn == nil {
 		return
 	}
@@ -4,13 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/Adi-ty/chess/internal/chat"
+	"github.com/Adi-ty/chess/internal/engine"
+	"github.com/Adi-ty/chess/internal/leaderboard"
+	"github.com/Adi-ty/chess/internal/matchmaking"
+	"github.com/Adi-ty/chess/internal/queue"
 	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/variant"
 	"github.com/gorilla/websocket"
-	"github.com/notnil/chess"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -18,24 +25,75 @@ type GameManager struct {
 	games       map[string]*Game
 	sessions    map[string]*PlayerSession
 
-	pendingUser string
+	// pool holds the players waiting for an opponent.
+	pool *matchmaking.Pool[GameSettings]
+
+	challenges map[string]*Challenge
 
 	gameStore  store.GameStore
+	ratingStore store.RatingStore
+	chatStore   store.ChatStore
+	userStore   store.UserStore
+	statsStore  store.StatsStore
+	analysisStore store.AnalysisStore
+	tournamentStore store.TournamentStore
 	redisClient *redis.Client
 
 	pubsubs map[string]*redis.PubSub
 
+	leaderboard *leaderboard.Leaderboard
+
+	// bots maps the user IDs of computer players to what moves for them.
+	bots map[string]Bot
+
+	chatFilter chat.Filter
+
+	// siteURL is the address recorded in the Site header of exported games.
+	siteURL string
+
+	// firstMoveTimeout is how long the first player has to move before
+	// the game is aborted. Zero disables the automatic abort.
+	firstMoveTimeout time.Duration
+
+	// maxLiveGames is how many live games a player may have going at once,
+	// or zero for no limit.
+	maxLiveGames int
+
+	// tournamentMu serializes pairing and scoring tournaments, though not
+	// the work of pairing a Swiss round. It is taken before mu, and guards
+	// arenas, the running arena tournaments by ID.
+	tournamentMu sync.Mutex
+	arenas       map[string]*arena
+
 	mu          sync.RWMutex
 }
 
-func NewGameManager(gameStore store.GameStore, redisClient *redis.Client) *GameManager {
-	return &GameManager{
+func NewGameManager(gameStore store.GameStore, ratingStore store.RatingStore, chatStore store.ChatStore, userStore store.UserStore, statsStore store.StatsStore, analysisStore store.AnalysisStore, tournamentStore store.TournamentStore, redisClient *redis.Client, firstMoveTimeout time.Duration) *GameManager {
+	gm := &GameManager{
 		games:       make(map[string]*Game),
 		sessions:    make(map[string]*PlayerSession),
+		pool:        matchmaking.NewPool[GameSettings](matchmaking.DefaultConfig),
+		challenges:  make(map[string]*Challenge),
 		gameStore:   gameStore,
+		ratingStore: ratingStore,
+		chatStore:   chatStore,
+		userStore:   userStore,
+		statsStore:  statsStore,
+		analysisStore: analysisStore,
+		tournamentStore: tournamentStore,
 		redisClient: redisClient,
 		pubsubs:     make(map[string]*redis.PubSub),
+		leaderboard: leaderboard.New(redisClient),
+		bots:        make(map[string]Bot),
+		chatFilter:  chat.NopFilter{},
+		firstMoveTimeout: firstMoveTimeout,
+		maxLiveGames: DefaultMaxLiveGames,
+		arenas:      make(map[string]*arena),
+	}
+	for _, level := range engine.Levels {
+		gm.bots[engine.PlayerID(level.Number)] = engine.New(level)
 	}
+	return gm
 }
 
 func (gm *GameManager) CanUserConnect(userID string) error {
@@ -61,91 +119,208 @@ func (gm *GameManager) AddUser(conn *websocket.Conn, userID string) {
 		gm.sessions[userID] = session
 	}
 
-	if session.Conn != nil && session.Conn != conn {
+	if session.Conn != nil && session.Conn.Conn != conn {
 		session.Conn.Close()
 	}
 
-	session.Conn = conn
+	session.Conn = newConn(conn)
 	session.Disconnected = false
 	session.LastSeen = time.Now()
 
-	if session.GameID != "" {
-		// if game, exists := gm.games[session.GameID]; exists && game.IsActive() {
-        //     // Game is in memory, no need to fetch/replay
-		// 	game.mu.RLock()
-        //     boardState := game.board.Position().Board().String()
-        //     game.mu.RUnlock()
-        //     session.Conn.WriteJSON(map[string]interface{}{
-        //         "type": "board_state",
-        //         "board": boardState,
-        //         "game_id": game.ID,
-        //     })
-        //     go gm.AddHandler(session)
-        //     return
-        // }
-
-		dbGame, err := gm.gameStore.GetGameByUserID(context.Background(), userID)
-		if err != nil {
-			log.Printf("Failed to fetch game from store: %v", err)
-		} else if dbGame != nil {
-			game := &Game{
-                ID:          dbGame.ID,
-                WhiteUserID: dbGame.WhiteUserID,
-                BlackUserID: dbGame.BlackUserID,
-                board:       chess.NewGame(),
-                status:      GameStatusInProgress,
-                startTime:   time.Now(),
-                moveNumber:  0,
-                disconnected: make(map[string]time.Time),
-            }
-			gm.games[dbGame.ID] = game
-			gm.sessions[game.WhiteUserID].GameID = game.ID
-            gm.sessions[game.BlackUserID].GameID = game.ID
-
-			// Replay moves
-			moves, err := gm.gameStore.GetMovesByGameID(context.Background(), dbGame.ID)
-			if err != nil {
-                log.Printf("Error fetching moves for game %s: %v", dbGame.ID, err)
-            } else if len(moves) > 0 {
-				if whiteSess, exists := gm.sessions[game.WhiteUserID]; exists && whiteSess.Conn != nil {
-                    whiteSess.Conn.WriteJSON(map[string]interface{}{"type": "board_replay", "moves": moves})
-                }
-                if blackSess, exists := gm.sessions[game.BlackUserID]; exists && blackSess.Conn != nil {
-                    blackSess.Conn.WriteJSON(map[string]interface{}{"type": "board_replay", "moves": moves})
-                }
+	muted, err := gm.chatStore.IsChatMuted(context.Background(), userID)
+	if err != nil {
+		log.Printf("Failed to fetch chat setting for %s: %v", userID, err)
+	}
+	session.chatMuted.Store(muted)
+
+	gm.restoreGames(session)
+
+	gm.sendPendingChallenges(session)
+
+	go gm.AddHandler(session)
+}
+
+// restoreGames picks the user's games up again when they connect: the live
+// games they were in before reconnecting and every correspondence game,
+// each loaded from the store unless it is already in memory. The caller must
+// hold gm.mu.
+func (gm *GameManager) restoreGames(session *PlayerSession) {
+	dbGames, err := gm.gameStore.ListActiveGamesByUserID(context.Background(), session.UserID)
+	if err != nil {
+		log.Printf("Failed to fetch games of %s from store: %v", session.UserID, err)
+	}
 
-			
-				if _, exists := gm.pubsubs[dbGame.ID]; !exists {
-					pubsub := gm.redisClient.Subscribe(context.Background(), "game:"+dbGame.ID)
-					gm.pubsubs[dbGame.ID] = pubsub
-					go gm.listenForMoves(dbGame.ID)
-					log.Printf("Subscribed to game channel: %s", dbGame.ID)
+	for _, dbGame := range dbGames {
+		if game, exists := gm.games[dbGame.ID]; exists && game.resultPending() {
+			continue
+		}
+		if dbGame.DaysPerMove > 0 {
+			game, exists := gm.games[dbGame.ID]
+			if !exists || !game.IsActive() {
+				if game, _, err = gm.restoreGame(dbGame); err != nil {
+					log.Printf("Failed to restore game %s: %v", dbGame.ID, err)
+					continue
 				}
+			}
+			gm.addGame(session, game)
 
-                for _, move := range moves {
-					mv, err := chess.UCINotation{}.Decode(game.board.Position(), move.Move)
-					if err != nil {
-						session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "failed to restore game"})
-						log.Printf("Error decoding move %s: %v", move.Move, err)
-						return
-					}
-					
-                    if err := game.board.Move(mv); err != nil {
-                        log.Printf("Error replaying move %s: %v", move.Move, err)
-						session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "failed to restore game"})
-						return
-                    }
-                    game.moveNumber = move.MoveNumber
-                }
-            }
+			game.mu.RLock()
+			session.Conn.WriteJSON(game.snapshot(GAME_STATE))
+			game.mu.RUnlock()
+			continue
 		}
 
-		if game, exists := gm.games[session.GameID]; !exists || !game.IsActive() {
-			session.GameID = ""
+		if !session.plays(dbGame.ID) {
+			continue
+		}
+		// Moves reach the store through a queue, so a game still in memory
+		// is ahead of it and is picked up as it is.
+		if game, exists := gm.games[dbGame.ID]; exists && game.IsActive() {
+			game.mu.Lock()
+			delete(game.disconnected, session.UserID)
+			session.Conn.WriteJSON(game.snapshot(GAME_STATE))
+			game.mu.Unlock()
+			continue
+		}
+		game, moves, err := gm.restoreGame(dbGame)
+		if err != nil {
+			log.Printf("Failed to restore game %s: %v", dbGame.ID, err)
+			session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: dbGame.ID, Message: "failed to restore game"})
+			continue
+		}
+		if len(moves) > 0 {
+			for _, userID := range []string{game.WhiteUserID, game.BlackUserID} {
+				if sess, exists := gm.sessions[userID]; exists && sess.Conn != nil {
+					sess.Conn.WriteJSON(map[string]interface{}{"type": "board_replay", "game_id": game.ID, "moves": moves})
+				}
+			}
 		}
 	}
 
-	go gm.AddHandler(session)
+	gm.pruneGames(session)
+}
+
+// restoreGame rebuilds an in-progress game from the store, replays its moves
+// and restarts its clock, replacing any copy in memory. The caller must hold
+// gm.mu.
+func (gm *GameManager) restoreGame(dbGame *store.Game) (*Game, []queue.MovePayload, error) {
+	game, moves, err := gm.loadGame(dbGame)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pubsub := gm.installGame(game); pubsub != nil {
+		gm.listen(game.ID, pubsub)
+	}
+	game.resume(gm, moves)
+	return game, moves, nil
+}
+
+// loadGame rebuilds an in-progress game and its clock from the store and
+// replays its moves. It only reads the store, so the caller need not hold
+// gm.mu.
+func (gm *GameManager) loadGame(dbGame *store.Game) (*Game, []queue.MovePayload, error) {
+	settings := GameSettings{
+		TimeControl: TimeControl{
+			Base:        time.Duration(dbGame.BaseSeconds) * time.Second,
+			Increment:   time.Duration(dbGame.IncrementSeconds) * time.Second,
+			DaysPerMove: dbGame.DaysPerMove,
+		},
+		Rated:   dbGame.Rated,
+		Variant: variant.Variant(dbGame.Variant),
+		FEN:     dbGame.InitialFEN,
+		Arena:   dbGame.Arena,
+		Armageddon: dbGame.Armageddon,
+	}
+	board, err := variant.New(settings.Variant, settings.FEN)
+	if err != nil {
+		return nil, nil, err
+	}
+	moves, err := gm.gameStore.GetMovesByGameID(context.Background(), dbGame.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	startTime, err := time.Parse(time.RFC3339, dbGame.StartedAt)
+	if err != nil {
+		startTime = time.Now()
+	}
+	game := &Game{
+		ID:           dbGame.ID,
+		WhiteUserID:  dbGame.WhiteUserID,
+		BlackUserID:  dbGame.BlackUserID,
+		board:        board,
+		status:       GameStatusInProgress,
+		startTime:    startTime,
+		settings:     settings,
+		clock:        settings.newClock(),
+		seriesID:     dbGame.SeriesID,
+		rematchOf:    dbGame.RematchOf,
+		tournamentID: dbGame.TournamentID,
+		disconnected: make(map[string]time.Time),
+	}
+	for _, move := range moves {
+		if err := board.Move(move.Move); err != nil {
+			return nil, nil, fmt.Errorf("replaying move %s: %w", move.Move, err)
+		}
+		game.moveNumber = move.MoveNumber
+	}
+	if dbGame.MoveDeadline != nil {
+		game.deadline = *dbGame.MoveDeadline
+	}
+	if len(moves) > 0 && !settings.TimeControl.Correspondence() {
+		last := moves[len(moves)-1]
+		if last.WhiteTimeMs > 0 || last.BlackTimeMs > 0 {
+			game.clock.white = time.Duration(last.WhiteTimeMs) * time.Millisecond
+			game.clock.black = time.Duration(last.BlackTimeMs) * time.Millisecond
+		}
+	}
+	game.clock.restoreBerserk(dbGame.WhiteBerserk, dbGame.BlackBerserk, settings.TimeControl.Base)
+	return game, moves, nil
+}
+
+// installGame puts a loaded game in place of any copy in memory. If nothing
+// listens to the game's channel yet it returns the subscription to pass to
+// listen, which talks to Redis and so may be done without gm.mu. The caller
+// must hold gm.mu.
+func (gm *GameManager) installGame(game *Game) *redis.PubSub {
+	if old, exists := gm.games[game.ID]; exists {
+		old.mu.Lock()
+		old.superseded = true
+		old.clock.halt()
+		old.stopFirstMoveTimer()
+		old.mu.Unlock()
+	}
+	gm.games[game.ID] = game
+	gm.attachPlayers(game)
+
+	if _, exists := gm.pubsubs[game.ID]; exists {
+		return nil
+	}
+	// Subscribing without a channel does not reach Redis yet.
+	pubsub := gm.redisClient.Subscribe(context.Background())
+	gm.pubsubs[game.ID] = pubsub
+	return pubsub
+}
+
+// listen subscribes to the game's channel and relays its messages.
+func (gm *GameManager) listen(gameID string, pubsub *redis.PubSub) {
+	if err := pubsub.Subscribe(context.Background(), "game:"+gameID); err != nil {
+		log.Printf("Failed to subscribe to game channel %s: %v", gameID, err)
+	}
+	go gm.listenForMoves(gameID, pubsub)
+	log.Printf("Subscribed to game channel: %s", gameID)
+}
+
+// resume restarts the clock of a restored game from its last move, and its
+// first move timer and bot.
+func (g *Game) resume(gm *GameManager, moves []queue.MovePayload) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(moves) > 0 && !g.settings.TimeControl.Correspondence() {
+		g.startClock(gm, time.Unix(int64(moves[len(moves)-1].CreatedAt), 0))
+	}
+	g.startFirstMoveTimer(gm)
+	g.scheduleBotMove(gm)
 }
 
 func (gm *GameManager) RemoveUser(userID string) {
@@ -160,16 +335,19 @@ func (gm *GameManager) RemoveUser(userID string) {
 	session.Disconnected = true
 	session.LastSeen = time.Now()
 
-	if session.GameID != "" {
-		game := gm.games[session.GameID]
+	gm.pool.Cancel(userID)
+	stopSpectating(session)
+
+	for _, gameID := range session.games {
+		game := gm.games[gameID]
 		if game != nil {
 			game.HandleDisconnect(session.UserID, gm)
 
 			game.mu.RLock()
             if game.status == GameStatusAbandoned {
-                if pubsub, exists := gm.pubsubs[session.GameID]; exists {
+                if pubsub, exists := gm.pubsubs[gameID]; exists {
                     pubsub.Close()
-                    delete(gm.pubsubs, session.GameID)
+                    delete(gm.pubsubs, gameID)
                 }
             }
             game.mu.RUnlock()
@@ -211,125 +389,339 @@ func (gm *GameManager) AddHandler(session *PlayerSession) {
 func (gm *GameManager) handleMessage(session *PlayerSession, message IncomingMessage) {
 	switch message.Type {
 	case INIT_GAME:
-		gm.handleInitGame(session)
+		gm.handleInitGame(session, message)
+	case CANCEL_SEEK:
+		gm.handleCancelSeek(session)
+	case CHALLENGE:
+		gm.handleCreateChallenge(session, message)
+	case CHALLENGE_ACCEPT:
+		gm.handleAcceptChallenge(session, message.ChallengeID)
+	case CHALLENGE_DECLINE:
+		gm.handleDeclineChallenge(session, message.ChallengeID)
+	case REMATCH_OFFER:
+		gm.handleRematch(session, message.GameID, (*GameManager).OfferRematch)
+	case REMATCH_ACCEPT:
+		gm.handleRematch(session, message.GameID, (*GameManager).AcceptRematch)
+	case SPECTATE:
+		gm.handleSpectate(session, message.GameID)
+	case UNSPECTATE:
+		gm.handleUnspectate(session, message.GameID)
 	case MOVE:
-		gm.handleMove(session, message.Move)
+		gm.handleMove(session, message.GameID, message.Move)
+	case RESIGN:
+		gm.handleGameAction(session, message.GameID, (*Game).Resign)
+	case OFFER_DRAW:
+		gm.handleGameAction(session, message.GameID, (*Game).OfferDraw)
+	case ACCEPT_DRAW:
+		gm.handleGameAction(session, message.GameID, (*Game).AcceptDraw)
+	case DECLINE_DRAW:
+		gm.handleGameAction(session, message.GameID, (*Game).DeclineDraw)
+	case CLAIM_DRAW:
+		gm.handleGameAction(session, message.GameID, (*Game).ClaimDraw)
+	case TAKEBACK_REQUEST:
+		gm.handleGameAction(session, message.GameID, (*Game).RequestTakeback)
+	case TAKEBACK_ACCEPT:
+		gm.handleGameAction(session, message.GameID, (*Game).AcceptTakeback)
+	case TAKEBACK_DECLINE:
+		gm.handleGameAction(session, message.GameID, (*Game).DeclineTakeback)
+	case ABORT:
+		gm.handleGameAction(session, message.GameID, (*Game).Abort)
+	case BERSERK:
+		gm.handleGameAction(session, message.GameID, (*Game).Berserk)
+	case PLAY_COMPUTER:
+		gm.handlePlayComputer(session, message)
+	case CHAT:
+		gm.handleChat(session, message)
+	case MUTE_CHAT:
+		gm.handleMuteChat(session, message.Muted)
 	default:
 		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "unknown message type"})
 	}
 }
 
-func (gm *GameManager) handleInitGame(session *PlayerSession) {
+func (gm *GameManager) handleInitGame(session *PlayerSession, message IncomingMessage) {
+	settings, err := ParseGameSettings(message.TimeControl, message.Rated, message.Variant, message.FEN)
+	if err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+		return
+	}
+	tc := settings.TimeControl
+
+	userRating, err := gm.ratingStore.GetRating(context.Background(), session.UserID, string(settings.RatingCategory()))
+	if err != nil {
+		log.Printf("Failed to fetch rating for %s: %v", session.UserID, err)
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "failed to join matchmaking"})
+		return
+	}
+
 	gm.mu.Lock()
 	defer gm.mu.Unlock()
 
-	if existingGame, exists := gm.games[session.GameID]; exists {
-		if existingGame.IsActive() {
-			session.Conn.WriteJSON(OutgoingError{
-				Type:    ERROR,
-				Message: "you are already in an active game",
-			})
-			return
-		} else {
-			delete(gm.games, session.GameID)
-			session.GameID = ""
-		}
+	if gm.busy(session.UserID, settings) {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: ErrPlayerBusy.Error()})
+		return
+	}
+	gm.forgetFinishedGames(session)
+
+	now := time.Now()
+	match, err := gm.pool.Add(&matchmaking.Seek[GameSettings]{
+		UserID:    session.UserID,
+		Key:       settings,
+		Rating:    userRating.Rating,
+		CreatedAt: now,
+	}, now)
+	if err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+		return
 	}
 
-	if gm.pendingUser == session.UserID {
-		session.Conn.WriteJSON(OutgoingError{
-			Type:    ERROR,
-			Message: "already waiting for opponent",
-		})
+	if match != nil {
+		gm.startMatch(match)
 		return
 	}
 
-	if gm.pendingUser != "" {
-		if _, exists := gm.sessions[gm.pendingUser]; !exists {
-			gm.pendingUser = ""
+	if status, ok := gm.pool.Status(session.UserID); ok {
+		gm.sendWaiting(session, status)
+	}
+	log.Printf("Player %s waiting for opponent (%s)", session.UserID, tc)
+}
+
+// startGame creates a game between two connected players, persists it and
+// tells both players their colors. previous is the game this one is a
+// rematch of, if any, and tournamentID the tournament it is played in. The
+// caller must hold gm.mu.
+func (gm *GameManager) startGame(whiteUserID, blackUserID string, settings GameSettings, previous *Game, tournamentID string) *Game {
+	game := StartNewGame(whiteUserID, blackUserID, settings)
+	if previous != nil {
+		game.seriesID = previous.seriesID
+		game.rematchOf = previous.ID
+	}
+	game.tournamentID = tournamentID
+	gm.games[game.ID] = game
+	gm.attachPlayers(game)
+
+	pubsub := gm.redisClient.Subscribe(context.Background(), "game:"+game.ID)
+	gm.pubsubs[game.ID] = pubsub
+
+	go gm.listenForMoves(game.ID, pubsub)
+
+	tc := settings.TimeControl
+	var deadline *time.Time
+	if tc.Correspondence() {
+		game.deadline = game.startTime.Add(tc.MoveTime())
+		deadline = &game.deadline
+	}
+	_, err := gm.gameStore.CreateGame(context.Background(), &store.Game{
+		ID:          game.ID,
+		WhiteUserID: whiteUserID,
+		BlackUserID: blackUserID,
+		Status:      string(GameStatusInProgress),
+		BaseSeconds: int(tc.Base.Seconds()),
+		IncrementSeconds: int(tc.Increment.Seconds()),
+		Rated:       settings.Rated,
+		RatingCategory: string(settings.RatingCategory()),
+		SeriesID:    game.seriesID,
+		RematchOf:   game.rematchOf,
+		Variant:     string(game.board.Variant()),
+		InitialFEN:  initialFEN(game.board),
+		DaysPerMove: tc.DaysPerMove,
+		MoveDeadline: deadline,
+		TournamentID: tournamentID,
+		Arena:       settings.Arena,
+		Armageddon:  settings.Armageddon,
+		StartedAt:   game.startTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Failed to create game in store: %v", err)
+	}
+
+	for _, player := range [][2]string{{whiteUserID, ColorWhite}, {blackUserID, ColorBlack}} {
+		start := map[string]interface{}{"type": "game_start", "color": player[1], "game_id": game.ID, "time_control": tc.String(), "rated": settings.Rated, "series_id": game.seriesID, "tournament_id": tournamentID,
+			"variant": game.board.Variant(), "fen": game.board.StartFEN(), "move_deadline_ms": game.deadlineMillis()}
+		if settings.Armageddon {
+			start["armageddon"] = true
 		}
+		game.sendTo(gm, player[0], start)
 	}
 
-	currentUserID := session.UserID
+	// The bot only moves once the game is in the store, so its first move
+	// cannot reference a missing game.
+	game.mu.Lock()
+	game.startFirstMoveTimer(gm)
+	game.scheduleBotMove(gm)
+	game.mu.Unlock()
 
-	if gm.pendingUser != "" {
-		pendingUserID := gm.pendingUser
+	log.Printf("Game started: %s (white: %s, black: %s, %s)", game.ID, whiteUserID, blackUserID, tc)
+	return game
+}
 
-		// Prevent same user from playing against themselves
-		if currentUserID != "" && pendingUserID != "" && currentUserID == pendingUserID {
-			session.Conn.WriteJSON(OutgoingError{
-				Type:    ERROR,
-				Message: "you cannot play against yourself",
-			})
-			return
+// initialFEN is the starting position to store for the game, empty when it
+// is the standard one.
+func initialFEN(board *variant.Board) string {
+	if board.Variant() == variant.Standard {
+		return ""
+	}
+	return board.StartFEN()
+}
+
+// attachPlayers adds game to the players' sessions and hands the computer
+// side, if any, to its bot. Bots have no session. Players who are not
+// connected to a live game start out away from it. The caller must hold
+// gm.mu.
+func (gm *GameManager) attachPlayers(game *Game) {
+	for _, userID := range []string{game.WhiteUserID, game.BlackUserID} {
+		if bot, ok := gm.bots[userID]; ok {
+			game.bot, game.botID = bot, userID
+			continue
+		}
+		if session, ok := gm.sessions[userID]; ok {
+			gm.addGame(session, game)
 		}
+		if !gm.isConnected(userID) && !game.settings.TimeControl.Correspondence() {
+			game.mu.Lock()
+			game.disconnected[userID] = time.Now()
+			game.mu.Unlock()
+		}
+	}
+}
 
-		gm.pendingUser = ""
+// addGame adds game to the session's games, dropping the ones whose rematch
+// window has closed. The caller must hold gm.mu.
+func (gm *GameManager) addGame(session *PlayerSession, game *Game) {
+	gm.pruneGames(session)
+	if !session.plays(game.ID) {
+		session.games = append(session.games, game.ID)
+	}
+}
 
-		whiteUserID := pendingUserID
-		blackUserID := currentUserID
+// pruneGames drops the games that have ended from the session, keeping those
+// that may still be rematched. The caller must hold gm.mu.
+func (gm *GameManager) pruneGames(session *PlayerSession) {
+	session.games = slices.DeleteFunc(session.games, func(id string) bool {
+		game, exists := gm.games[id]
+		if !exists {
+			return true
+		}
+		game.mu.RLock()
+		defer game.mu.RUnlock()
+		return game.status != GameStatusInProgress && game.rematchClosed()
+	})
+}
 
-		game := StartNewGame(whiteUserID, blackUserID)
-		gm.games[game.ID] = game
-		gm.sessions[whiteUserID].GameID = game.ID
-		gm.sessions[blackUserID].GameID = game.ID
+// forgetFinishedGames drops the session's games that have ended from it and
+// from memory. The caller must hold gm.mu.
+func (gm *GameManager) forgetFinishedGames(session *PlayerSession) {
+	for _, id := range session.games {
+		if game, exists := gm.games[id]; exists && !game.IsActive() {
+			delete(gm.games, id)
+		}
+	}
+	gm.pruneGames(session)
+}
 
-		pubsub := gm.redisClient.Subscribe(context.Background(), "game:"+game.ID)
-		gm.pubsubs[game.ID] = pubsub
+// DefaultMaxLiveGames is how many live games a player may have going at
+// once unless SetMaxLiveGames says otherwise.
+const DefaultMaxLiveGames = 2
 
-		go gm.listenForMoves(game.ID)
+// SetMaxLiveGames sets how many live games a player may have going at once.
+// Correspondence games are not counted. Zero or less lifts the limit, e.g.
+// for simuls.
+func (gm *GameManager) SetMaxLiveGames(n int) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.maxLiveGames = n
+}
 
-		_, err := gm.gameStore.CreateGame(context.Background(), &store.Game{
-			ID:          game.ID,
-			WhiteUserID: whiteUserID,
-			BlackUserID: blackUserID,
-			Status:      string(GameStatusInProgress),
-			StartedAt:   game.startTime.Format(time.RFC3339),
-		})
-		if err != nil {
-			log.Printf("Failed to create game in store: %v", err)
+// busy reports whether the user already plays as many live games as allowed
+// and so cannot start another one with settings. Correspondence games can
+// always be started. The caller must hold gm.mu.
+func (gm *GameManager) busy(userID string, settings GameSettings) bool {
+	if settings.TimeControl.Correspondence() || gm.maxLiveGames <= 0 {
+		return false
+	}
+	session, ok := gm.sessions[userID]
+	if !ok {
+		return false
+	}
+	live := 0
+	for _, id := range session.games {
+		game, exists := gm.games[id]
+		if exists && !game.settings.TimeControl.Correspondence() && game.IsActive() {
+			live++
 		}
-		
-		gm.sessions[whiteUserID].Conn.WriteJSON(map[string]string{"type": "game_start", "color": "white", "game_id": game.ID})
-		gm.sessions[blackUserID].Conn.WriteJSON(map[string]string{"type": "game_start", "color": "black", "game_id": game.ID})
-
-		log.Printf("Game started: %s (white: %s, black: %s)", game.ID, whiteUserID, blackUserID)
-	} else {
-		gm.pendingUser = session.UserID
-		session.Conn.WriteJSON(map[string]string{
-			"type":    "waiting",
-			"message": "waiting for opponent",
-		})
-		log.Printf("Player %s waiting for opponent", currentUserID)
 	}
+	return live >= gm.maxLiveGames
 }
 
-func (gm *GameManager) handleMove(session *PlayerSession, move string) {
+// gameFor finds the game a message acts on: the one named by gameID or, for
+// messages that name none, the session's only game, or else its only game
+// still in progress. The caller must hold gm.mu.
+func (gm *GameManager) gameFor(session *PlayerSession, gameID string) (*Game, error) {
+	if gameID == "" {
+		ids := session.games
+		if len(ids) > 1 {
+			ids = slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+				game, exists := gm.games[id]
+				return !exists || !game.IsActive()
+			})
+		}
+		switch len(ids) {
+		case 0:
+			return nil, ErrNoGame
+		case 1:
+			gameID = ids[0]
+		default:
+			return nil, ErrGameIDRequired
+		}
+	}
+
+	game, exists := gm.games[gameID]
+	if !exists {
+		return nil, ErrGameNotFound
+	}
+	return game, nil
+}
+
+func (gm *GameManager) handleMove(session *PlayerSession, gameID string, move string) {
 	gm.mu.RLock()
-	game, exists := gm.games[session.GameID]
+	game, err := gm.gameFor(session, gameID)
 	gm.mu.RUnlock()
 
-	if !exists || game == nil {
+	if err != nil {
 		session.Conn.WriteJSON(OutgoingError{
 			Type:    ERROR,
-			Message: "you are not in a game",
+			GameID:  gameID,
+			Message: err.Error(),
 		})
 		return
 	}
 
-	if session.GameID == "" {
-		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "no active game"})
-		return
-	}
-
 	if err := game.MakeMove(session, move, gm); err != nil {
 		session.Conn.WriteJSON(OutgoingError{
 			Type:    ERROR,
+			GameID:  game.ID,
 			Message: err.Error(),
 		})
 	}
 }
 
+// handleGameAction runs a game-level action such as resigning or offering a
+// draw against the game the message names, or the session's only game.
+func (gm *GameManager) handleGameAction(session *PlayerSession, gameID string, action func(*Game, *PlayerSession, *GameManager) error) {
+	gm.mu.RLock()
+	game, err := gm.gameFor(session, gameID)
+	gm.mu.RUnlock()
+
+	if err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: err.Error()})
+		return
+	}
+
+	if err := action(game, session, gm); err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: game.ID, Message: err.Error()})
+	}
+}
+
 func (gm *GameManager) GetActiveGamesCount() int {
 	gm.mu.RLock()
 	defer gm.mu.RUnlock()
@@ -349,23 +741,25 @@ func (gm *GameManager) GetConnectedUsersCount() int {
 	return len(gm.sessions)
 }
 
-func (gm *GameManager) listenForMoves(gameID string) {
-    pubsub := gm.pubsubs[gameID]
+func (gm *GameManager) listenForMoves(gameID string, pubsub *redis.PubSub) {
     defer pubsub.Close()
 
     ch := pubsub.Channel()
     for msg := range ch {
-        var moveMsg OutgoingMove
-        if err := json.Unmarshal([]byte(msg.Payload), &moveMsg); err != nil {
-            log.Printf("Error unmarshaling pubsub message: %v", err)
+        if !json.Valid([]byte(msg.Payload)) {
+            log.Printf("Invalid pubsub message on game %s", gameID)
             continue
         }
+        payload := json.RawMessage(msg.Payload)
 
         game := gm.games[gameID]
         if game != nil {
             game.mu.RLock()
-            game.safeSend(gm.sessions[game.WhiteUserID].Conn, moveMsg)
-            game.safeSend(gm.sessions[game.BlackUserID].Conn, moveMsg)
+            for _, userID := range []string{game.WhiteUserID, game.BlackUserID} {
+                if session := gm.sessions[userID]; session != nil && deliverable(msg.Payload, RoomPlayer, session) {
+                    game.safeSend(session.Conn, payload)
+                }
+            }
             game.mu.RUnlock()
         }
     }
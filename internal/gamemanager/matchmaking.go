@@ -0,0 +1,85 @@
+package gamemanager
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/matchmaking"
+)
+
+const matchmakingInterval = time.Second
+
+// RunMatchmaker periodically pairs waiting players whose rating windows have
+// widened enough to match and keeps the rest informed of their position.
+func (gm *GameManager) RunMatchmaker() {
+	ticker := time.NewTicker(matchmakingInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		matches := gm.pool.Sweep(now)
+
+		gm.mu.Lock()
+		for i := range matches {
+			gm.startMatch(&matches[i])
+		}
+		for _, status := range gm.pool.Changed() {
+			if session, ok := gm.sessions[status.UserID]; ok && session.Conn != nil {
+				gm.sendWaiting(session, status)
+			}
+		}
+		gm.mu.Unlock()
+	}
+}
+
+// startMatch starts a game for a pair of seeks with random colors. If one of
+// the players disconnected in the meantime the other goes back to the pool.
+// The caller must hold gm.mu.
+func (gm *GameManager) startMatch(match *matchmaking.Match[GameSettings]) {
+	seeks := []*matchmaking.Seek[GameSettings]{match.First, match.Second}
+	if !gm.isConnected(match.First.UserID) || !gm.isConnected(match.Second.UserID) {
+		for _, seek := range seeks {
+			if !gm.isConnected(seek.UserID) {
+				log.Printf("Dropping match for %s: player is gone", seek.UserID)
+				continue
+			}
+			if next, err := gm.pool.Add(seek, time.Now()); err == nil && next != nil {
+				gm.startMatch(next)
+			}
+		}
+		return
+	}
+
+	white, black := match.First.UserID, match.Second.UserID
+	if rand.Intn(2) == 0 {
+		white, black = black, white
+	}
+	gm.startGame(white, black, match.First.Key, nil, "")
+}
+
+// isConnected reports whether userID can start a game now. Bots are always
+// available. The caller must hold gm.mu.
+func (gm *GameManager) isConnected(userID string) bool {
+	if _, ok := gm.bots[userID]; ok {
+		return true
+	}
+	session, ok := gm.sessions[userID]
+	return ok && session.Conn != nil
+}
+
+func (gm *GameManager) handleCancelSeek(session *PlayerSession) {
+	if !gm.pool.Cancel(session.UserID) {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, Message: "you are not waiting for a game"})
+		return
+	}
+	session.Conn.WriteJSON(OutgoingNotice{Type: SEEK_CANCELLED})
+}
+
+func (gm *GameManager) sendWaiting(session *PlayerSession, status matchmaking.Status) {
+	session.Conn.WriteJSON(OutgoingWaiting{
+		Type:          WAITING,
+		Message:       "waiting for opponent",
+		Position:      status.Position,
+		EstimatedWait: status.EstimatedWait.Milliseconds(),
+	})
+}
@@ -1,16 +1,58 @@
 package gamemanager
 
 import (
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Adi-ty/chess/internal/chat"
 	"github.com/gorilla/websocket"
 )
 
 type PlayerSession struct {
 	UserID       string
-	Conn         *websocket.Conn
-	GameID       string
+	Conn         *Conn
 	Disconnected bool
 	DisconnectedAt time.Time
 	LastSeen     time.Time
+
+	// spectating maps the games this connection watches to the function
+	// that stops watching.
+	spectating map[string]func()
+
+	// chatMuted is read by the game relays of the session while its own
+	// handler may change it.
+	chatMuted   atomic.Bool
+	chatLimiter chat.Limiter
+
+	// games are the IDs of the user's games, oldest first. Games that end
+	// stay for the rematch window.
+	games []string
+}
+
+// Conn is a WebSocket connection that every game of a session, and every
+// game it watches, writes to. The websocket package allows one writer at a
+// time, so writes take turns.
+type Conn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
+}
+
+func newConn(ws *websocket.Conn) *Conn {
+	return &Conn{Conn: ws}
+}
+
+func (c *Conn) WriteJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+func (s *PlayerSession) plays(gameID string) bool {
+	return slices.Contains(s.games, gameID)
+}
+
+func (s *PlayerSession) leave(gameID string) {
+	s.games = slices.DeleteFunc(s.games, func(id string) bool { return id == gameID })
 }
\ No newline at end of file
@@ -0,0 +1,133 @@
+package gamemanager
+
+import (
+	"errors"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+var (
+	ErrRematchUnavailable = errors.New("a rematch can only be offered shortly after the game ends")
+	ErrNoRematchOffer     = errors.New("there is no rematch offer to accept")
+	ErrTournamentRematch  = errors.New("tournament games cannot be rematched")
+)
+
+const rematchWindow = 30 * time.Second
+
+// rematchClosed reports whether the game ended too long ago to be rematched.
+// Correspondence players get as long as they have for a move rather than
+// rematchWindow. The caller must hold g.mu.
+func (g *Game) rematchClosed() bool {
+	window := rematchWindow
+	if g.settings.TimeControl.Correspondence() {
+		window = g.settings.TimeControl.MoveTime()
+	}
+	return time.Since(g.endTime) > window
+}
+
+// OfferRematch forwards a rematch offer to the opponent of a game that just
+// ended. If the opponent already offered one, or is the computer, the rematch
+// starts right away.
+func (gm *GameManager) OfferRematch(session *PlayerSession, gameID string) error {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	game, err := gm.finishedGame(session, gameID)
+	if err != nil {
+		return err
+	}
+
+	game.mu.Lock()
+	opponentID := game.opponentOf(session.UserID)
+	if game.rematchOffer == opponentID || game.isBot(opponentID) {
+		game.mu.Unlock()
+		return gm.startRematch(game)
+	}
+	game.rematchOffer = session.UserID
+	game.sendTo(gm, opponentID, OutgoingNotice{Type: REMATCH_OFFER, GameID: game.ID})
+	game.mu.Unlock()
+
+	return nil
+}
+
+func (gm *GameManager) AcceptRematch(session *PlayerSession, gameID string) error {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	game, err := gm.finishedGame(session, gameID)
+	if err != nil {
+		return err
+	}
+
+	game.mu.RLock()
+	offered := game.rematchOffer != "" && game.rematchOffer != session.UserID
+	game.mu.RUnlock()
+	if !offered {
+		return ErrNoRematchOffer
+	}
+
+	return gm.startRematch(game)
+}
+
+// finishedGame returns the game named by gameID, or else the latest of the
+// session's games that have ended, if it ended within the rematch window.
+// The caller must hold gm.mu.
+func (gm *GameManager) finishedGame(session *PlayerSession, gameID string) (*Game, error) {
+	var game *Game
+	for _, id := range session.games {
+		if g, exists := gm.games[id]; exists && !g.IsActive() && (gameID == "" || id == gameID) {
+			game = g
+		}
+	}
+	if game == nil {
+		return nil, ErrRematchUnavailable
+	}
+
+	game.mu.RLock()
+	defer game.mu.RUnlock()
+
+	if game.status == GameStatusInProgress || game.rematchClosed() {
+		return nil, ErrRematchUnavailable
+	}
+	if game.colorOf(session.UserID) == chess.NoColor {
+		return nil, ErrNotInGame
+	}
+	if game.tournamentID != "" {
+		return nil, ErrTournamentRematch
+	}
+	return game, nil
+}
+
+// startRematch starts the next game of the series with colors swapped. The
+// previous game is forgotten only once the rematch has started, so that a
+// standing offer can still be accepted if a player was briefly offline or
+// busy. The caller must hold gm.mu.
+func (gm *GameManager) startRematch(previous *Game) error {
+	previous.mu.RLock()
+	white, black := previous.BlackUserID, previous.WhiteUserID
+	settings := previous.settings
+	previous.mu.RUnlock()
+
+	for _, userID := range []string{white, black} {
+		if !gm.isConnected(userID) && !settings.TimeControl.Correspondence() {
+			return ErrPlayerOffline
+		}
+		if gm.busy(userID, settings) {
+			return ErrPlayerBusy
+		}
+	}
+
+	gm.pool.Cancel(white)
+	gm.pool.Cancel(black)
+	delete(gm.games, previous.ID)
+
+	gm.startGame(white, black, settings, previous, "")
+	return nil
+}
+
+func (gm *GameManager) handleRematch(session *PlayerSession, gameID string, action func(*GameManager, *PlayerSession, string) error) {
+	if err := action(gm, session, gameID); err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: err.Error()})
+	}
+}
@@ -0,0 +1,169 @@
+package gamemanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrGameNotFound    = errors.New("game not found")
+	ErrAlreadyWatching = errors.New("you are already spectating this game")
+	ErrNotWatching     = errors.New("you are not spectating this game")
+	ErrWatchingOwnGame = errors.New("you are playing this game")
+)
+
+// Spectate subscribes conn to the game's channel and sends it the current
+// position, move list and clocks. Moves and game_over then stream in as they
+// are published, along with the spectator room's chat. session is the
+// watching user's session, or nil for an anonymous read-only connection. The
+// returned function stops spectating.
+func (gm *GameManager) Spectate(conn *Conn, session *PlayerSession, gameID string) (func(), error) {
+	gm.mu.RLock()
+	game, exists := gm.games[gameID]
+	gm.mu.RUnlock()
+	if !exists {
+		return nil, ErrGameNotFound
+	}
+
+	// Subscribing waits on Redis, so it is done before taking the game
+	// lock, and messages published before the snapshot may still be on
+	// their way. Every game message is published under the lock, so a
+	// marker published right after the snapshot tells them apart: whatever
+	// comes before it is already in the snapshot.
+	pubsub := gm.redisClient.Subscribe(context.Background(), "game:"+gameID)
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	marker := spectateMarker{Type: SPECTATE_MARKER, Token: uuid.New().String()}
+
+	game.mu.Lock()
+	game.viewers++
+	conn.WriteJSON(game.snapshot(SPECTATE))
+	game.publish(gm, marker)
+	game.publish(gm, OutgoingViewers{Type: VIEWERS, GameID: game.ID, Count: game.viewers})
+	game.mu.Unlock()
+
+	go func() {
+		caughtUp := false
+		for msg := range pubsub.Channel() {
+			if !caughtUp {
+				var m spectateMarker
+				caughtUp = json.Unmarshal([]byte(msg.Payload), &m) == nil && m == marker
+				continue
+			}
+			if deliverable(msg.Payload, RoomSpectator, session) {
+				game.safeSend(conn, json.RawMessage(msg.Payload))
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			pubsub.Close()
+
+			game.mu.Lock()
+			defer game.mu.Unlock()
+			game.viewers--
+			game.publish(gm, OutgoingViewers{Type: VIEWERS, GameID: game.ID, Count: game.viewers})
+		})
+	}
+	return stop, nil
+}
+
+// spectateMarker is published on a game's channel when a spectator joins,
+// to mark where its snapshot ends. Nobody else receives it.
+type spectateMarker struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// AddSpectator serves a read-only connection watching a single game until
+// the client goes away.
+func (gm *GameManager) AddSpectator(ws *websocket.Conn, gameID string) {
+	conn := newConn(ws)
+	defer conn.Close()
+
+	stop, err := gm.Spectate(conn, nil, gameID)
+	if err != nil {
+		conn.WriteJSON(OutgoingError{Type: ERROR, Message: err.Error()})
+		return
+	}
+	defer stop()
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		conn.WriteJSON(OutgoingError{Type: ERROR, Message: "spectators cannot send messages"})
+	}
+}
+
+func (gm *GameManager) handleSpectate(session *PlayerSession, gameID string) {
+	if session.plays(gameID) {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: ErrWatchingOwnGame.Error()})
+		return
+	}
+	if _, watching := session.spectating[gameID]; watching {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: ErrAlreadyWatching.Error()})
+		return
+	}
+
+	stop, err := gm.Spectate(session.Conn, session, gameID)
+	if err != nil {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: err.Error()})
+		return
+	}
+
+	if session.spectating == nil {
+		session.spectating = make(map[string]func())
+	}
+	session.spectating[gameID] = stop
+}
+
+func (gm *GameManager) handleUnspectate(session *PlayerSession, gameID string) {
+	stop, watching := session.spectating[gameID]
+	if !watching {
+		session.Conn.WriteJSON(OutgoingError{Type: ERROR, GameID: gameID, Message: ErrNotWatching.Error()})
+		return
+	}
+	stop()
+	delete(session.spectating, gameID)
+}
+
+// snapshot describes the game as it stands. The caller must hold g.mu.
+func (g *Game) snapshot(msgType string) OutgoingGameState {
+	whiteMs, blackMs := g.clock.millis(time.Now())
+
+	return OutgoingGameState{
+		Type:        msgType,
+		GameID:      g.ID,
+		WhiteUserID: g.WhiteUserID,
+		BlackUserID: g.BlackUserID,
+		Status:      string(g.status),
+		TimeControl: g.settings.TimeControl.String(),
+		Rated:       g.settings.Rated,
+		Variant:     string(g.board.Variant()),
+		InitialFEN:  g.board.StartFEN(),
+		FEN:         g.board.FEN(),
+		Moves:       g.board.Moves(),
+		WhiteTime:   whiteMs,
+		BlackTime:   blackMs,
+		Deadline:    g.deadlineMillis(),
+		Viewers:     g.viewers,
+	}
+}
+
+func stopSpectating(session *PlayerSession) {
+	for gameID, stop := range session.spectating {
+		stop()
+		delete(session.spectating, gameID)
+	}
+}
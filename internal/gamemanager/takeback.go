@@ -0,0 +1,134 @@
+package gamemanager
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/queue"
+	"github.com/Adi-ty/chess/internal/variant"
+)
+
+var (
+	ErrTakebackRated     = errors.New("takebacks are not allowed in rated games")
+	ErrTakebackPending   = errors.New("a takeback has already been requested")
+	ErrNoTakeback        = errors.New("there is no takeback request to respond to")
+	ErrNothingToTakeBack = errors.New("you have no move to take back")
+)
+
+// RequestTakeback asks the opponent to undo the requester's last move. The
+// request stands until the opponent answers it or a move is made.
+func (g *Game) RequestTakeback(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.settings.Rated {
+		return ErrTakebackRated
+	}
+	if g.takeback != "" {
+		return ErrTakebackPending
+	}
+	if g.takebackPlies(session.UserID) == 0 {
+		return ErrNothingToTakeBack
+	}
+
+	if g.isBot(g.opponentOf(session.UserID)) {
+		// The computer always lets casual players take a move back.
+		g.rewind(gm, g.takebackPlies(session.UserID))
+		return nil
+	}
+
+	g.takeback = session.UserID
+	g.sendTo(gm, g.opponentOf(session.UserID), OutgoingNotice{Type: TAKEBACK_REQUEST, GameID: g.ID})
+	return nil
+}
+
+func (g *Game) AcceptTakeback(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.takeback == "" || g.takeback == session.UserID {
+		return ErrNoTakeback
+	}
+
+	plies := g.takebackPlies(g.takeback)
+	g.takeback = ""
+	if plies == 0 {
+		return ErrNothingToTakeBack
+	}
+
+	g.rewind(gm, plies)
+	return nil
+}
+
+func (g *Game) DeclineTakeback(session *PlayerSession, gm *GameManager) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.checkPlayer(session); err != nil {
+		return err
+	}
+	if g.takeback == "" || g.takeback == session.UserID {
+		return ErrNoTakeback
+	}
+
+	g.sendTo(gm, g.takeback, OutgoingNotice{Type: TAKEBACK_DECLINED, GameID: g.ID})
+	g.takeback = ""
+	return nil
+}
+
+// takebackPlies returns how many half-moves have to be undone to get back to
+// the position before userID's last move, or 0 if they have not moved yet.
+func (g *Game) takebackPlies(userID string) int {
+	plies := 1
+	if g.board.Position().Turn() == g.colorOf(userID) {
+		plies = 2
+	}
+	if plies > len(g.board.Moves()) {
+		return 0
+	}
+	return plies
+}
+
+// rewind undoes the last plies half-moves, drops them from the moves table
+// and sends the resulting position to both players. The caller must hold g.mu.
+func (g *Game) rewind(gm *GameManager, plies int) {
+	moves := g.board.Moves()
+	moves = moves[:len(moves)-plies]
+
+	board, err := variant.Replay(g.board.Variant(), g.board.StartFEN(), moves)
+	if err != nil {
+		log.Printf("Failed to replay moves for takeback in game %s: %v", g.ID, err)
+		return
+	}
+	g.board = board
+	g.moveNumber -= plies
+	g.drawOffer = ""
+
+	if err := queue.EnqueueTakeback(gm.redisClient, g.ID, g.moveNumber); err != nil {
+		log.Printf("Failed to enqueue takeback: %v", err)
+	}
+
+	// Time already spent is not refunded; the clock just switches back to
+	// the side that is to move again.
+	now := time.Now()
+	g.clock.stop(now)
+	g.startClock(gm, now)
+
+	whiteMs, blackMs := g.clock.millis(now)
+	g.publish(gm, OutgoingTakeback{
+		Type:      TAKEBACK,
+		GameID:    g.ID,
+		FEN:       g.board.FEN(),
+		Moves:     moves,
+		WhiteTime: whiteMs,
+		BlackTime: blackMs,
+	})
+	g.scheduleBotMove(gm)
+}
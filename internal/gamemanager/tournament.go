@@ -0,0 +1,805 @@
+package gamemanager
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/tournament"
+	"github.com/notnil/chess"
+)
+
+var (
+	ErrTournamentNotFound    = errors.New("tournament not found")
+	ErrTournamentStarted     = errors.New("the tournament has already started")
+	ErrTournamentFinished    = errors.New("the tournament is over")
+	ErrTournamentUnavailable = errors.New("failed to update the tournament")
+	ErrNotOrganizer          = errors.New("only the organizer can start the tournament")
+	ErrNotRegistered         = errors.New("you are not registered for this tournament")
+	ErrTooFewPlayers         = errors.New("a tournament needs at least two players")
+	ErrTournamentFull        = errors.New("the tournament is full")
+	ErrInvalidTournamentName = errors.New("name must be between 1 and 100 characters")
+	ErrInvalidRounds         = errors.New("rounds must be between 1 and 20")
+	ErrInvalidDuration       = errors.New("duration must be between 5 and 720 minutes")
+	ErrUnknownFormat         = errors.New("format must be swiss, arena, round_robin, single_elimination or double_elimination")
+	ErrUnknownTiebreak       = errors.New("tiebreak must be armageddon or blitz")
+	ErrTournamentAbort       = errors.New("tournament games cannot be aborted")
+)
+
+const (
+	TournamentSwiss             = "swiss"
+	TournamentArena             = "arena"
+	TournamentRoundRobin        = "round_robin"
+	TournamentSingleElimination = "single_elimination"
+	TournamentDoubleElimination = "double_elimination"
+
+	maxTournamentRounds = 20
+
+	// swissPairingAttempts is how often a Swiss round whose players or
+	// results change while it is paired is tried again within one go.
+	swissPairingAttempts = 3
+
+	// Round robins and knockouts are for small groups: every player of a
+	// round robin plays all the others.
+	maxRoundRobinPlayers = 20
+	maxKnockoutPlayers   = 64
+
+	minArenaMinutes = 5
+	maxArenaMinutes = 720
+
+	// tournamentInterval is how often tournaments due to start or to pair
+	// their next round are looked for.
+	tournamentInterval = 10 * time.Second
+
+	// roundBreak is the pause between the last game of a round ending and
+	// the next round being paired, for the players to catch their breath.
+	roundBreak = 30 * time.Second
+
+	// tournamentFirstMoveTimeout stands in for the first move window when
+	// the manager has none.
+	tournamentFirstMoveTimeout = time.Minute
+
+	// armageddonHandicap is how much less time Black has in armageddon.
+	armageddonHandicap = time.Minute
+)
+
+// Knockout tiebreak games are played at fixed time controls and unrated.
+var (
+	blitzTiebreak      = TimeControl{Base: 3 * time.Minute, Increment: 2 * time.Second}
+	armageddonTiebreak = TimeControl{Base: 5 * time.Minute}
+)
+
+type TournamentOptions struct {
+	Name        string
+	Format      string
+	TimeControl string
+	Rated       bool
+	Variant     string
+	// Rounds is how many rounds a Swiss tournament plays, DurationMinutes
+	// how long an arena lasts. Round robins and knockouts have as many
+	// rounds as their players need. Tiebreak decides drawn knockout
+	// matches, by armageddon unless it is blitz.
+	Rounds          int
+	DurationMinutes int
+	Tiebreak        string
+	// StartsAt is when the tournament starts by itself. Without it the
+	// organizer starts it.
+	StartsAt *time.Time
+}
+
+// CreateTournament stores a new tournament open for registration.
+func (gm *GameManager) CreateTournament(creatorID string, opts TournamentOptions) (*store.Tournament, error) {
+	name := strings.TrimSpace(opts.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidTournamentName
+	}
+	format := opts.Format
+	if format == "" {
+		format = TournamentSwiss
+	}
+	settings, err := ParseGameSettings(opts.TimeControl, opts.Rated, opts.Variant, "")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case TournamentSwiss:
+		if opts.Rounds < 1 || opts.Rounds > maxTournamentRounds {
+			return nil, ErrInvalidRounds
+		}
+		opts.DurationMinutes = 0
+	case TournamentArena:
+		if opts.DurationMinutes < minArenaMinutes || opts.DurationMinutes > maxArenaMinutes {
+			return nil, ErrInvalidDuration
+		}
+		if settings.TimeControl.Correspondence() {
+			return nil, ErrArenaCorrespondence
+		}
+		opts.Rounds = 0
+	case TournamentRoundRobin:
+		opts.Rounds, opts.DurationMinutes = 0, 0
+	case TournamentSingleElimination, TournamentDoubleElimination:
+		switch tournament.Tiebreak(opts.Tiebreak) {
+		case "":
+			opts.Tiebreak = string(tournament.TiebreakArmageddon)
+		case tournament.TiebreakArmageddon, tournament.TiebreakBlitz:
+		default:
+			return nil, ErrUnknownTiebreak
+		}
+		opts.Rounds, opts.DurationMinutes = 0, 0
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if !knockout(format) {
+		opts.Tiebreak = ""
+	}
+
+	t := &store.Tournament{
+		Name:        name,
+		Format:      format,
+		CreatorID:   creatorID,
+		TimeControl: settings.TimeControl.String(),
+		Rated:       settings.Rated,
+		Variant:     string(settings.Variant),
+		Rounds:      opts.Rounds,
+		StartsAt:    opts.StartsAt,
+
+		DurationMinutes: opts.DurationMinutes,
+		Tiebreak:        opts.Tiebreak,
+	}
+	if err := gm.tournamentStore.CreateTournament(context.Background(), t); err != nil {
+		log.Printf("Failed to create tournament: %v", err)
+		return nil, ErrTournamentUnavailable
+	}
+	return t, nil
+}
+
+// JoinTournament registers a player, seeded by their rating in the
+// tournament's category. Players may join until the tournament is over;
+// latecomers are paired from the next round, or in a running arena right
+// away. Round robins and knockouts only take players until they start.
+func (gm *GameManager) JoinTournament(userID, tournamentID string) error {
+	gm.tournamentMu.Lock()
+	defer gm.tournamentMu.Unlock()
+
+	t, err := gm.getTournament(tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.Status == store.TournamentFinished {
+		return ErrTournamentFinished
+	}
+	if limit := fieldLimit(t.Format); limit > 0 {
+		if t.Status != store.TournamentCreated {
+			return ErrTournamentStarted
+		}
+		players, err := gm.tournamentPlayers(t.ID)
+		if err != nil {
+			return err
+		}
+		if len(players) >= limit && !slices.ContainsFunc(players, func(p tournament.Player) bool { return p.ID == userID }) {
+			return ErrTournamentFull
+		}
+	}
+	settings, err := tournamentSettings(t)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	r, err := gm.ratingStore.GetRating(ctx, userID, string(settings.RatingCategory()))
+	if err != nil {
+		log.Printf("Failed to fetch rating for %s: %v", userID, err)
+		return ErrTournamentUnavailable
+	}
+	player := tournament.Player{ID: userID, Rating: r.Rating}
+	if err := gm.tournamentStore.AddTournamentPlayer(ctx, t.ID, player); err != nil {
+		log.Printf("Failed to register %s for tournament %s: %v", userID, t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	if a := gm.arenas[t.ID]; a != nil {
+		gm.enterArena(a, player, "")
+	}
+	return nil
+}
+
+// WithdrawFromTournament leaves the player out of the rounds still to be
+// paired. A game in progress is played out and earlier results stand.
+func (gm *GameManager) WithdrawFromTournament(userID, tournamentID string) error {
+	gm.tournamentMu.Lock()
+	defer gm.tournamentMu.Unlock()
+
+	t, err := gm.getTournament(tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.Status == store.TournamentFinished {
+		return ErrTournamentFinished
+	}
+	players, err := gm.tournamentStore.ListTournamentPlayers(context.Background(), t.ID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	registered := false
+	for _, p := range players {
+		registered = registered || (p.ID == userID && !p.Withdrawn)
+	}
+	if !registered {
+		return ErrNotRegistered
+	}
+
+	if err := gm.tournamentStore.WithdrawTournamentPlayer(context.Background(), t.ID, userID); err != nil {
+		log.Printf("Failed to withdraw %s from tournament %s: %v", userID, t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	if a := gm.arenas[t.ID]; a != nil {
+		a.pool.Cancel(userID)
+	}
+	return nil
+}
+
+// StartTournament lets the organizer start the tournament before, or
+// instead of, its scheduled time.
+func (gm *GameManager) StartTournament(userID, tournamentID string) error {
+	gm.tournamentMu.Lock()
+	defer gm.tournamentMu.Unlock()
+
+	t, err := gm.getTournament(tournamentID)
+	if err != nil {
+		return err
+	}
+	if t.CreatorID != userID {
+		return ErrNotOrganizer
+	}
+	if t.Status != store.TournamentCreated {
+		return ErrTournamentStarted
+	}
+	return gm.startTournament(t)
+}
+
+// startTournament pairs the first round, or opens an arena. The caller must
+// hold gm.tournamentMu.
+func (gm *GameManager) startTournament(t *store.Tournament) error {
+	if t.Format == TournamentArena {
+		return gm.startArena(t)
+	}
+	return gm.startRound(t, 1)
+}
+
+// RunTournamentScheduler starts the tournaments whose time has come and
+// pairs the next round of those whose last round ended at least roundBreak
+// ago. Rounds are paired from the store, so a tournament carries on after
+// a restart. Running arenas are kept going and closed when time is up.
+func (gm *GameManager) RunTournamentScheduler() {
+	ticker := time.NewTicker(tournamentInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		ctx := context.Background()
+
+		due, err := gm.tournamentStore.ListDueTournaments(ctx, now)
+		if err != nil {
+			log.Printf("Failed to fetch due tournaments: %v", err)
+		}
+		for _, t := range due {
+			gm.tournamentMu.Lock()
+			if err := gm.startTournament(t); errors.Is(err, ErrTooFewPlayers) {
+				log.Printf("Tournament %s cancelled: %v", t.ID, err)
+				gm.finishTournament(t)
+			}
+			gm.tournamentMu.Unlock()
+		}
+
+		running, err := gm.tournamentStore.ListTournaments(ctx, store.TournamentInProgress)
+		if err != nil {
+			log.Printf("Failed to fetch running tournaments: %v", err)
+		}
+		for _, t := range running {
+			gm.tournamentMu.Lock()
+			gm.advanceTournament(t, now)
+			gm.tournamentMu.Unlock()
+		}
+	}
+}
+
+// advanceTournament pairs the next round once the current one has been over
+// for roundBreak. The caller must hold gm.tournamentMu.
+func (gm *GameManager) advanceTournament(t *store.Tournament, now time.Time) {
+	if t.Format == TournamentArena {
+		gm.advanceArena(t, now)
+		return
+	}
+
+	rounds, err := gm.tournamentStore.ListTournamentRounds(context.Background(), t.ID)
+	if err != nil {
+		log.Printf("Failed to fetch rounds of tournament %s: %v", t.ID, err)
+		return
+	}
+	if len(rounds) == 0 {
+		return
+	}
+	last := rounds[len(rounds)-1]
+	if last.EndedAt == nil || now.Sub(*last.EndedAt) < roundBreak {
+		return
+	}
+
+	if err := gm.startRound(t, last.Round+1); errors.Is(err, ErrTooFewPlayers) {
+		gm.finishTournament(t)
+	}
+}
+
+// startRound pairs round and starts its games: Swiss rounds among the
+// players who have not withdrawn, round robin rounds by the Berger tables.
+// A knockout round starts with whichever of its matches have their players,
+// the rest as the matches they wait for are decided. The caller must hold
+// gm.tournamentMu, which is let go while a Swiss round is paired.
+func (gm *GameManager) startRound(t *store.Tournament, round int) error {
+	ctx := context.Background()
+	history, err := gm.tournamentStore.ListTournamentPairings(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to fetch pairings of tournament %s: %v", t.ID, err)
+		return ErrTournamentUnavailable
+	}
+
+	var pairings []tournament.Pairing
+	switch t.Format {
+	case TournamentSwiss:
+		if pairings, err = gm.pairSwiss(t, round, history); err != nil {
+			return err
+		}
+	default:
+		if round == 1 {
+			if err := gm.seedTournament(t); err != nil {
+				return err
+			}
+		}
+		if t.Format == TournamentRoundRobin {
+			field, withdrawn, err := gm.tournamentField(t)
+			if err != nil {
+				return err
+			}
+			pairings = forfeits(tournament.RoundRobin(round, field), withdrawn, false)
+		}
+	}
+
+	settings, err := tournamentSettings(t)
+	if err != nil {
+		return err
+	}
+	// The round is stored before its games start, so that no game is left
+	// without a pairing to record its result.
+	if err := gm.tournamentStore.StartTournamentRound(ctx, t.ID, round, pairings); err != nil {
+		log.Printf("Failed to store round %d of tournament %s: %v", round, t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	gm.startGames(t, settings, pairings)
+	t.Status, t.CurrentRound = store.TournamentInProgress, round
+	gm.announceRound(t, pairings)
+
+	log.Printf("Tournament %s: round %d started with %d pairings", t.ID, round, len(pairings))
+	gm.checkRound(t)
+	return nil
+}
+
+// pairSwiss pairs a Swiss round among the players who have not withdrawn.
+// history is every pairing so far. gm.tournamentMu is let go while the
+// pairings are worked out, so that a large field does not hold up every
+// other tournament, and the round is paired again if its players or results
+// changed in the meantime. The caller must hold gm.tournamentMu.
+func (gm *GameManager) pairSwiss(t *store.Tournament, round int, history []tournament.Pairing) ([]tournament.Pairing, error) {
+	for attempt := 0; attempt < swissPairingAttempts; attempt++ {
+		players, err := gm.tournamentPlayers(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(players) < 2 {
+			return nil, ErrTooFewPlayers
+		}
+
+		gm.tournamentMu.Unlock()
+		pairings := tournament.PairSwiss(round, players, history)
+		gm.tournamentMu.Lock()
+
+		current, err := gm.tournamentPlayers(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		latest, err := gm.tournamentStore.ListTournamentPairings(context.Background(), t.ID)
+		if err != nil {
+			log.Printf("Failed to fetch pairings of tournament %s: %v", t.ID, err)
+			return nil, ErrTournamentUnavailable
+		}
+		if slices.ContainsFunc(latest, func(p tournament.Pairing) bool { return p.Round >= round }) {
+			return nil, ErrTournamentStarted
+		}
+		if slices.Equal(players, current) && slices.Equal(history, latest) {
+			return pairings, nil
+		}
+		history = latest
+	}
+	log.Printf("Tournament %s: round %d kept changing while it was paired", t.ID, round)
+	return nil, ErrTournamentUnavailable
+}
+
+// seedTournament seeds the players of a round robin or knockout by rating
+// and fixes the number of rounds they need. Players who register later are
+// left out. The caller must hold gm.tournamentMu.
+func (gm *GameManager) seedTournament(t *store.Tournament) error {
+	field, err := gm.tournamentPlayers(t.ID)
+	if err != nil {
+		return err
+	}
+	if len(field) < 2 {
+		return ErrTooFewPlayers
+	}
+	slices.SortStableFunc(field, func(a, b tournament.Player) int {
+		return cmp.Or(cmp.Compare(b.Rating, a.Rating), cmp.Compare(a.ID, b.ID))
+	})
+	seeds := make([]string, len(field))
+	for i, p := range field {
+		seeds[i] = p.ID
+	}
+
+	rounds := tournament.RoundRobinRounds(len(field))
+	if knockout(t.Format) {
+		rounds = tournament.KnockoutRounds(len(field), t.Format == TournamentDoubleElimination)
+	}
+	if err := gm.tournamentStore.SeedTournament(context.Background(), t.ID, seeds, rounds); err != nil {
+		log.Printf("Failed to seed tournament %s: %v", t.ID, err)
+		return ErrTournamentUnavailable
+	}
+	t.Rounds = rounds
+	return nil
+}
+
+// tournamentField returns the seeded players of a round robin or knockout
+// in seed order and which of them have withdrawn.
+func (gm *GameManager) tournamentField(t *store.Tournament) ([]tournament.Player, map[string]bool, error) {
+	registered, err := gm.tournamentStore.ListTournamentPlayers(context.Background(), t.ID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", t.ID, err)
+		return nil, nil, ErrTournamentUnavailable
+	}
+	seeded := slices.DeleteFunc(slices.Clone(registered), func(p *store.TournamentPlayer) bool { return p.Seed == 0 })
+	slices.SortFunc(seeded, func(a, b *store.TournamentPlayer) int { return a.Seed - b.Seed })
+
+	field := make([]tournament.Player, 0, len(seeded))
+	withdrawn := make(map[string]bool)
+	for _, p := range seeded {
+		field = append(field, p.Player)
+		withdrawn[p.ID] = p.Withdrawn
+	}
+	return field, withdrawn, nil
+}
+
+// forfeits scores the games of players who have withdrawn as lost without
+// being played. When both have withdrawn the game is dropped, unless keep,
+// when White wins it so that a knockout match is still decided.
+func forfeits(pairings []tournament.Pairing, withdrawn map[string]bool, keep bool) []tournament.Pairing {
+	result := pairings[:0]
+	for _, p := range pairings {
+		switch w, b := withdrawn[p.White], withdrawn[p.Black]; {
+		case w && b && !keep:
+			continue
+		case w && !b:
+			p.Result = tournament.BlackWon
+		case b:
+			p.Result = tournament.WhiteWon
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// startGames starts the game of every pairing still to be played, fills in
+// its GameID and links it to the pairing, which must have been stored.
+// Knockout tiebreaks are played at their own time controls. The caller must
+// hold gm.tournamentMu.
+func (gm *GameManager) startGames(t *store.Tournament, settings GameSettings, pairings []tournament.Pairing) {
+	gm.mu.Lock()
+	for i := range pairings {
+		if pairings[i].Finished() {
+			continue
+		}
+		game := gm.startGame(pairings[i].White, pairings[i].Black, tiebreakSettings(settings, t, pairings[i].Game), nil, t.ID)
+		pairings[i].GameID = game.ID
+	}
+	gm.mu.Unlock()
+
+	for _, p := range pairings {
+		if p.GameID == "" {
+			continue
+		}
+		if err := gm.tournamentStore.SetPairingGame(context.Background(), t.ID, p); err != nil {
+			log.Printf("Failed to store game %s of board %d, round %d of tournament %s: %v", p.GameID, p.Board, p.Round, t.ID, err)
+		}
+	}
+}
+
+// tiebreakSettings are the settings of the game-th game of a knockout
+// match. Only the first is played at the tournament's own.
+func tiebreakSettings(settings GameSettings, t *store.Tournament, game int) GameSettings {
+	switch tournament.Tiebreak(t.Tiebreak).Kind(game) {
+	case tournament.BlitzGame:
+		return GameSettings{TimeControl: blitzTiebreak, Variant: settings.Variant}
+	case tournament.ArmageddonGame:
+		return GameSettings{TimeControl: armageddonTiebreak, Variant: settings.Variant, Armageddon: true}
+	default:
+		return settings
+	}
+}
+
+// announceRound tells every paired player their pairing, including the one
+// with the bye, who gets no game.
+func (gm *GameManager) announceRound(t *store.Tournament, pairings []tournament.Pairing) {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	for _, p := range pairings {
+		for _, userID := range []string{p.White, p.Black} {
+			if session, ok := gm.sessions[userID]; ok && session.Conn != nil {
+				session.Conn.WriteJSON(OutgoingTournamentRound{Type: TOURNAMENT_ROUND, TournamentID: t.ID, Pairing: p})
+			}
+		}
+	}
+}
+
+// tournamentGameOver records the result of a tournament game. The last
+// result of a round ends the round, and the last round the tournament.
+// Arenas carry on as arenaGameOver decides.
+func (gm *GameManager) tournamentGameOver(tournamentID string, result tournament.Pairing) {
+	gm.tournamentMu.Lock()
+	defer gm.tournamentMu.Unlock()
+
+	ctx := context.Background()
+	if err := gm.tournamentStore.SetPairingResult(ctx, result); err != nil {
+		log.Printf("Failed to record result of tournament game %s: %v", result.GameID, err)
+		return
+	}
+
+	t, err := gm.getTournament(tournamentID)
+	if err != nil {
+		return
+	}
+	pairings, err := gm.tournamentStore.ListTournamentPairings(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to fetch pairings of tournament %s: %v", t.ID, err)
+		return
+	}
+	if t.Format == TournamentArena {
+		gm.arenaGameOver(t, result, pairings)
+		return
+	}
+	gm.checkRound(t)
+}
+
+// checkRound ends the current round once all its games are over, and the
+// tournament with its last round. Knockout matches are played out first:
+// a drawn match gets its next tiebreak game and a match whose players have
+// become known its first. The caller must hold gm.tournamentMu.
+func (gm *GameManager) checkRound(t *store.Tournament) {
+	ctx := context.Background()
+	round := t.CurrentRound
+	var last bool
+	for {
+		pairings, err := gm.tournamentStore.ListTournamentPairings(ctx, t.ID)
+		if err != nil {
+			log.Printf("Failed to fetch pairings of tournament %s: %v", t.ID, err)
+			return
+		}
+		if !knockout(t.Format) {
+			for _, p := range pairings {
+				if p.Round == round && !p.Finished() {
+					return
+				}
+			}
+			last = round >= t.Rounds
+			break
+		}
+
+		bracket, err := gm.bracket(t, pairings)
+		if err != nil {
+			return
+		}
+		next := bracket.NextGames(round)
+		if len(next) == 0 {
+			if !bracket.RoundDone(round) {
+				return
+			}
+			last = bracket.Finished()
+			break
+		}
+		started, err := gm.startMatchGames(t, next)
+		if err != nil || started {
+			return
+		}
+		// Only forfeits: they may have decided matches that others of the
+		// round were waiting for.
+	}
+
+	if err := gm.tournamentStore.FinishTournamentRound(ctx, t.ID, round); err != nil {
+		log.Printf("Failed to end round %d of tournament %s: %v", round, t.ID, err)
+	}
+	if last {
+		gm.finishTournament(t)
+	}
+}
+
+// startMatchGames adds the next games of knockout matches to the current
+// round, forfeiting those of players who have withdrawn, and reports
+// whether any game was started. The caller must hold gm.tournamentMu.
+func (gm *GameManager) startMatchGames(t *store.Tournament, next []tournament.Pairing) (bool, error) {
+	_, withdrawn, err := gm.tournamentField(t)
+	if err != nil {
+		return false, err
+	}
+	next = forfeits(next, withdrawn, true)
+	settings, err := tournamentSettings(t)
+	if err != nil {
+		return false, err
+	}
+
+	started := false
+	for i := range next {
+		if err := gm.tournamentStore.AddTournamentPairing(context.Background(), t.ID, &next[i]); err != nil {
+			log.Printf("Failed to store game %d of board %d, round %d of tournament %s: %v", next[i].Game, next[i].Board, next[i].Round, t.ID, err)
+			return false, ErrTournamentUnavailable
+		}
+		started = started || !next[i].Finished()
+	}
+	gm.startGames(t, settings, next)
+	gm.announceRound(t, next)
+	return started, nil
+}
+
+// bracket plays out a knockout from its pairings.
+func (gm *GameManager) bracket(t *store.Tournament, pairings []tournament.Pairing) (*tournament.Bracket, error) {
+	field, _, err := gm.tournamentField(t)
+	if err != nil {
+		return nil, err
+	}
+	return tournament.Knockout(field, t.Format == TournamentDoubleElimination, tournament.Tiebreak(t.Tiebreak), pairings), nil
+}
+
+// finishTournament closes the tournament and stores everyone's final rank
+// and score. The caller must hold gm.tournamentMu.
+func (gm *GameManager) finishTournament(t *store.Tournament) {
+	ctx := context.Background()
+	registered, err := gm.tournamentStore.ListTournamentPlayers(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", t.ID, err)
+		return
+	}
+	pairings, err := gm.tournamentStore.ListTournamentPairings(ctx, t.ID)
+	if err != nil {
+		log.Printf("Failed to fetch pairings of tournament %s: %v", t.ID, err)
+		return
+	}
+
+	players := contenders(registered, pairings)
+	var results []store.TournamentResult
+	switch {
+	case t.Format == TournamentArena:
+		for _, s := range tournament.ArenaStandings(players, pairings) {
+			results = append(results, store.TournamentResult{UserID: s.ID, Rank: s.Rank, Score: float64(s.Score)})
+		}
+	case knockout(t.Format) && t.Rounds > 0:
+		bracket, err := gm.bracket(t, pairings)
+		if err != nil {
+			return
+		}
+		for _, s := range bracket.Standings() {
+			results = append(results, store.TournamentResult{UserID: s.ID, Rank: s.Rank, Score: float64(s.Wins)})
+		}
+	default:
+		for _, s := range tournament.Standings(players, pairings) {
+			results = append(results, store.TournamentResult{UserID: s.ID, Rank: s.Rank, Score: s.Score})
+		}
+	}
+
+	if err := gm.tournamentStore.FinishTournament(ctx, t.ID, results); err != nil {
+		log.Printf("Failed to finish tournament %s: %v", t.ID, err)
+		return
+	}
+	if t.Format == TournamentArena {
+		delete(gm.arenas, t.ID)
+		gm.pushArenaStandings(t, registered, pairings, true)
+	}
+	log.Printf("Tournament %s finished", t.ID)
+}
+
+// contenders are the players to rank: those still in the tournament and
+// those who withdrew after playing.
+func contenders(registered []*store.TournamentPlayer, pairings []tournament.Pairing) []tournament.Player {
+	paired := make(map[string]bool)
+	for _, p := range pairings {
+		paired[p.White] = true
+		paired[p.Black] = true
+	}
+	var players []tournament.Player
+	for _, p := range registered {
+		if !p.Withdrawn || paired[p.ID] {
+			players = append(players, p.Player)
+		}
+	}
+	return players
+}
+
+// tournamentResult is the game's pairing result for the tournament
+// standings. A game that ended without a result was not started in time,
+// which loses it for the player who was to move. Their opponent only wins it
+// by being there: if they are away too, it is a double forfeit.
+func (g *Game) tournamentResult(outcome string) tournament.Pairing {
+	result := tournament.Result(outcome)
+	if outcome == chess.NoOutcome.String() {
+		turn := g.board.Position().Turn()
+		result = tournament.Result(lossFor(turn).String())
+		waiting := g.WhiteUserID
+		if turn == chess.White {
+			waiting = g.BlackUserID
+		}
+		if _, away := g.disconnected[waiting]; away {
+			result = tournament.DoubleForfeit
+		}
+	}
+	return tournament.Pairing{
+		GameID:       g.ID,
+		Result:       result,
+		WhiteBerserk: g.clock.berserked(true),
+		BlackBerserk: g.clock.berserked(false),
+	}
+}
+
+func (gm *GameManager) getTournament(id string) (*store.Tournament, error) {
+	t, err := gm.tournamentStore.GetTournament(context.Background(), id)
+	if err != nil {
+		log.Printf("Failed to get tournament %s: %v", id, err)
+		return nil, ErrTournamentUnavailable
+	}
+	if t == nil {
+		return nil, ErrTournamentNotFound
+	}
+	return t, nil
+}
+
+// knockout reports whether format is played in a bracket.
+func knockout(format string) bool {
+	return format == TournamentSingleElimination || format == TournamentDoubleElimination
+}
+
+// fieldLimit is how many players a tournament of format takes, 0 when
+// there is no limit and players may join late.
+func fieldLimit(format string) int {
+	switch {
+	case format == TournamentRoundRobin:
+		return maxRoundRobinPlayers
+	case knockout(format):
+		return maxKnockoutPlayers
+	}
+	return 0
+}
+
+// tournamentPlayers returns the players who have not withdrawn.
+func (gm *GameManager) tournamentPlayers(tournamentID string) ([]tournament.Player, error) {
+	registered, err := gm.tournamentStore.ListTournamentPlayers(context.Background(), tournamentID)
+	if err != nil {
+		log.Printf("Failed to list players of tournament %s: %v", tournamentID, err)
+		return nil, ErrTournamentUnavailable
+	}
+	var players []tournament.Player
+	for _, p := range registered {
+		if !p.Withdrawn {
+			players = append(players, p.Player)
+		}
+	}
+	return players, nil
+}
+
+func tournamentSettings(t *store.Tournament) (GameSettings, error) {
+	return ParseGameSettings(t.TimeControl, t.Rated, t.Variant, "")
+}
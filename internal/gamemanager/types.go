@@ -1,35 +1,188 @@
 package gamemanager
 
+import "github.com/Adi-ty/chess/internal/tournament"
+
 type IncomingMessage struct {
-	Type string `json:"type"`
-	Move string `json:"move,omitempty"`
+	Type        string `json:"type"`
+	Move        string `json:"move,omitempty"`
+	TimeControl string `json:"time_control,omitempty"`
+	Rated       bool   `json:"rated,omitempty"`
+	OpponentID  string `json:"opponent_id,omitempty"`
+	Color       string `json:"color,omitempty"`
+	ChallengeID string `json:"challenge_id,omitempty"`
+	GameID      string `json:"game_id,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Muted       bool   `json:"muted,omitempty"`
+	Level       int    `json:"level,omitempty"`
+	Variant     string `json:"variant,omitempty"`
+	FEN         string `json:"fen,omitempty"`
 }
 
 type OutgoingMove struct {
-	Type string `json:"type"`
-	Move string `json:"move"`
+	Type      string `json:"type"`
+	GameID    string `json:"game_id"`
+	Move      string `json:"move"`
+	WhiteTime int64  `json:"white_time_ms"`
+	BlackTime int64  `json:"black_time_ms"`
+	Deadline  int64  `json:"move_deadline_ms,omitempty"`
 }
 
 type OutgoingGameOver struct {
-	Type    string `json:"type"`
-	Outcome string `json:"outcome"`
-	Method  string `json:"method"`
+	Type    string                          `json:"type"`
+	GameID  string                          `json:"game_id"`
+	Outcome string                          `json:"outcome"`
+	Method  string                          `json:"method"`
+	Ratings map[string]OutgoingRatingChange `json:"ratings,omitempty"`
+}
+
+type OutgoingRatingChange struct {
+	Rating      int  `json:"rating"`
+	Diff        int  `json:"diff"`
+	Provisional bool `json:"provisional"`
 }
 
 type OutgoingError struct {
 	Type    string `json:"type"`
+	GameID  string `json:"game_id,omitempty"`
 	Message string `json:"message"`
 }
 
+type OutgoingTakeback struct {
+	Type      string   `json:"type"`
+	GameID    string   `json:"game_id"`
+	FEN       string   `json:"fen"`
+	Moves     []string `json:"moves"`
+	WhiteTime int64    `json:"white_time_ms"`
+	BlackTime int64    `json:"black_time_ms"`
+}
+
+type OutgoingChallenge struct {
+	Type      string     `json:"type"`
+	Challenge *Challenge `json:"challenge"`
+}
+
+type OutgoingChallengeUpdate struct {
+	Type        string `json:"type"`
+	ChallengeID string `json:"challenge_id"`
+}
+
+type OutgoingGameState struct {
+	Type        string   `json:"type"`
+	GameID      string   `json:"game_id"`
+	WhiteUserID string   `json:"white_user_id"`
+	BlackUserID string   `json:"black_user_id"`
+	Status      string   `json:"status"`
+	TimeControl string   `json:"time_control"`
+	Rated       bool     `json:"rated"`
+	Variant     string   `json:"variant"`
+	InitialFEN  string   `json:"initial_fen"`
+	FEN         string   `json:"fen"`
+	Moves       []string `json:"moves"`
+	WhiteTime   int64    `json:"white_time_ms"`
+	BlackTime   int64    `json:"black_time_ms"`
+	Deadline    int64    `json:"move_deadline_ms,omitempty"`
+	Viewers     int      `json:"viewers"`
+}
+
+type OutgoingViewers struct {
+	Type   string `json:"type"`
+	GameID string `json:"game_id"`
+	Count  int    `json:"count"`
+}
+
+type OutgoingNotice struct {
+	Type   string `json:"type"`
+	GameID string `json:"game_id,omitempty"`
+}
+
+type OutgoingChat struct {
+	Type   string `json:"type"`
+	GameID string `json:"game_id"`
+	Room   string `json:"room"`
+	UserID string `json:"user_id"`
+	Text   string `json:"text"`
+	SentAt int64  `json:"sent_at_ms"`
+}
+
+type OutgoingChatMuted struct {
+	Type  string `json:"type"`
+	Muted bool   `json:"muted"`
+}
+
+type OutgoingTournamentRound struct {
+	Type         string             `json:"type"`
+	TournamentID string             `json:"tournament_id"`
+	Pairing      tournament.Pairing `json:"pairing"`
+}
+
+type OutgoingArenaStandings struct {
+	Type         string                     `json:"type"`
+	TournamentID string                     `json:"tournament_id"`
+	EndsAt       int64                      `json:"ends_at_ms"`
+	Finished     bool                       `json:"finished"`
+	Standings    []tournament.ArenaStanding `json:"standings"`
+}
+
+type OutgoingBerserk struct {
+	Type      string `json:"type"`
+	GameID    string `json:"game_id"`
+	Color     string `json:"color"`
+	WhiteTime int64  `json:"white_time_ms"`
+	BlackTime int64  `json:"black_time_ms"`
+}
+
 type OutgoingWaiting struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
+	Type          string `json:"type"`
+	Message       string `json:"message"`
+	Position      int    `json:"position"`
+	EstimatedWait int64  `json:"estimated_wait_ms"`
 }
 
 const (
-	INIT_GAME = "init_game"
-	MOVE      = "move"
+	INIT_GAME         = "init_game"
+	CANCEL_SEEK       = "cancel_seek"
+	PLAY_COMPUTER     = "play_computer"
+	CHALLENGE         = "challenge"
+	CHALLENGE_ACCEPT  = "challenge_accept"
+	CHALLENGE_DECLINE = "challenge_decline"
+	REMATCH_OFFER     = "rematch_offer"
+	REMATCH_ACCEPT    = "rematch_accept"
+	SPECTATE          = "spectate"
+	UNSPECTATE        = "unspectate"
+	MOVE              = "move"
+	RESIGN            = "resign"
+	OFFER_DRAW        = "offer_draw"
+	ACCEPT_DRAW       = "accept_draw"
+	DECLINE_DRAW      = "decline_draw"
+	CLAIM_DRAW        = "claim_draw"
+	ABORT             = "abort"
+	BERSERK           = "berserk"
+	CHAT              = "chat"
+	MUTE_CHAT         = "mute_chat"
+
+	TAKEBACK_REQUEST = "takeback_request"
+	TAKEBACK_ACCEPT  = "takeback_accept"
+	TAKEBACK_DECLINE = "takeback_decline"
+
 	GAME_OVER = "game_over"
 	ERROR     = "error"
 	WAITING   = "waiting"
+
+	SEEK_CANCELLED     = "seek_cancelled"
+	CHALLENGE_CREATED  = "challenge_created"
+	CHALLENGE_DECLINED = "challenge_declined"
+	DRAW_OFFER         = "draw_offer"
+	DRAW_DECLINED      = "draw_declined"
+
+	GAME_STATE        = "game_state"
+	VIEWERS           = "viewers"
+	TAKEBACK          = "takeback"
+	TAKEBACK_DECLINED = "takeback_declined"
+	CHAT_MUTED        = "chat_muted"
+	TOURNAMENT_ROUND  = "tournament_round"
+	ARENA_STANDINGS   = "arena_standings"
+
+	// SPECTATE_MARKER only travels on game channels and is never sent to
+	// clients.
+	SPECTATE_MARKER = "spectate_marker"
 )
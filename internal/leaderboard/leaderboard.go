@@ -0,0 +1,145 @@
+package leaderboard
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// InactiveAfter is how long a player can go without a rated game in a
+// category before dropping off its leaderboard.
+const InactiveAfter = 30 * 24 * time.Hour
+
+// Each category has two sorted sets: one scored by rating, which is the
+// leaderboard itself, and one scored by the time of the player's last rated
+// game, used to drop inactive players.
+func ratingKey(category string) string { return "leaderboard:" + category }
+func seenKey(category string) string   { return "leaderboard:" + category + ":seen" }
+
+type Entry struct {
+	Rank   int64   `json:"rank"`
+	UserID string  `json:"user_id"`
+	Rating float64 `json:"rating"`
+}
+
+type Leaderboard struct {
+	redisClient *redis.Client
+}
+
+func New(redisClient *redis.Client) *Leaderboard {
+	return &Leaderboard{redisClient: redisClient}
+}
+
+// Record updates a player's entry after a rated game. Provisional players are
+// removed rather than ranked.
+func (l *Leaderboard) Record(ctx context.Context, r *store.Rating) error {
+	pipe := l.redisClient.TxPipeline()
+	if r.Provisional {
+		pipe.ZRem(ctx, ratingKey(r.Category), r.UserID)
+		pipe.ZRem(ctx, seenKey(r.Category), r.UserID)
+	} else {
+		pipe.ZAdd(ctx, ratingKey(r.Category), redis.Z{Score: r.Rating, Member: r.UserID})
+		pipe.ZAdd(ctx, seenKey(r.Category), redis.Z{Score: float64(r.UpdatedAt.Unix()), Member: r.UserID})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Page returns up to limit entries starting at offset, best first, and the
+// number of ranked players.
+func (l *Leaderboard) Page(ctx context.Context, category string, offset, limit int64) ([]Entry, int64, error) {
+	if err := l.prune(ctx, category); err != nil {
+		return nil, 0, err
+	}
+
+	members, err := l.redisClient.ZRevRangeWithScores(ctx, ratingKey(category), offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := l.redisClient.ZCard(ctx, ratingKey(category)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]Entry, len(members))
+	for i, m := range members {
+		entries[i] = Entry{Rank: offset + int64(i) + 1, UserID: m.Member.(string), Rating: m.Score}
+	}
+	return entries, total, nil
+}
+
+// Rank returns the player's entry, or nil if they are not ranked.
+func (l *Leaderboard) Rank(ctx context.Context, category, userID string) (*Entry, error) {
+	rank, err := l.redisClient.ZRevRank(ctx, ratingKey(category), userID).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	score, err := l.redisClient.ZScore(ctx, ratingKey(category), userID).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Rank: rank + 1, UserID: userID, Rating: score}, nil
+}
+
+// Rebuild replaces a category's sets with the given ratings. The new sets are
+// built under temporary keys and renamed into place, so readers never see a
+// half-built leaderboard.
+func (l *Leaderboard) Rebuild(ctx context.Context, category string, ratings []*store.Rating) error {
+	tmpRating := ratingKey(category) + ":rebuild"
+	tmpSeen := seenKey(category) + ":rebuild"
+
+	pipe := l.redisClient.TxPipeline()
+	pipe.Del(ctx, tmpRating, tmpSeen)
+	for _, r := range ratings {
+		if r.Provisional {
+			continue
+		}
+		pipe.ZAdd(ctx, tmpRating, redis.Z{Score: r.Rating, Member: r.UserID})
+		pipe.ZAdd(ctx, tmpSeen, redis.Z{Score: float64(r.UpdatedAt.Unix()), Member: r.UserID})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	n, err := l.redisClient.ZCard(ctx, tmpRating).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return l.redisClient.Del(ctx, ratingKey(category), seenKey(category)).Err()
+	}
+
+	pipe = l.redisClient.TxPipeline()
+	pipe.Rename(ctx, tmpRating, ratingKey(category))
+	pipe.Rename(ctx, tmpSeen, seenKey(category))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// prune drops the players whose last rated game is older than InactiveAfter.
+func (l *Leaderboard) prune(ctx context.Context, category string) error {
+	cutoff := strconv.FormatInt(time.Now().Add(-InactiveAfter).Unix(), 10)
+	stale, err := l.redisClient.ZRangeByScore(ctx, seenKey(category), &redis.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+
+	members := make([]interface{}, len(stale))
+	for i, userID := range stale {
+		members[i] = userID
+	}
+	pipe := l.redisClient.TxPipeline()
+	pipe.ZRem(ctx, ratingKey(category), members...)
+	pipe.ZRem(ctx, seenKey(category), members...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,246 @@
+package matchmaking
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAlreadySeeking = errors.New("already waiting for opponent")
+)
+
+// Config controls how far apart in rating two seeks may be. A new seek only
+// accepts opponents within InitialWindow points; the window then widens by
+// WindowGrowth points per second of waiting, up to MaxWindow. Seeks that
+// avoid each other are kept apart until one of them has waited AvoidFor.
+type Config struct {
+	InitialWindow float64
+	WindowGrowth  float64
+	MaxWindow     float64
+	AvoidFor      time.Duration
+}
+
+var DefaultConfig = Config{
+	InitialWindow: 100,
+	WindowGrowth:  10,
+	MaxWindow:     700,
+}
+
+// Seek is one player waiting for a game. K identifies the kind of game
+// wanted (time control, variant, ...); only seeks with equal keys are paired.
+type Seek[K comparable] struct {
+	UserID    string
+	Key       K
+	Rating    float64
+	CreatedAt time.Time
+	// Avoid is a player the seek would rather not be paired with, such as
+	// the opponent just played.
+	Avoid string
+
+	lastPosition int
+}
+
+// Match pairs two seeks. First is the one that was waiting longer.
+type Match[K comparable] struct {
+	First  *Seek[K]
+	Second *Seek[K]
+}
+
+// Status is a seek's place in its queue.
+type Status struct {
+	UserID        string
+	Position      int
+	EstimatedWait time.Duration
+}
+
+type Pool[K comparable] struct {
+	cfg Config
+
+	queues map[K][]*Seek[K]
+	byUser map[string]*Seek[K]
+
+	// avgWait is a moving average of how long matched seeks waited, per key.
+	avgWait map[K]time.Duration
+
+	mu sync.Mutex
+}
+
+func NewPool[K comparable](cfg Config) *Pool[K] {
+	return &Pool[K]{
+		cfg:     cfg,
+		queues:  make(map[K][]*Seek[K]),
+		byUser:  make(map[string]*Seek[K]),
+		avgWait: make(map[K]time.Duration),
+	}
+}
+
+// Add pairs seek with the longest waiting compatible seek as of now, or
+// queues it if there is none. A seek that goes back into the pool keeps its
+// CreatedAt, so its rating window stays as wide as its wait.
+func (p *Pool[K]) Add(seek *Seek[K], now time.Time) (*Match[K], error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.byUser[seek.UserID]; exists {
+		return nil, ErrAlreadySeeking
+	}
+
+	for _, other := range p.queues[seek.Key] {
+		if p.compatible(seek, other, now) {
+			p.remove(other)
+			p.recordWait(other, now)
+			return &Match[K]{First: other, Second: seek}, nil
+		}
+	}
+
+	p.queues[seek.Key] = append(p.queues[seek.Key], seek)
+	p.byUser[seek.UserID] = seek
+	return nil, nil
+}
+
+// Cancel removes the user's seek and reports whether there was one.
+func (p *Pool[K]) Cancel(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seek, exists := p.byUser[userID]
+	if !exists {
+		return false
+	}
+	p.remove(seek)
+	return true
+}
+
+func (p *Pool[K]) Contains(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, exists := p.byUser[userID]
+	return exists
+}
+
+// Sweep pairs seeks whose rating windows have widened enough to overlap
+// since they were queued.
+func (p *Pool[K]) Sweep(now time.Time) []Match[K] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var matches []Match[K]
+	for key, queue := range p.queues {
+		for i := 0; i < len(queue); i++ {
+			for j := i + 1; j < len(queue); j++ {
+				if !p.compatible(queue[i], queue[j], now) {
+					continue
+				}
+				first, second := queue[i], queue[j]
+				p.remove(first)
+				p.remove(second)
+				p.recordWait(first, now)
+				p.recordWait(second, now)
+				matches = append(matches, Match[K]{First: first, Second: second})
+
+				queue = p.queues[key]
+				i--
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// Status returns the user's current position and estimated wait.
+func (p *Pool[K]) Status(userID string) (Status, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seek, exists := p.byUser[userID]
+	if !exists {
+		return Status{}, false
+	}
+	return p.status(seek), true
+}
+
+// Changed returns the status of every seek whose queue position moved since
+// it was last reported.
+func (p *Pool[K]) Changed() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var changed []Status
+	for _, seek := range p.byUser {
+		st := p.status(seek)
+		if st.Position != seek.lastPosition {
+			seek.lastPosition = st.Position
+			changed = append(changed, st)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].UserID < changed[j].UserID })
+	return changed
+}
+
+func (p *Pool[K]) status(seek *Seek[K]) Status {
+	position := 0
+	for i, s := range p.queues[seek.Key] {
+		if s == seek {
+			position = i + 1
+			break
+		}
+	}
+
+	wait := p.avgWait[seek.Key] - time.Since(seek.CreatedAt)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return Status{UserID: seek.UserID, Position: position, EstimatedWait: wait}
+}
+
+func (p *Pool[K]) window(seek *Seek[K], now time.Time) float64 {
+	waited := now.Sub(seek.CreatedAt).Seconds()
+	if waited < 0 {
+		waited = 0
+	}
+	return math.Min(p.cfg.InitialWindow+p.cfg.WindowGrowth*waited, p.cfg.MaxWindow)
+}
+
+// compatible reports whether both seeks accept each other's rating and
+// neither is still avoiding the other.
+func (p *Pool[K]) compatible(a, b *Seek[K], now time.Time) bool {
+	if a.UserID == b.UserID {
+		return false
+	}
+	if (a.Avoid == b.UserID || b.Avoid == a.UserID) &&
+		now.Sub(a.CreatedAt) < p.cfg.AvoidFor && now.Sub(b.CreatedAt) < p.cfg.AvoidFor {
+		return false
+	}
+	diff := math.Abs(a.Rating - b.Rating)
+	return diff <= p.window(a, now) && diff <= p.window(b, now)
+}
+
+func (p *Pool[K]) remove(seek *Seek[K]) {
+	delete(p.byUser, seek.UserID)
+
+	queue := p.queues[seek.Key]
+	for i, s := range queue {
+		if s == seek {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) == 0 {
+		delete(p.queues, seek.Key)
+	} else {
+		p.queues[seek.Key] = queue
+	}
+}
+
+func (p *Pool[K]) recordWait(seek *Seek[K], now time.Time) {
+	waited := now.Sub(seek.CreatedAt)
+	if prev, ok := p.avgWait[seek.Key]; ok {
+		waited = (prev*3 + waited) / 4
+	}
+	p.avgWait[seek.Key] = waited
+}
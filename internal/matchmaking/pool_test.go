@@ -0,0 +1,185 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	InitialWindow: 100,
+	WindowGrowth:  10,
+	MaxWindow:     300,
+	AvoidFor:      20 * time.Second,
+}
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func seek(userID string, rating float64, waited time.Duration) *Seek[string] {
+	return &Seek[string]{UserID: userID, Key: "3+2", Rating: rating, CreatedAt: start.Add(-waited)}
+}
+
+func TestCompatible(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *Seek[string]
+		want bool
+	}{
+		{
+			name: "within the initial window",
+			a:    seek("a", 1500, 0),
+			b:    seek("b", 1600, 0),
+			want: true,
+		},
+		{
+			name: "outside the initial window",
+			a:    seek("a", 1500, 0),
+			b:    seek("b", 1601, 0),
+		},
+		{
+			name: "both windows widened",
+			a:    seek("a", 1500, 10*time.Second),
+			b:    seek("b", 1700, 10*time.Second),
+			want: true,
+		},
+		{
+			name: "only one window widened",
+			a:    seek("a", 1500, 10*time.Second),
+			b:    seek("b", 1700, 0),
+		},
+		{
+			name: "windows stop at the maximum",
+			a:    seek("a", 1500, time.Hour),
+			b:    seek("b", 1801, time.Hour),
+		},
+		{
+			name: "the same user",
+			a:    seek("a", 1500, 0),
+			b:    seek("a", 1500, 0),
+		},
+		{
+			name: "avoided while both are new",
+			a:    &Seek[string]{UserID: "a", Rating: 1500, CreatedAt: start, Avoid: "b"},
+			b:    seek("b", 1500, 0),
+		},
+		{
+			name: "avoided no longer once one has waited",
+			a:    &Seek[string]{UserID: "a", Rating: 1500, CreatedAt: start, Avoid: "b"},
+			b:    seek("b", 1500, 20*time.Second),
+			want: true,
+		},
+	}
+
+	p := NewPool[string](testConfig)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.compatible(tt.a, tt.b, start); got != tt.want {
+				t.Errorf("compatible = %v, want %v", got, tt.want)
+			}
+			if got := p.compatible(tt.b, tt.a, start); got != tt.want {
+				t.Errorf("compatible the other way round = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	p := NewPool[string](testConfig)
+
+	for _, s := range []*Seek[string]{seek("a", 1500, 0), seek("b", 1700, 0)} {
+		if m, err := p.Add(s, start); m != nil || err != nil {
+			t.Fatalf("Add(%s) = %+v, %v, want it queued", s.UserID, m, err)
+		}
+	}
+	if _, err := p.Add(seek("a", 1500, 0), start); err != ErrAlreadySeeking {
+		t.Errorf("second seek of a: %v, want %v", err, ErrAlreadySeeking)
+	}
+
+	other := &Seek[string]{UserID: "c", Key: "10+0", Rating: 1500, CreatedAt: start}
+	if m, _ := p.Add(other, start); m != nil {
+		t.Errorf("seek for another key matched %s", m.First.UserID)
+	}
+
+	m, err := p.Add(seek("d", 1520, 0), start)
+	if err != nil || m == nil {
+		t.Fatalf("Add(d) = %+v, %v, want a match", m, err)
+	}
+	if m.First.UserID != "a" || m.Second.UserID != "d" {
+		t.Errorf("matched %s with %s, want the longest waiting seek a with d", m.First.UserID, m.Second.UserID)
+	}
+	if p.Contains("a") || p.Contains("d") || !p.Contains("b") {
+		t.Error("matched seeks are still queued, or the other one is not")
+	}
+	if st, ok := p.Status("b"); !ok || st.Position != 1 {
+		t.Errorf("status of b = %+v, %v, want first in its queue", st, ok)
+	}
+	if !p.Cancel("b") || p.Cancel("b") || p.Contains("b") {
+		t.Error("cancelling b twice did not remove it exactly once")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	tests := []struct {
+		name  string
+		seeks []*Seek[string]
+		after time.Duration
+		// want are the user IDs of the matches, first seek first.
+		want [][2]string
+	}{
+		{
+			name:  "windows not wide enough yet",
+			seeks: []*Seek[string]{seek("a", 1500, 0), seek("b", 1650, 0)},
+			after: 4 * time.Second,
+		},
+		{
+			name:  "windows widened",
+			seeks: []*Seek[string]{seek("a", 1500, 0), seek("b", 1650, 0)},
+			after: 5 * time.Second,
+			want:  [][2]string{{"a", "b"}},
+		},
+		{
+			name: "every compatible pair, oldest first",
+			seeks: []*Seek[string]{
+				seek("a", 1500, 0),
+				seek("b", 2000, 0),
+				seek("c", 1650, 0),
+				seek("d", 2150, 0),
+			},
+			after: 5 * time.Second,
+			want:  [][2]string{{"a", "c"}, {"b", "d"}},
+		},
+		{
+			name: "an odd seek left over",
+			seeks: []*Seek[string]{
+				seek("a", 1500, 0),
+				seek("b", 1600, 0),
+				seek("c", 1700, 0),
+			},
+			after: 10 * time.Second,
+			want:  [][2]string{{"a", "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool[string](testConfig)
+			// Queue directly: Add would pair the seeks straight away.
+			for _, s := range tt.seeks {
+				p.queues[s.Key] = append(p.queues[s.Key], s)
+				p.byUser[s.UserID] = s
+			}
+
+			matches := p.Sweep(start.Add(tt.after))
+			if len(matches) != len(tt.want) {
+				t.Fatalf("got %d matches, want %d", len(matches), len(tt.want))
+			}
+			for i, m := range matches {
+				if got := [2]string{m.First.UserID, m.Second.UserID}; got != tt.want[i] {
+					t.Errorf("match %d is %v, want %v", i, got, tt.want[i])
+				}
+				if p.Contains(m.First.UserID) || p.Contains(m.Second.UserID) {
+					t.Errorf("match %d is still queued", i)
+				}
+			}
+		})
+	}
+}
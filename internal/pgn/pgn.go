@@ -0,0 +1,83 @@
+package pgn
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Adi-ty/chess/internal/variant"
+)
+
+const lineWidth = 80
+
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Encode writes a game in PGN export format: the tag pairs in the order
+// given, a blank line and the SAN movetext terminated by result.
+func Encode(tags []Tag, board *variant.Board, result string) string {
+	var b strings.Builder
+	for _, tag := range tags {
+		b.WriteString("[" + tag.Key + " \"" + escape(tag.Value) + "\"]\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(Movetext(board, result))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// Movetext renders the moves of board in SAN followed by result, wrapped at
+// 80 columns. Move numbers follow the starting position, so games set up
+// from a FEN are numbered correctly.
+func Movetext(board *variant.Board, result string) string {
+	fields := strings.Fields(board.StartFEN())
+	moveNumber := fullMoveNumber(fields)
+	whiteToMove := fields[1] == "w"
+
+	var tokens []string
+	for i, san := range board.SAN() {
+		if whiteToMove {
+			tokens = append(tokens, strconv.Itoa(moveNumber)+".")
+		} else if i == 0 {
+			tokens = append(tokens, strconv.Itoa(moveNumber)+"...")
+		}
+		tokens = append(tokens, san)
+		if !whiteToMove {
+			moveNumber++
+		}
+		whiteToMove = !whiteToMove
+	}
+	tokens = append(tokens, result)
+
+	var b strings.Builder
+	lineLen := 0
+	for i, token := range tokens {
+		if i > 0 {
+			if lineLen+1+len(token) > lineWidth {
+				b.WriteString("\n")
+				lineLen = 0
+			} else {
+				b.WriteString(" ")
+				lineLen++
+			}
+		}
+		b.WriteString(token)
+		lineLen += len(token)
+	}
+	return b.String()
+}
+
+func fullMoveNumber(fields []string) int {
+	if len(fields) == 6 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+func escape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
@@ -0,0 +1,99 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Adi-ty/chess/internal/variant"
+)
+
+func replay(t *testing.T, v variant.Variant, fen string, moves ...string) *variant.Board {
+	t.Helper()
+	b, err := variant.Replay(v, fen, moves)
+	if err != nil {
+		t.Fatalf("replaying %v: %v", moves, err)
+	}
+	return b
+}
+
+func TestMovetext(t *testing.T) {
+	tests := []struct {
+		name   string
+		board  *variant.Board
+		result string
+		want   string
+	}{
+		{
+			name:   "no moves",
+			board:  replay(t, variant.Standard, ""),
+			result: "*",
+			want:   "*",
+		},
+		{
+			name:   "numbered by full moves",
+			board:  replay(t, variant.Standard, "", "e2e4", "e7e5", "g1f3"),
+			result: "*",
+			want:   "1. e4 e5 2. Nf3 *",
+		},
+		{
+			name:   "checks and mate",
+			board:  replay(t, variant.Standard, "", "f2f3", "e7e5", "g2g4", "d8h4"),
+			result: "0-1",
+			want:   "1. f3 e5 2. g4 Qh4# 0-1",
+		},
+		{
+			name:   "from a position with Black to move",
+			board:  replay(t, variant.FromPosition, "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12", "e8d7", "e2e4"),
+			result: "1/2-1/2",
+			want:   "12... Kd7 13. e4 1/2-1/2",
+		},
+		{
+			name:   "from a position with White to move",
+			board:  replay(t, variant.FromPosition, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 30", "e2e4"),
+			result: "*",
+			want:   "30. e4 *",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Movetext(tt.board, tt.result); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMovetextWraps(t *testing.T) {
+	var moves []string
+	for i := 0; i < 10; i++ {
+		moves = append(moves, "g1f3", "g8f6", "f3g1", "f6g8")
+	}
+	b := replay(t, variant.Standard, "", moves[:38]...)
+
+	text := Movetext(b, "*")
+	lines := strings.Split(text, "\n")
+	if len(lines) < 2 {
+		t.Fatalf("not wrapped: %q", text)
+	}
+	for _, line := range lines {
+		if len(line) > lineWidth {
+			t.Errorf("line of %d characters: %q", len(line), line)
+		}
+		if strings.HasPrefix(line, " ") || strings.HasSuffix(line, " ") {
+			t.Errorf("line with stray space: %q", line)
+		}
+	}
+	if got := strings.Join(strings.Fields(text), " "); !strings.HasPrefix(got, "1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3") || !strings.HasSuffix(got, "19. Nf3 Nf6 *") {
+		t.Errorf("movetext %q", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	b := replay(t, variant.Standard, "", "e2e4")
+	got := Encode([]Tag{{"Event", `Say "hi" \ bye`}, {"Result", "*"}}, b, "*")
+	want := "[Event \"Say \\\"hi\\\" \\\\ bye\"]\n[Result \"*\"]\n\n1. e4 *\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package puzzle
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxIDLength is the longest puzzle ID the store takes.
+const maxIDLength = 20
+
+// Record is a puzzle as read from a CSV file.
+type Record struct {
+	ID       string
+	FEN      string
+	Solution []string
+	Rating   float64
+	// Deviation is 0 when the file has none.
+	Deviation float64
+	Themes    []string
+}
+
+// columns are the header names a CSV file is read by, lowercased. The
+// Lichess puzzle database's names are accepted as well.
+var columns = map[string][]string{
+	"id":        {"id", "puzzleid"},
+	"fen":       {"fen"},
+	"moves":     {"moves", "solution"},
+	"rating":    {"rating"},
+	"deviation": {"deviation", "ratingdeviation"},
+	"themes":    {"themes"},
+}
+
+// ReadCSV calls fn with every puzzle of a CSV file whose header names an
+// id, fen, moves, rating and themes column, and optionally a deviation one.
+// Moves and themes are separated by spaces. A row that cannot be read is
+// passed to skip with its line and the reason, and reading goes on.
+func ReadCSV(r io.Reader, fn func(Record) error, skip func(line int, err error)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, names := range columns {
+			for _, n := range names {
+				if n == name {
+					index[column] = i
+				}
+			}
+		}
+	}
+	for _, column := range []string{"id", "fen", "moves", "rating", "themes"} {
+		if _, ok := index[column]; !ok {
+			return fmt.Errorf("no %s column", column)
+		}
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				skip(parseErr.Line, err)
+				continue
+			}
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		rec, err := parseRecord(row, index)
+		if err != nil {
+			skip(line, err)
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func parseRecord(row []string, index map[string]int) (Record, error) {
+	field := func(column string) string {
+		i, ok := index[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	rec := Record{
+		ID:       field("id"),
+		FEN:      field("fen"),
+		Solution: strings.Fields(strings.ToLower(field("moves"))),
+		Themes:   strings.Fields(field("themes")),
+	}
+	if rec.ID == "" || len(rec.ID) > maxIDLength {
+		return Record{}, fmt.Errorf("id must be 1 to %d characters", maxIDLength)
+	}
+	rating, err := strconv.ParseFloat(field("rating"), 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("invalid rating %q", field("rating"))
+	}
+	rec.Rating = rating
+	if d := field("deviation"); d != "" {
+		if rec.Deviation, err = strconv.ParseFloat(d, 64); err != nil {
+			return Record{}, fmt.Errorf("invalid deviation %q", d)
+		}
+	}
+	return rec, nil
+}
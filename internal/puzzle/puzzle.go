@@ -0,0 +1,120 @@
+// Package puzzle checks puzzle solutions and reads puzzles to import.
+//
+// A puzzle is a position and its solution: a line of moves in UCI notation
+// that starts and ends with the solver's moves, the opponent's replies in
+// between.
+package puzzle
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+var (
+	ErrInvalidFEN      = errors.New("invalid FEN")
+	ErrIllegalMove     = errors.New("illegal move")
+	ErrInvalidSolution = errors.New("a solution needs an odd number of moves")
+	ErrNoMoves         = errors.New("no moves played")
+	ErrTooManyMoves    = errors.New("more moves played than the solution has")
+)
+
+// Verdict is how far the moves played solve a puzzle.
+type Verdict struct {
+	// Correct is whether every move played was right. Solved is whether
+	// they make the whole solution.
+	Correct bool
+	Solved  bool
+	// Reply is the opponent's answer to the last move while the solution
+	// goes on.
+	Reply string
+}
+
+// Validate checks that the solution is a line of legal moves from fen
+// ending with one of the solver's.
+func Validate(fen string, solution []string) error {
+	if len(solution)%2 == 0 {
+		return ErrInvalidSolution
+	}
+	game, err := newGame(fen)
+	if err != nil {
+		return err
+	}
+	for _, move := range solution {
+		if err := play(game, move); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check compares the solver's moves played so far, without the opponent's
+// replies, with the solution. A move other than the solution's is still
+// right if it mates: mate in one has no single answer.
+func Check(fen string, solution, played []string) (Verdict, error) {
+	if len(played) == 0 {
+		return Verdict{}, ErrNoMoves
+	}
+	if 2*len(played)-1 > len(solution) {
+		return Verdict{}, ErrTooManyMoves
+	}
+	game, err := newGame(fen)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	for i, move := range played {
+		move = strings.ToLower(move)
+		if err := play(game, move); err != nil {
+			return Verdict{}, err
+		}
+		if game.Method() == chess.Checkmate {
+			return Verdict{Correct: true, Solved: true}, nil
+		}
+		if move != solution[2*i] {
+			return Verdict{}, nil
+		}
+		if 2*i+1 == len(solution) {
+			return Verdict{Correct: true, Solved: true}, nil
+		}
+		if err := play(game, solution[2*i+1]); err != nil {
+			return Verdict{}, err
+		}
+	}
+	return Verdict{Correct: true, Reply: solution[2*len(played)-1]}, nil
+}
+
+// Advance plays move from fen and returns the position after it.
+func Advance(fen, move string) (string, error) {
+	game, err := newGame(fen)
+	if err != nil {
+		return "", err
+	}
+	if err := play(game, move); err != nil {
+		return "", err
+	}
+	return game.Position().String(), nil
+}
+
+func newGame(fen string) (*chess.Game, error) {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, ErrInvalidFEN
+	}
+	return chess.NewGame(opt), nil
+}
+
+func play(game *chess.Game, move string) error {
+	if game.Outcome() != chess.NoOutcome {
+		return ErrIllegalMove
+	}
+	mv, err := chess.UCINotation{}.Decode(game.Position(), move)
+	if err != nil {
+		return ErrIllegalMove
+	}
+	if err := game.Move(mv); err != nil {
+		return ErrIllegalMove
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AnalysisPayload asks the analysis worker to review a finished game. It
+// carries the moves itself so the job does not race the moves queue.
+type AnalysisPayload struct {
+	GameID  string   `json:"game_id"`
+	Variant string   `json:"variant"`
+	FEN     string   `json:"fen"`
+	Moves   []string `json:"moves"`
+}
+
+func EnqueueAnalysis(redisClient *redis.Client, payload AnalysisPayload) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return redisClient.LPush(context.Background(), "analysis_queue", jsonData).Err()
+}
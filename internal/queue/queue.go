@@ -12,7 +12,14 @@ type MovePayload struct {
 	UserID string `json:"user_id"`
 	MoveNumber int    `json:"move_number"`
 	Move     string `json:"move"`
+	WhiteTimeMs int64 `json:"white_time_ms"`
+	BlackTimeMs int64 `json:"black_time_ms"`
 	CreatedAt float64 `json:"created_at"`
+
+	// Truncate marks a takeback: rather than inserting a move, every move of
+	// the game after MoveNumber is deleted. It shares the moves queue so it
+	// can never overtake an insert that is still pending.
+	Truncate bool `json:"truncate,omitempty"`
 }
 
 func EnqueueMove(redisClient *redis.Client, payload MovePayload) error {
@@ -22,4 +29,12 @@ func EnqueueMove(redisClient *redis.Client, payload MovePayload) error {
 	}
 
 	return redisClient.LPush(context.Background(), "moves_queue", jsonData).Err()
+}
+
+func EnqueueTakeback(redisClient *redis.Client, gameID string, moveNumber int) error {
+	return EnqueueMove(redisClient, MovePayload{
+		GameID:     gameID,
+		MoveNumber: moveNumber,
+		Truncate:   true,
+	})
 }
\ No newline at end of file
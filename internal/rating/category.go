@@ -0,0 +1,53 @@
+package rating
+
+import (
+	"time"
+)
+
+type Category string
+
+const (
+	Bullet         Category = "bullet"
+	Blitz          Category = "blitz"
+	Rapid          Category = "rapid"
+	Classical      Category = "classical"
+	Correspondence Category = "correspondence"
+
+	// The rule variants are rated in pools of their own whatever the time
+	// control.
+	KingOfTheHill Category = "king_of_the_hill"
+	ThreeCheck    Category = "three_check"
+	RacingKings   Category = "racing_kings"
+	Horde         Category = "horde"
+)
+
+// Puzzle is the rating earned solving puzzles. It is kept apart from the
+// game categories and has no leaderboard.
+const Puzzle Category = "puzzle"
+
+var Categories = []Category{Bullet, Blitz, Rapid, Classical, Correspondence, KingOfTheHill, ThreeCheck, RacingKings, Horde}
+
+// CategoryFor buckets a time control by its estimated game duration, base
+// time plus 40 increments.
+func CategoryFor(base, increment time.Duration) Category {
+	estimate := base + 40*increment
+	switch {
+	case estimate < 3*time.Minute:
+		return Bullet
+	case estimate < 8*time.Minute:
+		return Blitz
+	case estimate < 25*time.Minute:
+		return Rapid
+	default:
+		return Classical
+	}
+}
+
+func ParseCategory(s string) (Category, bool) {
+	for _, c := range Categories {
+		if string(c) == s {
+			return c, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,136 @@
+package rating
+
+import (
+	"math"
+)
+
+// Glicko-2 as described in Glickman's "Example of the Glicko-2 system".
+// Every game is treated as its own rating period.
+
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// MinDeviation keeps very active players' ratings from freezing.
+	MinDeviation = 45.0
+	MaxDeviation = 350.0
+
+	// ProvisionalDeviation is the deviation above which a rating is shown as
+	// provisional.
+	ProvisionalDeviation = 110.0
+
+	scale   = 173.7178
+	tau     = 0.5
+	epsilon = 0.000001
+)
+
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Result is the score obtained against one opponent: 1 for a win, 0.5 for a
+// draw and 0 for a loss.
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+func Default() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+func (r Rating) Provisional() bool {
+	return r.Deviation > ProvisionalDeviation
+}
+
+// Update returns the player's rating after the given results.
+func Update(player Rating, results ...Result) Rating {
+	mu := (player.Rating - DefaultRating) / scale
+	phi := player.Deviation / scale
+	sigma := player.Volatility
+
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + sigma*sigma)
+		return player.withDeviation(phi * scale)
+	}
+
+	var vInv, deltaSum float64
+	for _, res := range results {
+		muJ := (res.Opponent.Rating - DefaultRating) / scale
+		phiJ := res.Opponent.Deviation / scale
+		gJ := g(phiJ)
+		e := expected(mu, muJ, gJ)
+		vInv += gJ * gJ * e * (1 - e)
+		deltaSum += gJ * (res.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	sigma = newVolatility(phi, sigma, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * deltaSum
+
+	return Rating{
+		Rating:     mu*scale + DefaultRating,
+		Volatility: sigma,
+	}.withDeviation(phi * scale)
+}
+
+func (r Rating) withDeviation(d float64) Rating {
+	r.Deviation = math.Max(MinDeviation, math.Min(MaxDeviation, d))
+	return r
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muJ, gJ float64) float64 {
+	return 1 / (1 + math.Exp(-gJ*(mu-muJ)))
+}
+
+// newVolatility solves for the new volatility with the Illinois algorithm
+// (step 5 of the paper).
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,142 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+// TestUpdateGlickmanExample checks the worked example of Glickman's
+// "Example of the Glicko-2 system".
+func TestUpdateGlickmanExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	got := Update(player,
+		Result{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		Result{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		Result{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	)
+
+	if !near(got.Rating, 1464.06, 0.01) {
+		t.Errorf("rating %.4f, want 1464.06", got.Rating)
+	}
+	if !near(got.Deviation, 151.52, 0.01) {
+		t.Errorf("deviation %.4f, want 151.52", got.Deviation)
+	}
+	if !near(got.Volatility, 0.05999, 0.00001) {
+		t.Errorf("volatility %.6f, want 0.05999", got.Volatility)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		player  Rating
+		results []Result
+		check   func(t *testing.T, before, after Rating)
+	}{
+		{
+			name:   "no games widens the deviation",
+			player: Rating{Rating: 1500, Deviation: 200, Volatility: 0.06},
+			check: func(t *testing.T, before, after Rating) {
+				want := math.Sqrt(200*200 + 0.06*0.06*scale*scale)
+				if after.Rating != before.Rating || !near(after.Deviation, want, 0.0001) || after.Volatility != before.Volatility {
+					t.Errorf("got %+v, want rating and volatility kept and deviation %.4f", after, want)
+				}
+			},
+		},
+		{
+			name:   "no games caps the deviation",
+			player: Default(),
+			check: func(t *testing.T, before, after Rating) {
+				if after.Deviation != MaxDeviation {
+					t.Errorf("deviation %.4f, want %v", after.Deviation, MaxDeviation)
+				}
+			},
+		},
+		{
+			name:    "a win gains",
+			player:  Default(),
+			results: []Result{{Opponent: Default(), Score: 1}},
+			check: func(t *testing.T, before, after Rating) {
+				if after.Rating <= before.Rating || after.Deviation >= before.Deviation {
+					t.Errorf("got %+v after a win from %+v", after, before)
+				}
+			},
+		},
+		{
+			name:    "a draw between equals changes nothing but the deviation",
+			player:  Default(),
+			results: []Result{{Opponent: Default(), Score: 0.5}},
+			check: func(t *testing.T, before, after Rating) {
+				if !near(after.Rating, before.Rating, 0.0001) || after.Deviation >= before.Deviation {
+					t.Errorf("got %+v after a draw from %+v", after, before)
+				}
+			},
+		},
+		{
+			name:    "the deviation does not fall below the floor",
+			player:  Rating{Rating: 2000, Deviation: 30, Volatility: 0.06},
+			results: []Result{{Opponent: Rating{Rating: 2000, Deviation: 30, Volatility: 0.06}, Score: 1}},
+			check: func(t *testing.T, before, after Rating) {
+				if after.Deviation != MinDeviation {
+					t.Errorf("deviation %.4f, want %v", after.Deviation, MinDeviation)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, tt.player, Update(tt.player, tt.results...))
+		})
+	}
+}
+
+func TestProvisional(t *testing.T) {
+	if !Default().Provisional() {
+		t.Error("a new rating is not provisional")
+	}
+	if (Rating{Rating: 1500, Deviation: ProvisionalDeviation}).Provisional() {
+		t.Errorf("a deviation of %v is provisional", ProvisionalDeviation)
+	}
+}
+
+func TestCategoryFor(t *testing.T) {
+	tests := []struct {
+		base, increment time.Duration
+		want            Category
+	}{
+		{time.Minute, 0, Bullet},
+		{2 * time.Minute, time.Second, Bullet},
+		{3 * time.Minute, 0, Blitz},
+		{3 * time.Minute, 2 * time.Second, Blitz},
+		{5 * time.Minute, 3 * time.Second, Blitz},
+		{5 * time.Minute, 5 * time.Second, Rapid},
+		{15 * time.Minute, 10 * time.Second, Rapid},
+		{20 * time.Minute, 10 * time.Second, Classical},
+		{30 * time.Minute, 0, Classical},
+	}
+
+	for _, tt := range tests {
+		if got := CategoryFor(tt.base, tt.increment); got != tt.want {
+			t.Errorf("CategoryFor(%s, %s) = %s, want %s", tt.base, tt.increment, got, tt.want)
+		}
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	for _, c := range Categories {
+		if got, ok := ParseCategory(string(c)); !ok || got != c {
+			t.Errorf("ParseCategory(%q) = %q, %v", c, got, ok)
+		}
+	}
+	for _, s := range []string{"", "Blitz", "puzzle", "ultrabullet"} {
+		if got, ok := ParseCategory(s); ok {
+			t.Errorf("ParseCategory(%q) = %q, want no category", s, got)
+		}
+	}
+}
@@ -10,6 +10,7 @@ func SetUpRoutes(app *app.Application) *http.ServeMux {
 	router := http.NewServeMux()
 
 	router.HandleFunc("/ws", app.WebSocketHandler.WsHandler)
+	router.HandleFunc("/ws/spectate", app.WebSocketHandler.SpectateHandler)
 	router.HandleFunc("GET /auth/google", app.AuthHandler.HandleGoogleLogin)
 	router.HandleFunc("GET /auth/google/callback", app.AuthHandler.HandleGoogleCallback)
 	router.HandleFunc("POST /auth/logout", app.AuthHandler.HandleLogout)
@@ -18,5 +19,56 @@ func SetUpRoutes(app *app.Application) *http.ServeMux {
 		http.HandlerFunc(app.AuthHandler.HandleMe),
 	))
 
+	router.Handle("POST /challenges", app.JWTService.Middleware(
+		http.HandlerFunc(app.ChallengeHandler.HandleCreateChallenge),
+	))
+	router.Handle("GET /challenges/{id}", app.JWTService.OptionalMiddleware(
+		http.HandlerFunc(app.ChallengeHandler.HandleGetChallenge),
+	))
+	router.Handle("POST /challenges/{id}/accept", app.JWTService.Middleware(
+		http.HandlerFunc(app.ChallengeHandler.HandleAcceptChallenge),
+	))
+	router.Handle("POST /challenges/{id}/decline", app.JWTService.Middleware(
+		http.HandlerFunc(app.ChallengeHandler.HandleDeclineChallenge),
+	))
+
+	router.HandleFunc("GET /games/{id}", app.GameHandler.HandleGetGame)
+	router.HandleFunc("GET /games/{id}/analysis", app.GameHandler.HandleGetAnalysis)
+	router.Handle("GET /users/me/games/active", app.JWTService.Middleware(
+		http.HandlerFunc(app.GameHandler.HandleListActiveGames),
+	))
+	router.HandleFunc("GET /users/{id}", app.UserHandler.HandleGetUser)
+	router.HandleFunc("GET /users/{id}/games", app.GameHandler.HandleListGames)
+	router.HandleFunc("GET /users/{id}/games.pgn", app.GameHandler.HandleExportPGN)
+
+	router.HandleFunc("GET /tournaments", app.TournamentHandler.HandleListTournaments)
+	router.Handle("POST /tournaments", app.JWTService.Middleware(
+		http.HandlerFunc(app.TournamentHandler.HandleCreateTournament),
+	))
+	router.HandleFunc("GET /tournaments/{id}", app.TournamentHandler.HandleGetTournament)
+	router.HandleFunc("GET /tournaments/{id}/crosstable", app.TournamentHandler.HandleGetCrosstable)
+	router.HandleFunc("GET /tournaments/{id}/bracket", app.TournamentHandler.HandleGetBracket)
+	router.HandleFunc("GET /tournaments/{id}/games.pgn", app.TournamentHandler.HandleExportPGN)
+	router.Handle("POST /tournaments/{id}/join", app.JWTService.Middleware(
+		http.HandlerFunc(app.TournamentHandler.HandleJoinTournament),
+	))
+	router.Handle("POST /tournaments/{id}/withdraw", app.JWTService.Middleware(
+		http.HandlerFunc(app.TournamentHandler.HandleWithdrawFromTournament),
+	))
+	router.Handle("POST /tournaments/{id}/start", app.JWTService.Middleware(
+		http.HandlerFunc(app.TournamentHandler.HandleStartTournament),
+	))
+
+	router.Handle("GET /puzzles/next", app.JWTService.Middleware(
+		http.HandlerFunc(app.PuzzleHandler.HandleNextPuzzle),
+	))
+	router.Handle("POST /puzzles/{id}/solve", app.JWTService.Middleware(
+		http.HandlerFunc(app.PuzzleHandler.HandleSolvePuzzle),
+	))
+
+	router.Handle("GET /leaderboard/{category}", app.JWTService.OptionalMiddleware(
+		http.HandlerFunc(app.LeaderboardHandler.HandleGetLeaderboard),
+	))
+
 	return router
 }
\ No newline at end of file
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	AnalysisPending = "pending"
+	AnalysisDone    = "done"
+	AnalysisFailed  = "failed"
+)
+
+// GameAnalysis is an engine review of a finished game.
+type GameAnalysis struct {
+	GameID      string         `json:"game_id"`
+	Status      string         `json:"status"`
+	White       PlayerAnalysis `json:"white"`
+	Black       PlayerAnalysis `json:"black"`
+	Moves       []MoveAnalysis `json:"moves"`
+	RequestedAt time.Time      `json:"requested_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+// PlayerAnalysis sums up one side's play. The counts are derived from the
+// moves when the analysis is loaded.
+type PlayerAnalysis struct {
+	ACPL         int     `json:"acpl"`
+	Accuracy     float64 `json:"accuracy"`
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+}
+
+// MoveAnalysis grades one move. EvalCP and EvalMate describe the position
+// after the move from White's point of view; EvalMate is non-zero when there
+// is a forced mate, negative when Black mates.
+type MoveAnalysis struct {
+	Ply            int    `json:"ply"`
+	Color          string `json:"color"`
+	Move           string `json:"move"`
+	EvalCP         int    `json:"eval_cp"`
+	EvalMate       int    `json:"eval_mate,omitempty"`
+	BestMove       string `json:"best_move"`
+	CPLoss         int    `json:"cp_loss"`
+	Classification string `json:"classification"`
+}
+
+type AnalysisStore interface {
+	SetAnalysisStatus(ctx context.Context, gameID string, status string) error
+	SaveGameAnalysis(ctx context.Context, analysis *GameAnalysis) error
+	GetGameAnalysis(ctx context.Context, gameID string) (*GameAnalysis, error)
+}
+
+type PostgresAnalysisStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAnalysisStore(db *sql.DB) *PostgresAnalysisStore {
+	return &PostgresAnalysisStore{db: db}
+}
+
+// SetAnalysisStatus records that an analysis was requested or has failed.
+// Marking a game pending again restarts its analysis from scratch.
+func (s *PostgresAnalysisStore) SetAnalysisStatus(ctx context.Context, gameID string, status string) error {
+	query := `
+		INSERT INTO game_analysis (game_id, status)
+		VALUES ($1, $2)
+		ON CONFLICT (game_id) DO UPDATE
+		SET status = EXCLUDED.status,
+			requested_at = CASE WHEN EXCLUDED.status = 'pending' THEN NOW() ELSE game_analysis.requested_at END,
+			completed_at = NULL
+	`
+	_, err := s.db.ExecContext(ctx, query, gameID, status)
+	return err
+}
+
+// SaveGameAnalysis stores a completed analysis, replacing any earlier one.
+func (s *PostgresAnalysisStore) SaveGameAnalysis(ctx context.Context, analysis *GameAnalysis) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO game_analysis (game_id, status, white_acpl, black_acpl, white_accuracy, black_accuracy, completed_at)
+		VALUES ($1, 'done', $2, $3, $4, $5, NOW())
+		ON CONFLICT (game_id) DO UPDATE
+		SET status = 'done',
+			white_acpl = EXCLUDED.white_acpl,
+			black_acpl = EXCLUDED.black_acpl,
+			white_accuracy = EXCLUDED.white_accuracy,
+			black_accuracy = EXCLUDED.black_accuracy,
+			completed_at = EXCLUDED.completed_at
+	`
+	_, err = tx.ExecContext(ctx, query, analysis.GameID,
+		analysis.White.ACPL, analysis.Black.ACPL, analysis.White.Accuracy, analysis.Black.Accuracy)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM move_analysis WHERE game_id = $1`, analysis.GameID); err != nil {
+		return err
+	}
+	for _, m := range analysis.Moves {
+		query := `
+			INSERT INTO move_analysis (game_id, ply, color, move, eval_cp, eval_mate, best_move, cp_loss, classification)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`
+		_, err := tx.ExecContext(ctx, query, analysis.GameID, m.Ply, m.Color, m.Move, m.EvalCP, m.EvalMate, m.BestMove, m.CPLoss, m.Classification)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetGameAnalysis returns the game's analysis, or nil if none was requested.
+// Moves are only filled in once the analysis is done.
+func (s *PostgresAnalysisStore) GetGameAnalysis(ctx context.Context, gameID string) (*GameAnalysis, error) {
+	a := &GameAnalysis{GameID: gameID, Moves: []MoveAnalysis{}}
+	var completedAt sql.NullTime
+	query := `
+		SELECT status, COALESCE(white_acpl, 0), COALESCE(black_acpl, 0),
+			COALESCE(white_accuracy, 0), COALESCE(black_accuracy, 0), requested_at, completed_at
+		FROM game_analysis WHERE game_id = $1
+	`
+	err := s.db.QueryRowContext(ctx, query, gameID).Scan(&a.Status,
+		&a.White.ACPL, &a.Black.ACPL, &a.White.Accuracy, &a.Black.Accuracy, &a.RequestedAt, &completedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if completedAt.Valid {
+		a.CompletedAt = &completedAt.Time
+	}
+	if a.Status != AnalysisDone {
+		return a, nil
+	}
+
+	query = `
+		SELECT ply, color, move, eval_cp, eval_mate, best_move, cp_loss, classification
+		FROM move_analysis WHERE game_id = $1 ORDER BY ply
+	`
+	rows, err := s.db.QueryContext(ctx, query, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m MoveAnalysis
+		if err := rows.Scan(&m.Ply, &m.Color, &m.Move, &m.EvalCP, &m.EvalMate, &m.BestMove, &m.CPLoss, &m.Classification); err != nil {
+			return nil, err
+		}
+		a.Moves = append(a.Moves, m)
+
+		p := &a.White
+		if m.Color == "black" {
+			p = &a.Black
+		}
+		switch m.Classification {
+		case "inaccuracy":
+			p.Inaccuracies++
+		case "mistake":
+			p.Mistakes++
+		case "blunder":
+			p.Blunders++
+		}
+	}
+	return a, rows.Err()
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type ChatMessage struct {
+	ID        int       `json:"id"`
+	GameID    string    `json:"game_id"`
+	UserID    string    `json:"user_id"`
+	Room      string    `json:"room"`
+	Body      string    `json:"body"`
+	Blocked   bool      `json:"blocked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChatStore interface {
+	InsertChatMessage(ctx context.Context, msg *ChatMessage) error
+	IsChatMuted(ctx context.Context, userID string) (bool, error)
+	SetChatMuted(ctx context.Context, userID string, muted bool) error
+}
+
+type PostgresChatStore struct {
+	db *sql.DB
+}
+
+func NewPostgresChatStore(db *sql.DB) *PostgresChatStore {
+	return &PostgresChatStore{db: db}
+}
+
+func (s *PostgresChatStore) InsertChatMessage(ctx context.Context, msg *ChatMessage) error {
+	query := `
+		INSERT INTO chat_messages (game_id, user_id, room, body, blocked, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	return s.db.QueryRowContext(ctx, query, msg.GameID, msg.UserID, msg.Room, msg.Body, msg.Blocked, msg.CreatedAt).Scan(&msg.ID)
+}
+
+func (s *PostgresChatStore) IsChatMuted(ctx context.Context, userID string) (bool, error) {
+	var muted bool
+	query := `SELECT EXISTS (SELECT 1 FROM chat_mutes WHERE user_id = $1)`
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&muted)
+	return muted, err
+}
+
+func (s *PostgresChatStore) SetChatMuted(ctx context.Context, userID string, muted bool) error {
+	query := `DELETE FROM chat_mutes WHERE user_id = $1`
+	if muted {
+		query = `INSERT INTO chat_mutes (user_id) VALUES ($1) ON CONFLICT DO NOTHING`
+	}
+	_, err := s.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -3,6 +3,9 @@ package store
 import (
 	"context"
 	"database/sql"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Adi-ty/chess/internal/queue"
 )
@@ -14,16 +17,79 @@ type Game struct {
 	Status string `json:"status"`
 	Outcome string `json:"outcome,omitempty"`
 	Method string `json:"method,omitempty"`
+	BaseSeconds int `json:"base_seconds"`
+	IncrementSeconds int `json:"increment_seconds"`
+	Rated bool `json:"rated"`
+	RatingCategory string `json:"rating_category,omitempty"`
+	SeriesID string `json:"series_id,omitempty"`
+	RematchOf string `json:"rematch_of,omitempty"`
+	// Variant is the rule set. InitialFEN is empty for games that start
+	// from the standard position.
+	Variant string `json:"variant"`
+	InitialFEN string `json:"initial_fen,omitempty"`
+	// DaysPerMove is set for correspondence games, which have a deadline
+	// for each move instead of a clock. MoveDeadline is when the player to
+	// move runs out of time.
+	DaysPerMove int `json:"days_per_move,omitempty"`
+	MoveDeadline *time.Time `json:"move_deadline,omitempty"`
+	// TournamentID is set for the games of a tournament.
+	TournamentID string `json:"tournament_id,omitempty"`
+	// Arena is set for the games of an arena tournament, whose players may
+	// go berserk.
+	Arena bool `json:"arena,omitempty"`
+	WhiteBerserk bool `json:"white_berserk,omitempty"`
+	BlackBerserk bool `json:"black_berserk,omitempty"`
+	// Armageddon is set for the tiebreaks of a knockout match in which
+	// Black has less time and draw odds.
+	Armageddon bool `json:"armageddon,omitempty"`
 	StartedAt string `json:"started_at"`
 	EndedAt sql.NullString `json:"ended_at,omitempty"`
 }
 
 type GameStore interface {
 	CreateGame(ctx context.Context, game *Game) (*Game, error)
-	GetGameByUserID(ctx context.Context, id string) (*Game, error)
-	UpdateGameStatus(ctx context.Context, id string, status string, outcome string, method string, endedAt string) error
+	ListActiveGamesByUserID(ctx context.Context, userID string) ([]*Game, error)
+	ListOverdueGames(ctx context.Context, now time.Time) ([]*Game, error)
+	SetMoveDeadline(ctx context.Context, id string, deadline time.Time) error
+	SetGameBerserk(ctx context.Context, id string, white bool) error
+	UpdateGameStatus(ctx context.Context, id string, status string, outcome string, method string, endedAt string) (*RatingUpdate, error)
 	InsertMove(ctx context.Context, payload queue.MovePayload) error
 	GetMovesByGameID(ctx context.Context, gameID string) ([]queue.MovePayload, error)
+	DeleteMovesAfter(ctx context.Context, gameID string, moveNumber int) error
+	UpdateGameRecord(ctx context.Context, id string, record *GameRecord) error
+	GetGamePGN(ctx context.Context, id string) (string, error)
+	ExportPGNByUserID(ctx context.Context, userID string, fn func(pgn string) error) error
+	ExportPGNByTournamentID(ctx context.Context, tournamentID string, fn func(pgn string) error) error
+	GetGameByID(ctx context.Context, id string) (*Game, error)
+	ListGamesByUserID(ctx context.Context, filter GameFilter) ([]*Game, error)
+}
+
+// GameRecord is what is archived about a game once it is over.
+type GameRecord struct {
+	PGN      string
+	PlyCount int
+	ECO      string
+	Opening  string
+}
+
+// GameFilter selects a page of a user's games, newest first. Aborted games
+// are left out unless Status asks for them. Color, Outcome and OpponentID
+// are seen from UserID's side. Pages are keyed on
+// (started_at, id): set AfterStartedAt and AfterID to the last game of the
+// previous page to get the next one.
+type GameFilter struct {
+	UserID     string
+	Status     string
+	Color      string
+	Outcome    string
+	OpponentID string
+	Since      time.Time
+	Until      time.Time
+
+	AfterStartedAt time.Time
+	AfterID        string
+
+	Limit int
 }
 
 type PostgresGameStore struct {
@@ -38,9 +104,10 @@ func (s *PostgresGameStore) CreateGame(ctx context.Context, game *Game) (*Game,
 	var g Game
 	
 	query := `
-		INSERT INTO games (id, white_user_id, black_user_id, status, started_at, ended_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
-        RETURNING id, white_user_id, black_user_id, status, started_at, ended_at
+		INSERT INTO games (id, white_user_id, black_user_id, status, base_seconds, increment_seconds, rated, rating_category, series_id, rematch_of, variant, initial_fen, days_per_move, move_deadline, tournament_id, arena, armageddon, started_at, ended_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid, NULLIF($10, '')::uuid, $11, NULLIF($12, ''), $13, $14, NULLIF($15, '')::uuid, $16, $17, $18, $19)
+        RETURNING id, white_user_id, black_user_id, status, base_seconds, increment_seconds, rated, COALESCE(rating_category, ''),
+            COALESCE(series_id::text, ''), COALESCE(rematch_of::text, ''), variant, COALESCE(initial_fen, ''), days_per_move, move_deadline, COALESCE(tournament_id::text, ''), arena, armageddon, started_at, ended_at
 	`
 
 	err := s.db.QueryRowContext(ctx, query,
@@ -48,9 +115,22 @@ func (s *PostgresGameStore) CreateGame(ctx context.Context, game *Game) (*Game,
 		game.WhiteUserID,
 		game.BlackUserID,
 		game.Status,
+		game.BaseSeconds,
+		game.IncrementSeconds,
+		game.Rated,
+		game.RatingCategory,
+		game.SeriesID,
+		game.RematchOf,
+		game.Variant,
+		game.InitialFEN,
+		game.DaysPerMove,
+		game.MoveDeadline,
+		game.TournamentID,
+		game.Arena,
+		game.Armageddon,
 		game.StartedAt,
 		game.EndedAt,
-	).Scan(&g.ID, &g.WhiteUserID, &g.BlackUserID, &g.Status, &g.StartedAt, &g.EndedAt)
+	).Scan(&g.ID, &g.WhiteUserID, &g.BlackUserID, &g.Status, &g.BaseSeconds, &g.IncrementSeconds, &g.Rated, &g.RatingCategory, &g.SeriesID, &g.RematchOf, &g.Variant, &g.InitialFEN, &g.DaysPerMove, &g.MoveDeadline, &g.TournamentID, &g.Arena, &g.Armageddon, &g.StartedAt, &g.EndedAt)
 	
 	if err != nil {
 		return nil, err
@@ -59,45 +139,56 @@ func (s *PostgresGameStore) CreateGame(ctx context.Context, game *Game) (*Game,
 	return &g, nil
 }
 
-func (s *PostgresGameStore) GetGameByUserID(ctx context.Context, id string) (*Game, error) {
-	var g Game
-
-	query := `
-        SELECT id, white_user_id, black_user_id, status, started_at, ended_at
-        FROM games
-        WHERE (white_user_id = $1 OR black_user_id = $1) AND status = 'in_progress'
-        ORDER BY started_at DESC
-        LIMIT 1
-    `
-
-	row := s.db.QueryRowContext(ctx, query, id)
-	err := row.Scan(&g.ID, &g.WhiteUserID, &g.BlackUserID, &g.Status, &g.StartedAt, &g.EndedAt)
+// UpdateGameStatus records how a game ended and, for rated games that
+// finished with a result, updates both players' ratings in the same
+// transaction. Only a game still in progress is ended, so ending it twice
+// changes nothing and returns no update.
+func (s *PostgresGameStore) UpdateGameStatus(ctx context.Context, id string, status string, outcome string, method string, endedAt string) (*RatingUpdate, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return &g, nil
-}
-
-func (s *PostgresGameStore) UpdateGameStatus(ctx context.Context, id string, status string, outcome string, method string, endedAt string) error {
 	query := `
 		UPDATE games
 		SET status = $1, outcome = $2, method = $3, ended_at = $4
-		WHERE id = $5
+		WHERE id = $5 AND status = 'in_progress'
+		RETURNING white_user_id, black_user_id, rated, COALESCE(rating_category, '')
 	`
 
-	_, err := s.db.ExecContext(ctx, query, status, outcome, method, endedAt, id)
+	var whiteID, blackID sql.NullString
+	var rated bool
+	var category string
+	err = tx.QueryRowContext(ctx, query, status, outcome, method, endedAt, id).Scan(&whiteID, &blackID, &rated, &category)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	var update *RatingUpdate
+	if rated && category != "" && whiteID.Valid && blackID.Valid && (status == "completed" || status == "abandoned") {
+		update, err = applyRatings(ctx, tx, id, whiteID.String, blackID.String, category, outcome)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return update, nil
 }
 
 func (s *PostgresGameStore) GetMovesByGameID(ctx context.Context, gameID string) ([]queue.MovePayload, error) {
 	var moves []queue.MovePayload
 
-	query := `SELECT game_id, user_id, move_number, move, extract(epoch from created_at) FROM moves WHERE game_id = $1 ORDER BY move_number`
+	query := `
+		SELECT game_id, user_id, move_number, move, COALESCE(white_time_ms, 0), COALESCE(black_time_ms, 0), extract(epoch from created_at)
+		FROM moves WHERE game_id = $1 ORDER BY move_number
+	`
 
 	rows, err := s.db.QueryContext(ctx, query, gameID)
     if err != nil {
@@ -107,7 +198,7 @@ func (s *PostgresGameStore) GetMovesByGameID(ctx context.Context, gameID string)
 
     for rows.Next() {
         var m queue.MovePayload
-        if err := rows.Scan(&m.GameID, &m.UserID, &m.MoveNumber, &m.Move, &m.CreatedAt); err != nil {
+        if err := rows.Scan(&m.GameID, &m.UserID, &m.MoveNumber, &m.Move, &m.WhiteTimeMs, &m.BlackTimeMs, &m.CreatedAt); err != nil {
             return nil, err
         }
         moves = append(moves, m)
@@ -117,9 +208,209 @@ func (s *PostgresGameStore) GetMovesByGameID(ctx context.Context, gameID string)
 
 func (s *PostgresGameStore) InsertMove(ctx context.Context, payload queue.MovePayload) error {
 	query := `
-		INSERT INTO moves (game_id, user_id, move_number, move, created_at)
-		VALUES ($1, $2, $3, $4, to_timestamp($5))
+		INSERT INTO moves (game_id, user_id, move_number, move, white_time_ms, black_time_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, to_timestamp($7))
+	`
+	_, err := s.db.ExecContext(ctx, query, payload.GameID, payload.UserID, payload.MoveNumber, payload.Move, payload.WhiteTimeMs, payload.BlackTimeMs, payload.CreatedAt)
+	return err
+}
+
+func (s *PostgresGameStore) DeleteMovesAfter(ctx context.Context, gameID string, moveNumber int) error {
+	query := `DELETE FROM moves WHERE game_id = $1 AND move_number > $2`
+	_, err := s.db.ExecContext(ctx, query, gameID, moveNumber)
+	return err
+}
+func (s *PostgresGameStore) UpdateGameRecord(ctx context.Context, id string, record *GameRecord) error {
+	query := `UPDATE games SET pgn = $1, ply_count = $2, eco = NULLIF($3, ''), opening = NULLIF($4, '') WHERE id = $5`
+	_, err := s.db.ExecContext(ctx, query, record.PGN, record.PlyCount, record.ECO, record.Opening, id)
+	return err
+}
+
+// GetGamePGN returns the stored PGN of a game, or an empty string if the game
+// does not exist or has not finished yet.
+func (s *PostgresGameStore) GetGamePGN(ctx context.Context, id string) (string, error) {
+	var pgn string
+	query := `SELECT pgn FROM games WHERE id = $1`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&pgn)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return pgn, nil
+}
+
+// ExportPGNByUserID calls fn with the PGN of every finished game the user
+// played, oldest first, without loading them all into memory. Aborted games
+// have no result and are left out.
+func (s *PostgresGameStore) ExportPGNByUserID(ctx context.Context, userID string, fn func(pgn string) error) error {
+	query := `
+		SELECT pgn FROM games
+		WHERE (white_user_id = $1 OR black_user_id = $1) AND pgn <> '' AND status <> 'aborted'
+		ORDER BY started_at
 	`
-	_, err := s.db.ExecContext(ctx, query, payload.GameID, payload.UserID, payload.MoveNumber, payload.Move, payload.CreatedAt)
+	return exportPGN(ctx, s.db, fn, query, userID)
+}
+
+// ExportPGNByTournamentID calls fn with the PGN of every finished game of
+// the tournament by round, board and game, leaving out aborted ones.
+func (s *PostgresGameStore) ExportPGNByTournamentID(ctx context.Context, tournamentID string, fn func(pgn string) error) error {
+	query := `
+		SELECT g.pgn FROM tournament_pairings p
+		JOIN games g ON g.id = p.game_id
+		WHERE p.tournament_id = $1 AND g.pgn <> '' AND g.status <> 'aborted'
+		ORDER BY p.round, p.board, p.game
+	`
+	return exportPGN(ctx, s.db, fn, query, tournamentID)
+}
+
+func exportPGN(ctx context.Context, db *sql.DB, fn func(pgn string) error, query string, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pgn string
+		if err := rows.Scan(&pgn); err != nil {
+			return err
+		}
+		if err := fn(pgn); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+const gameColumns = `
+	id, white_user_id, black_user_id, status, COALESCE(outcome, ''), COALESCE(method, ''), base_seconds, increment_seconds, rated,
+	COALESCE(rating_category, ''), COALESCE(series_id::text, ''), COALESCE(rematch_of::text, ''), variant, COALESCE(initial_fen, ''),
+	days_per_move, move_deadline, COALESCE(tournament_id::text, ''), arena, white_berserk, black_berserk, armageddon, started_at, ended_at
+`
+
+func scanGame(row rowScanner) (*Game, error) {
+	var g Game
+	err := row.Scan(&g.ID, &g.WhiteUserID, &g.BlackUserID, &g.Status, &g.Outcome, &g.Method, &g.BaseSeconds, &g.IncrementSeconds, &g.Rated,
+		&g.RatingCategory, &g.SeriesID, &g.RematchOf, &g.Variant, &g.InitialFEN, &g.DaysPerMove, &g.MoveDeadline, &g.TournamentID,
+		&g.Arena, &g.WhiteBerserk, &g.BlackBerserk, &g.Armageddon, &g.StartedAt, &g.EndedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ListActiveGamesByUserID returns every game the user is still playing,
+// oldest first.
+func (s *PostgresGameStore) ListActiveGamesByUserID(ctx context.Context, userID string) ([]*Game, error) {
+	query := `SELECT ` + gameColumns + ` FROM games
+		WHERE (white_user_id = $1 OR black_user_id = $1) AND status = 'in_progress'
+		ORDER BY started_at, id`
+	return s.queryGames(ctx, query, userID)
+}
+
+// ListOverdueGames returns the games in progress whose move deadline passed
+// before now.
+func (s *PostgresGameStore) ListOverdueGames(ctx context.Context, now time.Time) ([]*Game, error) {
+	query := `SELECT ` + gameColumns + ` FROM games
+		WHERE status = 'in_progress' AND move_deadline < $1
+		ORDER BY move_deadline`
+	return s.queryGames(ctx, query, now)
+}
+
+func (s *PostgresGameStore) SetMoveDeadline(ctx context.Context, id string, deadline time.Time) error {
+	query := `UPDATE games SET move_deadline = $1 WHERE id = $2`
+	_, err := s.db.ExecContext(ctx, query, deadline, id)
 	return err
-}
\ No newline at end of file
+}
+
+// SetGameBerserk records that a side of an arena game went berserk.
+func (s *PostgresGameStore) SetGameBerserk(ctx context.Context, id string, white bool) error {
+	query := `UPDATE games SET black_berserk = true WHERE id = $1`
+	if white {
+		query = `UPDATE games SET white_berserk = true WHERE id = $1`
+	}
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *PostgresGameStore) queryGames(ctx context.Context, query string, args ...any) ([]*Game, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []*Game
+	for rows.Next() {
+		g, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+func (s *PostgresGameStore) GetGameByID(ctx context.Context, id string) (*Game, error) {
+	query := `SELECT ` + gameColumns + ` FROM games WHERE id = $1`
+
+	g, err := scanGame(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *PostgresGameStore) ListGamesByUserID(ctx context.Context, filter GameFilter) ([]*Game, error) {
+	args := []any{filter.UserID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"(white_user_id = $1 OR black_user_id = $1)"}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+arg(filter.Status))
+	} else {
+		conditions = append(conditions, "status <> 'aborted'")
+	}
+	switch filter.Color {
+	case "white":
+		conditions = append(conditions, "white_user_id = $1")
+	case "black":
+		conditions = append(conditions, "black_user_id = $1")
+	}
+	switch filter.Outcome {
+	case "win":
+		conditions = append(conditions, "((white_user_id = $1 AND outcome = '1-0') OR (black_user_id = $1 AND outcome = '0-1'))")
+	case "loss":
+		conditions = append(conditions, "((white_user_id = $1 AND outcome = '0-1') OR (black_user_id = $1 AND outcome = '1-0'))")
+	case "draw":
+		conditions = append(conditions, "outcome = '1/2-1/2'")
+	}
+	if filter.OpponentID != "" {
+		opponent := arg(filter.OpponentID)
+		conditions = append(conditions, "((white_user_id = $1 AND black_user_id = "+opponent+") OR (black_user_id = $1 AND white_user_id = "+opponent+"))")
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "started_at >= "+arg(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "started_at < "+arg(filter.Until))
+	}
+	if filter.AfterID != "" {
+		conditions = append(conditions, "(started_at, id) < ("+arg(filter.AfterStartedAt)+", "+arg(filter.AfterID)+")")
+	}
+
+	query := `SELECT ` + gameColumns + ` FROM games
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY started_at DESC, id DESC
+		LIMIT ` + arg(filter.Limit)
+
+	return s.queryGames(ctx, query, args...)
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/rating"
+)
+
+var ErrPuzzleAttempted = errors.New("puzzle already attempted")
+
+// DefaultPuzzleDeviation is the deviation of imported puzzles whose file
+// has none.
+const DefaultPuzzleDeviation = 75.0
+
+// Puzzle is a position to solve. Its solution is never served.
+type Puzzle struct {
+	ID        string    `json:"id"`
+	FEN       string    `json:"fen"`
+	Solution  []string  `json:"-"`
+	Rating    float64   `json:"rating"`
+	Deviation float64   `json:"-"`
+	Themes    []string  `json:"themes"`
+	Plays     int       `json:"plays"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Glicko is the puzzle's rating as an opponent. Puzzles are not rated
+// themselves, so their volatility never matters.
+func (p *Puzzle) Glicko() rating.Rating {
+	return rating.Rating{
+		Rating:     p.Rating,
+		Deviation:  p.Deviation,
+		Volatility: rating.DefaultVolatility,
+	}
+}
+
+// PuzzleAttempt is a user's one try at a puzzle and the puzzle rating it
+// left them with. An attempt starts when the puzzle is served and is only
+// rated once it is finished.
+type PuzzleAttempt struct {
+	UserID     string    `json:"user_id"`
+	PuzzleID   string    `json:"puzzle_id"`
+	Solved     bool      `json:"solved"`
+	Finished   bool      `json:"finished"`
+	RatingDiff int       `json:"rating_diff"`
+	CreatedAt  time.Time `json:"created_at"`
+	Rating     *Rating   `json:"rating,omitempty"`
+}
+
+type PuzzleStore interface {
+	UpsertPuzzle(ctx context.Context, puzzle *Puzzle) error
+	GetPuzzle(ctx context.Context, id string) (*Puzzle, error)
+	NextPuzzle(ctx context.Context, userID string, near float64, theme string) (*Puzzle, error)
+	ServePuzzle(ctx context.Context, userID, puzzleID string) error
+	FailServedPuzzles(ctx context.Context, userID string) error
+	GetPuzzleAttempt(ctx context.Context, userID, puzzleID string) (*PuzzleAttempt, error)
+	RecordPuzzleAttempt(ctx context.Context, userID, puzzleID string, solved bool) (*PuzzleAttempt, error)
+}
+
+type PostgresPuzzleStore struct {
+	db *sql.DB
+}
+
+func NewPostgresPuzzleStore(db *sql.DB) *PostgresPuzzleStore {
+	return &PostgresPuzzleStore{db: db}
+}
+
+const puzzleColumns = `id, fen, moves, rating, deviation, array_to_string(themes, ' '), plays, created_at`
+
+// puzzleWindows are the rating distances a next puzzle is picked from at
+// random, the narrowest first.
+var puzzleWindows = []float64{100, 200, 400}
+
+// UpsertPuzzle adds a puzzle, or replaces the one with its ID. Its plays
+// are kept.
+func (s *PostgresPuzzleStore) UpsertPuzzle(ctx context.Context, puzzle *Puzzle) error {
+	query := `
+		INSERT INTO puzzles (id, fen, moves, rating, deviation, themes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE
+		SET fen = EXCLUDED.fen, moves = EXCLUDED.moves, rating = EXCLUDED.rating,
+			deviation = EXCLUDED.deviation, themes = EXCLUDED.themes
+	`
+	themes := puzzle.Themes
+	if themes == nil {
+		themes = []string{}
+	}
+	_, err := s.db.ExecContext(ctx, query, puzzle.ID, puzzle.FEN, strings.Join(puzzle.Solution, " "),
+		puzzle.Rating, puzzle.Deviation, themes)
+	return err
+}
+
+func (s *PostgresPuzzleStore) GetPuzzle(ctx context.Context, id string) (*Puzzle, error) {
+	query := `SELECT ` + puzzleColumns + ` FROM puzzles WHERE id = $1`
+
+	p, err := scanPuzzle(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// NextPuzzle picks a puzzle the user has not tried, with theme unless it is
+// empty: a random one within the narrowest window around near that has
+// any, and otherwise the closest. It returns nil when there is none left.
+//
+// Rather than sorting the window, a random rating is drawn in it and the
+// first puzzle above it taken, or else the first below, so that the rating
+// index does the work.
+func (s *PostgresPuzzleStore) NextPuzzle(ctx context.Context, userID string, near float64, theme string) (*Puzzle, error) {
+	for _, window := range puzzleWindows {
+		pivot := near - window + rand.Float64()*2*window
+		p, err := s.firstPuzzle(ctx, `p.rating >= $3 AND p.rating <= $4 ORDER BY p.rating`, userID, theme, pivot, near+window)
+		if p != nil || err != nil {
+			return p, err
+		}
+		p, err = s.firstPuzzle(ctx, `p.rating < $3 AND p.rating >= $4 ORDER BY p.rating DESC`, userID, theme, pivot, near-window)
+		if p != nil || err != nil {
+			return p, err
+		}
+	}
+
+	above, err := s.firstPuzzle(ctx, `p.rating >= $3 ORDER BY p.rating`, userID, theme, near)
+	if err != nil {
+		return nil, err
+	}
+	below, err := s.firstPuzzle(ctx, `p.rating < $3 ORDER BY p.rating DESC`, userID, theme, near)
+	if err != nil || above == nil {
+		return below, err
+	}
+	if below != nil && near-below.Rating < above.Rating-near {
+		return below, nil
+	}
+	return above, nil
+}
+
+// firstPuzzle returns the first puzzle the user has not tried, with theme
+// unless it is empty, or nil if there is none. order holds the rest of the
+// conditions and the ORDER BY, with userID and theme as $1 and $2 and args
+// from $3 on.
+func (s *PostgresPuzzleStore) firstPuzzle(ctx context.Context, order string, userID, theme string, args ...any) (*Puzzle, error) {
+	query := `
+		SELECT ` + puzzleColumns + ` FROM puzzles p
+		WHERE ($2 = '' OR p.themes @> ARRAY[$2]::text[])
+		AND NOT EXISTS (SELECT 1 FROM puzzle_attempts a WHERE a.user_id = $1 AND a.puzzle_id = p.id)
+		AND ` + order + ` LIMIT 1`
+
+	p, err := scanPuzzle(s.db.QueryRowContext(ctx, query, append([]any{userID, theme}, args...)...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ServePuzzle starts the user's attempt at a puzzle they are shown. The
+// attempt is rated once they solve or fail it, and fails when they ask for
+// another puzzle first, so that a hard one cannot be skipped.
+func (s *PostgresPuzzleStore) ServePuzzle(ctx context.Context, userID, puzzleID string) error {
+	query := `
+		INSERT INTO puzzle_attempts (user_id, puzzle_id, solved, finished) VALUES ($1, $2, false, false)
+		ON CONFLICT DO NOTHING
+	`
+	_, err := s.db.ExecContext(ctx, query, userID, puzzleID)
+	return err
+}
+
+// FailServedPuzzles finishes and rates as failed every attempt the user
+// started without finishing it.
+func (s *PostgresPuzzleStore) FailServedPuzzles(ctx context.Context, userID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE puzzle_attempts SET finished = true
+		WHERE user_id = $1 AND NOT finished
+		RETURNING user_id, puzzle_id, solved, created_at
+	`
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	var failed []*PuzzleAttempt
+	for rows.Next() {
+		a := &PuzzleAttempt{Finished: true}
+		if err := rows.Scan(&a.UserID, &a.PuzzleID, &a.Solved, &a.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		failed = append(failed, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, a := range failed {
+		if err := ratePuzzleAttempt(ctx, tx, a); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresPuzzleStore) GetPuzzleAttempt(ctx context.Context, userID, puzzleID string) (*PuzzleAttempt, error) {
+	query := `
+		SELECT user_id, puzzle_id, solved, finished, rating_diff, created_at
+		FROM puzzle_attempts WHERE user_id = $1 AND puzzle_id = $2
+	`
+
+	var a PuzzleAttempt
+	err := s.db.QueryRowContext(ctx, query, userID, puzzleID).Scan(&a.UserID, &a.PuzzleID, &a.Solved, &a.Finished, &a.RatingDiff, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RecordPuzzleAttempt finishes the user's attempt at a puzzle, starting it
+// if the puzzle was not served, and rates it as a game against the puzzle,
+// won if they solved it. An attempt that is already finished fails with
+// ErrPuzzleAttempted.
+func (s *PostgresPuzzleStore) RecordPuzzleAttempt(ctx context.Context, userID, puzzleID string, solved bool) (*PuzzleAttempt, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO puzzle_attempts (user_id, puzzle_id, solved, finished) VALUES ($1, $2, $3, true)
+		ON CONFLICT (user_id, puzzle_id) DO UPDATE SET solved = EXCLUDED.solved, finished = true
+		WHERE NOT puzzle_attempts.finished
+		RETURNING user_id, puzzle_id, solved, created_at
+	`
+	a := PuzzleAttempt{Finished: true}
+	err = tx.QueryRowContext(ctx, query, userID, puzzleID, solved).Scan(&a.UserID, &a.PuzzleID, &a.Solved, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPuzzleAttempted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ratePuzzleAttempt(ctx, tx, &a); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ratePuzzleAttempt counts a finished attempt as a play of the puzzle and
+// rates it, filling in the attempt's new rating and its difference.
+func ratePuzzleAttempt(ctx context.Context, tx *sql.Tx, a *PuzzleAttempt) error {
+	query := `UPDATE puzzles SET plays = plays + 1 WHERE id = $1 RETURNING ` + puzzleColumns
+	puzzle, err := scanPuzzle(tx.QueryRowContext(ctx, query, a.PuzzleID))
+	if err != nil {
+		return err
+	}
+
+	old, err := lockRating(ctx, tx, a.UserID, string(rating.Puzzle))
+	if err != nil {
+		return err
+	}
+	score := 0.0
+	if a.Solved {
+		score = 1
+	}
+	updated := rating.Update(old.Glicko(), rating.Result{Opponent: puzzle.Glicko(), Score: score})
+	if a.Rating, err = saveRating(ctx, tx, "", old, updated); err != nil {
+		return err
+	}
+
+	a.RatingDiff = roundDiff(old.Rating, updated.Rating)
+	query = `UPDATE puzzle_attempts SET rating_diff = $1 WHERE user_id = $2 AND puzzle_id = $3`
+	_, err = tx.ExecContext(ctx, query, a.RatingDiff, a.UserID, a.PuzzleID)
+	return err
+}
+
+func scanPuzzle(row rowScanner) (*Puzzle, error) {
+	var p Puzzle
+	var moves, themes string
+	err := row.Scan(&p.ID, &p.FEN, &moves, &p.Rating, &p.Deviation, &themes, &p.Plays, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.Solution = strings.Fields(moves)
+	p.Themes = strings.Fields(themes)
+	return &p, nil
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/rating"
+)
+
+type Rating struct {
+	UserID      string    `json:"user_id"`
+	Category    string    `json:"category"`
+	Rating      float64   `json:"rating"`
+	Deviation   float64   `json:"deviation"`
+	Volatility  float64   `json:"volatility"`
+	Games       int       `json:"games"`
+	Provisional bool      `json:"provisional"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (r *Rating) Glicko() rating.Rating {
+	return rating.Rating{
+		Rating:     r.Rating,
+		Deviation:  r.Deviation,
+		Volatility: r.Volatility,
+	}
+}
+
+// RatingUpdate is the result of rating a finished game.
+type RatingUpdate struct {
+	Category  string
+	White     *Rating
+	Black     *Rating
+	WhiteDiff int
+	BlackDiff int
+}
+
+type RatingStore interface {
+	GetRating(ctx context.Context, userID string, category string) (*Rating, error)
+	GetRatingsByUserID(ctx context.Context, userID string) ([]*Rating, error)
+	ListActiveRatings(ctx context.Context, category string, since time.Time) ([]*Rating, error)
+}
+
+type PostgresRatingStore struct {
+	db *sql.DB
+}
+
+func NewPostgresRatingStore(db *sql.DB) *PostgresRatingStore {
+	return &PostgresRatingStore{db: db}
+}
+
+// GetRating returns the user's rating in category, or the default rating if
+// they have not played a rated game in it yet.
+func (s *PostgresRatingStore) GetRating(ctx context.Context, userID string, category string) (*Rating, error) {
+	query := `
+		SELECT user_id, category, rating, deviation, volatility, games, updated_at
+		FROM ratings WHERE user_id = $1 AND category = $2
+	`
+
+	r, err := scanRating(s.db.QueryRowContext(ctx, query, userID, category))
+	if err == sql.ErrNoRows {
+		return defaultRating(userID, category), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (s *PostgresRatingStore) GetRatingsByUserID(ctx context.Context, userID string) ([]*Rating, error) {
+	query := `
+		SELECT user_id, category, rating, deviation, volatility, games, updated_at
+		FROM ratings WHERE user_id = $1 ORDER BY category
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ratings []*Rating
+	for rows.Next() {
+		r, err := scanRating(rows)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+// ListActiveRatings returns every rating in category that changed since the
+// given time, provisional ones included.
+func (s *PostgresRatingStore) ListActiveRatings(ctx context.Context, category string, since time.Time) ([]*Rating, error) {
+	query := `
+		SELECT user_id, category, rating, deviation, volatility, games, updated_at
+		FROM ratings WHERE category = $1 AND games > 0 AND updated_at >= $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, category, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ratings []*Rating
+	for rows.Next() {
+		r, err := scanRating(rows)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRating(row rowScanner) (*Rating, error) {
+	var r Rating
+	err := row.Scan(&r.UserID, &r.Category, &r.Rating, &r.Deviation, &r.Volatility, &r.Games, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.Provisional = r.Glicko().Provisional()
+	return &r, nil
+}
+
+func defaultRating(userID string, category string) *Rating {
+	d := rating.Default()
+	return &Rating{
+		UserID:      userID,
+		Category:    category,
+		Rating:      d.Rating,
+		Deviation:   d.Deviation,
+		Volatility:  d.Volatility,
+		Provisional: true,
+	}
+}
+
+// scoreFor converts a PGN result into White's score. ok is false for results
+// that cannot be rated.
+func scoreFor(outcome string) (score float64, ok bool) {
+	switch outcome {
+	case "1-0":
+		return 1, true
+	case "0-1":
+		return 0, true
+	case "1/2-1/2":
+		return 0.5, true
+	}
+	return 0, false
+}
+
+// applyRatings rates a finished game inside tx: both players' ratings are
+// locked, updated against each other's pre-game rating, and the deltas are
+// written to the game row.
+func applyRatings(ctx context.Context, tx *sql.Tx, gameID, whiteID, blackID, category, outcome string) (*RatingUpdate, error) {
+	whiteScore, ok := scoreFor(outcome)
+	if !ok {
+		return nil, nil
+	}
+
+	// Lock in a stable order so two games between the same pair finishing
+	// at once cannot deadlock.
+	first, second := whiteID, blackID
+	if second < first {
+		first, second = second, first
+	}
+	locked := make(map[string]*Rating, 2)
+	for _, userID := range []string{first, second} {
+		r, err := lockRating(ctx, tx, userID, category)
+		if err != nil {
+			return nil, err
+		}
+		locked[userID] = r
+	}
+	white, black := locked[whiteID], locked[blackID]
+
+	newWhite := rating.Update(white.Glicko(), rating.Result{Opponent: black.Glicko(), Score: whiteScore})
+	newBlack := rating.Update(black.Glicko(), rating.Result{Opponent: white.Glicko(), Score: 1 - whiteScore})
+
+	update := &RatingUpdate{
+		Category:  category,
+		WhiteDiff: roundDiff(white.Rating, newWhite.Rating),
+		BlackDiff: roundDiff(black.Rating, newBlack.Rating),
+	}
+
+	var err error
+	if update.White, err = saveRating(ctx, tx, gameID, white, newWhite); err != nil {
+		return nil, err
+	}
+	if update.Black, err = saveRating(ctx, tx, gameID, black, newBlack); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE games SET white_rating_diff = $1, black_rating_diff = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, update.WhiteDiff, update.BlackDiff, gameID); err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+func lockRating(ctx context.Context, tx *sql.Tx, userID, category string) (*Rating, error) {
+	insert := `INSERT INTO ratings (user_id, category) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, insert, userID, category); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT user_id, category, rating, deviation, volatility, games, updated_at
+		FROM ratings WHERE user_id = $1 AND category = $2
+		FOR UPDATE
+	`
+	return scanRating(tx.QueryRowContext(ctx, query, userID, category))
+}
+
+// saveRating writes an updated rating and its history entry. gameID is
+// empty for puzzles.
+func saveRating(ctx context.Context, tx *sql.Tx, gameID string, old *Rating, updated rating.Rating) (*Rating, error) {
+	query := `
+		UPDATE ratings
+		SET rating = $1, deviation = $2, volatility = $3, games = games + 1, updated_at = NOW()
+		WHERE user_id = $4 AND category = $5
+		RETURNING user_id, category, rating, deviation, volatility, games, updated_at
+	`
+	r, err := scanRating(tx.QueryRowContext(ctx, query, updated.Rating, updated.Deviation, updated.Volatility, old.UserID, old.Category))
+	if err != nil {
+		return nil, err
+	}
+
+	history := `
+		INSERT INTO rating_history (user_id, category, game_id, rating, deviation, volatility)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6)
+	`
+	if _, err := tx.ExecContext(ctx, history, r.UserID, r.Category, gameID, r.Rating, r.Deviation, r.Volatility); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func roundDiff(before, after float64) int {
+	return int(math.Round(after) - math.Round(before))
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const maxProfileOpenings = 5
+
+// UserStats aggregates a user's finished games. It is cached in user_stats
+// and recomputed whenever one of their games finishes.
+type UserStats struct {
+	TotalGames      int            `json:"total_games"`
+	White           ResultCounts   `json:"white"`
+	Black           ResultCounts   `json:"black"`
+	CurrentStreak   Streak         `json:"current_streak"`
+	BestWinStreak   int            `json:"best_win_streak"`
+	Openings        []OpeningCount `json:"openings"`
+	AverageMoves    float64        `json:"average_moves"`
+	AverageDuration float64        `json:"average_duration_seconds"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+type ResultCounts struct {
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+}
+
+// Streak is the run of identical results ending with the user's latest game.
+type Streak struct {
+	Result string `json:"result,omitempty"`
+	Count  int    `json:"count"`
+}
+
+type OpeningCount struct {
+	ECO   string `json:"eco"`
+	Name  string `json:"name"`
+	Games int    `json:"games"`
+}
+
+type StatsStore interface {
+	GetUserStats(ctx context.Context, userID string) (*UserStats, error)
+	RefreshUserStats(ctx context.Context, userID string) (*UserStats, error)
+}
+
+type PostgresStatsStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStatsStore(db *sql.DB) *PostgresStatsStore {
+	return &PostgresStatsStore{db: db}
+}
+
+// GetUserStats returns the cached statistics, or nil if they have never been
+// computed for the user.
+func (s *PostgresStatsStore) GetUserStats(ctx context.Context, userID string) (*UserStats, error) {
+	var raw []byte
+	query := `SELECT stats FROM user_stats WHERE user_id = $1`
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&raw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var stats UserStats
+	if err := json.Unmarshal(raw, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// RefreshUserStats recomputes the user's statistics from their finished games
+// and caches the result.
+func (s *PostgresStatsStore) RefreshUserStats(ctx context.Context, userID string) (*UserStats, error) {
+	query := `
+		SELECT white_user_id = $1, outcome, ply_count,
+			COALESCE(EXTRACT(EPOCH FROM ended_at - started_at), 0), COALESCE(eco, ''), COALESCE(opening, '')
+		FROM games
+		WHERE (white_user_id = $1 OR black_user_id = $1)
+			AND status IN ('completed', 'abandoned') AND outcome IN ('1-0', '0-1', '1/2-1/2')
+		ORDER BY ended_at, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &UserStats{Openings: []OpeningCount{}}
+	openings := map[string]*OpeningCount{}
+	var totalPlies int
+	var totalSeconds float64
+	var winStreak int
+
+	for rows.Next() {
+		var isWhite bool
+		var outcome, eco, name string
+		var plies int
+		var seconds float64
+		if err := rows.Scan(&isWhite, &outcome, &plies, &seconds, &eco, &name); err != nil {
+			return nil, err
+		}
+
+		result := resultFor(isWhite, outcome)
+		counts := &stats.Black
+		if isWhite {
+			counts = &stats.White
+		}
+		switch result {
+		case "win":
+			counts.Wins++
+			winStreak++
+		case "loss":
+			counts.Losses++
+			winStreak = 0
+		default:
+			counts.Draws++
+			winStreak = 0
+		}
+		if winStreak > stats.BestWinStreak {
+			stats.BestWinStreak = winStreak
+		}
+
+		if result == stats.CurrentStreak.Result {
+			stats.CurrentStreak.Count++
+		} else {
+			stats.CurrentStreak = Streak{Result: result, Count: 1}
+		}
+
+		if eco != "" {
+			if o, ok := openings[eco+name]; ok {
+				o.Games++
+			} else {
+				openings[eco+name] = &OpeningCount{ECO: eco, Name: name, Games: 1}
+			}
+		}
+
+		stats.TotalGames++
+		totalPlies += plies
+		totalSeconds += seconds
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if stats.TotalGames > 0 {
+		// A move is a pair of plies, one by each side.
+		stats.AverageMoves = float64(totalPlies) / 2 / float64(stats.TotalGames)
+		stats.AverageDuration = totalSeconds / float64(stats.TotalGames)
+	}
+
+	for _, o := range openings {
+		stats.Openings = append(stats.Openings, *o)
+	}
+	sort.Slice(stats.Openings, func(i, j int) bool {
+		if stats.Openings[i].Games != stats.Openings[j].Games {
+			return stats.Openings[i].Games > stats.Openings[j].Games
+		}
+		return stats.Openings[i].ECO < stats.Openings[j].ECO
+	})
+	if len(stats.Openings) > maxProfileOpenings {
+		stats.Openings = stats.Openings[:maxProfileOpenings]
+	}
+	stats.UpdatedAt = time.Now().UTC()
+
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+	upsert := `
+		INSERT INTO user_stats (user_id, stats, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET stats = EXCLUDED.stats, updated_at = NOW()
+	`
+	if _, err := s.db.ExecContext(ctx, upsert, userID, raw); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func resultFor(isWhite bool, outcome string) string {
+	switch {
+	case outcome == "1/2-1/2":
+		return "draw"
+	case (outcome == "1-0") == isWhite:
+		return "win"
+	default:
+		return "loss"
+	}
+}
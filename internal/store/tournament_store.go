@@ -0,0 +1,404 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/tournament"
+)
+
+const (
+	TournamentCreated    = "created"
+	TournamentInProgress = "in_progress"
+	TournamentFinished   = "finished"
+)
+
+type Tournament struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Format      string `json:"format"`
+	Status      string `json:"status"`
+	CreatorID   string `json:"creator_id"`
+	TimeControl string `json:"time_control"`
+	Rated       bool   `json:"rated"`
+	Variant     string `json:"variant"`
+	// Rounds is how many rounds are played. CurrentRound is the round in
+	// progress, or the last one played once the tournament is over. An
+	// arena instead plays for DurationMinutes as a single round.
+	Rounds          int `json:"rounds"`
+	CurrentRound    int `json:"current_round"`
+	DurationMinutes int `json:"duration_minutes,omitempty"`
+	// Tiebreak is how drawn knockout matches are decided.
+	Tiebreak  string     `json:"tiebreak,omitempty"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TournamentPlayer is a registered player. Seed is their place in a round
+// robin or knockout, fixed when it starts. Rank and Score are their final
+// result, set when the tournament finishes.
+type TournamentPlayer struct {
+	tournament.Player
+	Withdrawn bool      `json:"withdrawn"`
+	JoinedAt  time.Time `json:"joined_at"`
+	Seed      int       `json:"seed,omitempty"`
+	Rank      *int      `json:"rank,omitempty"`
+	Score     *float64  `json:"score,omitempty"`
+}
+
+// TournamentResult is a player's final place in a tournament.
+type TournamentResult struct {
+	UserID string
+	Rank   int
+	Score  float64
+}
+
+type TournamentRound struct {
+	Round     int        `json:"round"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+type TournamentStore interface {
+	CreateTournament(ctx context.Context, t *Tournament) error
+	GetTournament(ctx context.Context, id string) (*Tournament, error)
+	ListTournaments(ctx context.Context, status string) ([]*Tournament, error)
+	ListDueTournaments(ctx context.Context, now time.Time) ([]*Tournament, error)
+	AddTournamentPlayer(ctx context.Context, tournamentID string, player tournament.Player) error
+	WithdrawTournamentPlayer(ctx context.Context, tournamentID, userID string) error
+	ListTournamentPlayers(ctx context.Context, tournamentID string) ([]*TournamentPlayer, error)
+	SeedTournament(ctx context.Context, tournamentID string, seeds []string, rounds int) error
+	StartTournamentRound(ctx context.Context, tournamentID string, round int, pairings []tournament.Pairing) error
+	AddTournamentPairing(ctx context.Context, tournamentID string, pairing *tournament.Pairing) error
+	SetPairingGame(ctx context.Context, tournamentID string, pairing tournament.Pairing) error
+	ListTournamentRounds(ctx context.Context, tournamentID string) ([]*TournamentRound, error)
+	ListTournamentPairings(ctx context.Context, tournamentID string) ([]tournament.Pairing, error)
+	GetTournamentPairing(ctx context.Context, gameID string) (*tournament.Pairing, error)
+	SetPairingResult(ctx context.Context, pairing tournament.Pairing) error
+	FinishTournamentRound(ctx context.Context, tournamentID string, round int) error
+	FinishTournament(ctx context.Context, tournamentID string, results []TournamentResult) error
+}
+
+type PostgresTournamentStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTournamentStore(db *sql.DB) *PostgresTournamentStore {
+	return &PostgresTournamentStore{db: db}
+}
+
+const tournamentColumns = `
+	id, name, format, status, creator_id, time_control, rated, variant, rounds, current_round,
+	duration_minutes, tiebreak, starts_at, started_at, ended_at, created_at
+`
+
+func scanTournament(row rowScanner) (*Tournament, error) {
+	var t Tournament
+	err := row.Scan(&t.ID, &t.Name, &t.Format, &t.Status, &t.CreatorID, &t.TimeControl, &t.Rated, &t.Variant, &t.Rounds, &t.CurrentRound,
+		&t.DurationMinutes, &t.Tiebreak, &t.StartsAt, &t.StartedAt, &t.EndedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateTournament stores a new tournament, filling in its ID, status and
+// creation time.
+func (s *PostgresTournamentStore) CreateTournament(ctx context.Context, t *Tournament) error {
+	query := `
+		INSERT INTO tournaments (name, format, creator_id, time_control, rated, variant, rounds, duration_minutes, tiebreak, starts_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, status, created_at
+	`
+	return s.db.QueryRowContext(ctx, query, t.Name, t.Format, t.CreatorID, t.TimeControl, t.Rated, t.Variant, t.Rounds, t.DurationMinutes, t.Tiebreak, t.StartsAt).
+		Scan(&t.ID, &t.Status, &t.CreatedAt)
+}
+
+// GetTournament returns the tournament, or nil if there is none with that ID.
+func (s *PostgresTournamentStore) GetTournament(ctx context.Context, id string) (*Tournament, error) {
+	query := `SELECT ` + tournamentColumns + ` FROM tournaments WHERE id = $1`
+	t, err := scanTournament(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListTournaments returns the tournaments with status, or all of them if it
+// is empty, the next to start first.
+func (s *PostgresTournamentStore) ListTournaments(ctx context.Context, status string) ([]*Tournament, error) {
+	query := `SELECT ` + tournamentColumns + ` FROM tournaments
+		WHERE $1 = '' OR status = $1
+		ORDER BY COALESCE(started_at, starts_at, created_at) DESC, id`
+	return s.queryTournaments(ctx, query, status)
+}
+
+// ListDueTournaments returns the tournaments scheduled to start by now that
+// have not started yet.
+func (s *PostgresTournamentStore) ListDueTournaments(ctx context.Context, now time.Time) ([]*Tournament, error) {
+	query := `SELECT ` + tournamentColumns + ` FROM tournaments
+		WHERE status = 'created' AND starts_at <= $1
+		ORDER BY starts_at`
+	return s.queryTournaments(ctx, query, now)
+}
+
+func (s *PostgresTournamentStore) queryTournaments(ctx context.Context, query string, args ...any) ([]*Tournament, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tournaments []*Tournament
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, rows.Err()
+}
+
+// AddTournamentPlayer registers a player, or registers them again if they
+// had withdrawn.
+func (s *PostgresTournamentStore) AddTournamentPlayer(ctx context.Context, tournamentID string, player tournament.Player) error {
+	query := `
+		INSERT INTO tournament_players (tournament_id, user_id, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tournament_id, user_id) DO UPDATE SET withdrawn = FALSE
+	`
+	_, err := s.db.ExecContext(ctx, query, tournamentID, player.ID, player.Rating)
+	return err
+}
+
+// WithdrawTournamentPlayer takes a player out of the rounds still to be
+// paired. Their results so far stand.
+func (s *PostgresTournamentStore) WithdrawTournamentPlayer(ctx context.Context, tournamentID, userID string) error {
+	query := `UPDATE tournament_players SET withdrawn = TRUE WHERE tournament_id = $1 AND user_id = $2`
+	_, err := s.db.ExecContext(ctx, query, tournamentID, userID)
+	return err
+}
+
+// ListTournamentPlayers returns every registered player, withdrawn or not,
+// in the order they joined.
+func (s *PostgresTournamentStore) ListTournamentPlayers(ctx context.Context, tournamentID string) ([]*TournamentPlayer, error) {
+	query := `
+		SELECT user_id, rating, withdrawn, joined_at, COALESCE(seed, 0), rank, score
+		FROM tournament_players WHERE tournament_id = $1
+		ORDER BY joined_at, user_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []*TournamentPlayer
+	for rows.Next() {
+		var p TournamentPlayer
+		if err := rows.Scan(&p.ID, &p.Rating, &p.Withdrawn, &p.JoinedAt, &p.Seed, &p.Rank, &p.Score); err != nil {
+			return nil, err
+		}
+		players = append(players, &p)
+	}
+	return players, rows.Err()
+}
+
+// SeedTournament fixes the seeds of a round robin or knockout, best first,
+// and how many rounds it has.
+func (s *PostgresTournamentStore) SeedTournament(ctx context.Context, tournamentID string, seeds []string, rounds int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE tournament_players SET seed = $1 WHERE tournament_id = $2 AND user_id = $3`
+	for i, userID := range seeds {
+		if _, err := tx.ExecContext(ctx, query, i+1, tournamentID, userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tournaments SET rounds = $1 WHERE id = $2`, rounds, tournamentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// StartTournamentRound stores a round and its pairings and makes it the
+// tournament's current round, starting the tournament with its first.
+func (s *PostgresTournamentStore) StartTournamentRound(ctx context.Context, tournamentID string, round int, pairings []tournament.Pairing) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO tournament_rounds (tournament_id, round) VALUES ($1, $2)`, tournamentID, round)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tournament_pairings (tournament_id, round, board, game, white_user_id, black_user_id, game_id, result)
+		VALUES ($1, $2, $3, GREATEST($4, 1), $5, NULLIF($6, '')::uuid, NULLIF($7, '')::uuid, $8)
+	`
+	for _, p := range pairings {
+		if _, err := tx.ExecContext(ctx, query, tournamentID, round, p.Board, p.Game, p.White, p.Black, p.GameID, p.Result); err != nil {
+			return err
+		}
+	}
+
+	query = `
+		UPDATE tournaments
+		SET status = 'in_progress', current_round = $1, started_at = COALESCE(started_at, NOW())
+		WHERE id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, round, tournamentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddTournamentPairing adds a pairing to a round that has started, such as
+// the next game of an arena or a knockout tiebreak. A pairing without a
+// board gets the one after the round's last.
+func (s *PostgresTournamentStore) AddTournamentPairing(ctx context.Context, tournamentID string, pairing *tournament.Pairing) error {
+	query := `
+		INSERT INTO tournament_pairings (tournament_id, round, board, game, white_user_id, black_user_id, game_id, result)
+		SELECT $1, $2, COALESCE(NULLIF($3, 0), MAX(board) + 1, 1), GREATEST($4, 1), $5, NULLIF($6, '')::uuid, NULLIF($7, '')::uuid, $8
+		FROM tournament_pairings WHERE tournament_id = $1 AND round = $2
+		RETURNING board
+	`
+	return s.db.QueryRowContext(ctx, query, tournamentID, pairing.Round, pairing.Board, pairing.Game, pairing.White, pairing.Black, pairing.GameID, pairing.Result).
+		Scan(&pairing.Board)
+}
+
+// SetPairingGame records pairing.GameID as the game a stored pairing is
+// played as.
+func (s *PostgresTournamentStore) SetPairingGame(ctx context.Context, tournamentID string, pairing tournament.Pairing) error {
+	query := `
+		UPDATE tournament_pairings SET game_id = $1
+		WHERE tournament_id = $2 AND round = $3 AND board = $4 AND game = GREATEST($5, 1)
+	`
+	_, err := s.db.ExecContext(ctx, query, pairing.GameID, tournamentID, pairing.Round, pairing.Board, pairing.Game)
+	return err
+}
+
+func (s *PostgresTournamentStore) ListTournamentRounds(ctx context.Context, tournamentID string) ([]*TournamentRound, error) {
+	query := `
+		SELECT round, started_at, ended_at
+		FROM tournament_rounds WHERE tournament_id = $1
+		ORDER BY round
+	`
+	rows, err := s.db.QueryContext(ctx, query, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rounds []*TournamentRound
+	for rows.Next() {
+		var r TournamentRound
+		if err := rows.Scan(&r.Round, &r.StartedAt, &r.EndedAt); err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, &r)
+	}
+	return rounds, rows.Err()
+}
+
+const pairingColumns = `
+	round, board, game, white_user_id, COALESCE(black_user_id::text, ''), COALESCE(game_id::text, ''), result,
+	white_berserk, black_berserk
+`
+
+func scanPairing(row rowScanner) (*tournament.Pairing, error) {
+	var p tournament.Pairing
+	err := row.Scan(&p.Round, &p.Board, &p.Game, &p.White, &p.Black, &p.GameID, &p.Result, &p.WhiteBerserk, &p.BlackBerserk)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListTournamentPairings returns every pairing of the tournament by round,
+// board and game.
+func (s *PostgresTournamentStore) ListTournamentPairings(ctx context.Context, tournamentID string) ([]tournament.Pairing, error) {
+	query := `SELECT ` + pairingColumns + ` FROM tournament_pairings
+		WHERE tournament_id = $1
+		ORDER BY round, board, game`
+	rows, err := s.db.QueryContext(ctx, query, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairings []tournament.Pairing
+	for rows.Next() {
+		p, err := scanPairing(rows)
+		if err != nil {
+			return nil, err
+		}
+		pairings = append(pairings, *p)
+	}
+	return pairings, rows.Err()
+}
+
+// GetTournamentPairing returns the pairing played as gameID, or nil if the
+// game is not a tournament game.
+func (s *PostgresTournamentStore) GetTournamentPairing(ctx context.Context, gameID string) (*tournament.Pairing, error) {
+	query := `SELECT ` + pairingColumns + ` FROM tournament_pairings WHERE game_id = $1`
+	p, err := scanPairing(s.db.QueryRowContext(ctx, query, gameID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// SetPairingResult records the result of the pairing played as
+// pairing.GameID and whether either player went berserk in it.
+func (s *PostgresTournamentStore) SetPairingResult(ctx context.Context, pairing tournament.Pairing) error {
+	query := `
+		UPDATE tournament_pairings SET result = $1, white_berserk = $2, black_berserk = $3
+		WHERE game_id = $4
+	`
+	_, err := s.db.ExecContext(ctx, query, pairing.Result, pairing.WhiteBerserk, pairing.BlackBerserk, pairing.GameID)
+	return err
+}
+
+func (s *PostgresTournamentStore) FinishTournamentRound(ctx context.Context, tournamentID string, round int) error {
+	query := `UPDATE tournament_rounds SET ended_at = NOW() WHERE tournament_id = $1 AND round = $2 AND ended_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, tournamentID, round)
+	return err
+}
+
+// FinishTournament closes the tournament and stores the players' final
+// results.
+func (s *PostgresTournamentStore) FinishTournament(ctx context.Context, tournamentID string, results []TournamentResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE tournament_players SET rank = $1, score = $2 WHERE tournament_id = $3 AND user_id = $4`
+	for _, r := range results {
+		if _, err := tx.ExecContext(ctx, query, r.Rank, r.Score, tournamentID, r.UserID); err != nil {
+			return err
+		}
+	}
+
+	query = `UPDATE tournaments SET status = 'finished', ended_at = NOW() WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, tournamentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -29,6 +29,7 @@ type PostgresUserStore struct {
 type UserStore interface {
 	CreateOrUpdate(ctx context.Context, user *User) (*User, error)
 	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUsersByIDs(ctx context.Context, ids []string) (map[string]*User, error)
 }
 
 func NewPostgresUserStore(db *sql.DB) *PostgresUserStore {
@@ -99,4 +100,29 @@ func (s *PostgresUserStore) GetUserByID(ctx context.Context, id string) (*User,
     }
 
     return &u, nil
-}
\ No newline at end of file
+}
+
+// GetUsersByIDs looks up several users at once. Unknown IDs are left out of
+// the result.
+func (s *PostgresUserStore) GetUsersByIDs(ctx context.Context, ids []string) (map[string]*User, error) {
+    query := `
+        SELECT id, email, display_name, COALESCE(avatar_url, ''), provider, provider_id, created_at, updated_at
+        FROM users WHERE id::text = ANY($1)
+    `
+
+    rows, err := s.db.QueryContext(ctx, query, ids)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    users := make(map[string]*User, len(ids))
+    for rows.Next() {
+        var u User
+        if err := rows.Scan(&u.ID, &u.Email, &u.DisplayName, &u.AvatarURL, &u.Provider, &u.ProviderID, &u.CreatedAt, &u.UpdatedAt); err != nil {
+            return nil, err
+        }
+        users[u.ID] = &u
+    }
+    return users, rows.Err()
+}
@@ -0,0 +1,124 @@
+package tournament
+
+import (
+	"math"
+	"sort"
+)
+
+// Arena scoring: a win is worth 2 points and a draw 1. Two wins in a row
+// set a player on fire, and while on fire every result scores double until
+// they fail to win. A berserk win earns one more point, which is never
+// doubled.
+const (
+	arenaWin          = 2
+	arenaDraw         = 1
+	arenaBerserkBonus = 1
+
+	// fireStreak is how many wins in a row set a player on fire.
+	fireStreak = 2
+)
+
+// ArenaStanding is a player's place in an arena.
+type ArenaStanding struct {
+	Rank int `json:"rank"`
+	Player
+	Score  int `json:"score"`
+	Played int `json:"played"`
+	Wins   int `json:"wins"`
+	// Berserks counts the games the player went berserk in.
+	Berserks int `json:"berserks"`
+	// Performance is the average rating of the player's opponents, plus
+	// 500 for every win and minus 500 for every loss, over games played.
+	Performance int  `json:"performance"`
+	OnFire      bool `json:"on_fire"`
+
+	streak  int
+	balance int
+	faced   float64
+}
+
+// ArenaStandings ranks an arena's players by score, then performance, then
+// rating. pairings must be in the order the games started, as streaks
+// depend on it; a player only ever has one arena game at a time, so that is
+// also the order their games finished in. Unfinished games do not count.
+func ArenaStandings(players []Player, pairings []Pairing) []ArenaStanding {
+	standings := make([]ArenaStanding, 0, len(players))
+	index := make(map[string]int, len(players))
+	for _, p := range players {
+		index[p.ID] = len(standings)
+		standings = append(standings, ArenaStanding{Player: p})
+	}
+
+	for i := range pairings {
+		p := &pairings[i]
+		if !p.Finished() || p.Result == Bye {
+			continue
+		}
+		for _, id := range []string{p.White, p.Black} {
+			j, ok := index[id]
+			if !ok {
+				continue
+			}
+			s := &standings[j]
+			if k, ok := index[p.Opponent(id)]; ok {
+				s.faced += standings[k].Rating
+			}
+			s.record(p.Points(id), p.Berserked(id))
+		}
+	}
+
+	for i := range standings {
+		s := &standings[i]
+		s.OnFire = s.streak >= fireStreak
+		if s.Played > 0 {
+			s.Performance = int(math.Round((s.faced + 500*float64(s.balance)) / float64(s.Played)))
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.Performance != b.Performance:
+			return a.Performance > b.Performance
+		default:
+			return a.Rating > b.Rating
+		}
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// record scores one game, worth points in the usual 1, ½, 0.
+func (s *ArenaStanding) record(points float64, berserk bool) {
+	onFire := s.streak >= fireStreak
+	s.Played++
+	if berserk {
+		s.Berserks++
+	}
+
+	score := 0
+	switch points {
+	case 1:
+		s.Wins++
+		s.balance++
+		s.streak++
+		score = arenaWin
+	case 0.5:
+		s.streak = 0
+		score = arenaDraw
+	default:
+		s.balance--
+		s.streak = 0
+	}
+	if onFire {
+		score *= 2
+	}
+	if berserk && points == 1 {
+		score += arenaBerserkBonus
+	}
+	s.Score += score
+}
@@ -0,0 +1,413 @@
+package tournament
+
+import "sort"
+
+// Tiebreak is how a knockout match whose first game was drawn is decided.
+type Tiebreak string
+
+const (
+	// TiebreakArmageddon settles it with one armageddon game.
+	TiebreakArmageddon Tiebreak = "armageddon"
+	// TiebreakBlitz plays two blitz games, and an armageddon game if the
+	// players share them.
+	TiebreakBlitz Tiebreak = "blitz"
+)
+
+// GameKind is how a game of a knockout match is played. Armageddon gives
+// White more time and Black draw odds.
+type GameKind string
+
+const (
+	MainGame       GameKind = "main"
+	BlitzGame      GameKind = "blitz"
+	ArmageddonGame GameKind = "armageddon"
+)
+
+// phases are the parts of a match. Each is played in full before its score
+// is compared, and the first to break the tie decides the match.
+func (tb Tiebreak) phases() [][]GameKind {
+	if tb == TiebreakBlitz {
+		return [][]GameKind{{MainGame}, {BlitzGame, BlitzGame}, {ArmageddonGame}}
+	}
+	return [][]GameKind{{MainGame}, {ArmageddonGame}}
+}
+
+// Kind is how the game-th game of a match is played.
+func (tb Tiebreak) Kind(game int) GameKind {
+	n := 0
+	for _, phase := range tb.phases() {
+		for _, kind := range phase {
+			n++
+			if n == game {
+				return kind
+			}
+		}
+	}
+	return MainGame
+}
+
+// settle works out a match between p1 and p2 from its games in order: the
+// winner, or else the number of the next game to play, which is 0 while a
+// game is in progress.
+func (tb Tiebreak) settle(p1, p2 string, games []Pairing) (winner string, next int) {
+	n := 0
+	for _, phase := range tb.phases() {
+		var s1, s2 float64
+		for _, kind := range phase {
+			if n >= len(games) {
+				return "", n + 1
+			}
+			g := &games[n]
+			n++
+			if !g.Finished() {
+				return "", 0
+			}
+			s1 += g.Points(p1)
+			s2 += g.Points(p2)
+			if kind == ArmageddonGame && g.Result == Drawn {
+				if g.Black == p1 {
+					s1++
+				} else {
+					s2++
+				}
+			}
+		}
+		switch {
+		case s1 > s2:
+			return p1, 0
+		case s2 > s1:
+			return p2, 0
+		}
+	}
+	// Armageddon cannot be shared.
+	return p1, 0
+}
+
+// Brackets of a knockout.
+const (
+	WinnersBracket = "winners"
+	LosersBracket  = "losers"
+	FinalBracket   = "final"
+)
+
+// Match is a knockout match. It is played in Round, one board of the
+// tournament round, and its players are known once the matches they come
+// from are decided. A player without an opponent goes through without
+// playing.
+type Match struct {
+	Bracket string    `json:"bracket"`
+	Round   int       `json:"round"`
+	Board   int       `json:"board"`
+	Player1 string    `json:"player1_user_id,omitempty"`
+	Player2 string    `json:"player2_user_id,omitempty"`
+	Winner  string    `json:"winner_user_id,omitempty"`
+	Decided bool      `json:"decided"`
+	Games   []Pairing `json:"games"`
+
+	slots [2]slot
+	ready bool
+	loser string
+	// eliminates is whether losing the match knocks the loser out.
+	eliminates bool
+	// reset is the second grand final, played only if the player from the
+	// losers bracket wins the first.
+	reset bool
+}
+
+// slot is where a player of a match comes from: a seed, or the winner or
+// loser of an earlier match.
+type slot struct {
+	seed  int
+	from  *Match
+	loser bool
+}
+
+// Bracket is a single or double elimination knockout as far as it has been
+// played.
+type Bracket struct {
+	Double   bool     `json:"double"`
+	Tiebreak Tiebreak `json:"tiebreak"`
+	Matches  []*Match `json:"matches"`
+	Champion string   `json:"champion_user_id,omitempty"`
+
+	seeds []Player
+}
+
+// KnockoutRounds is how many rounds a knockout of n players takes at most.
+// A double elimination final that the losers bracket wins is played again.
+func KnockoutRounds(n int, double bool) int {
+	k := bracketRounds(n)
+	if double {
+		return 2*k + 1
+	}
+	return k
+}
+
+// bracketRounds is how many rounds the winners bracket of n players has: a
+// bracket is a power of two, the top seeds getting byes.
+func bracketRounds(n int) int {
+	k := 1
+	for 1<<k < n {
+		k++
+	}
+	return k
+}
+
+// seedOrder lists the seeds of a bracket of size from top to bottom, such
+// that 1 meets size in the first round and the top two seeds could only
+// meet in the final.
+func seedOrder(size int) []int {
+	order := []int{1, 2}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// Knockout lays out the bracket of seeds, best first, and plays it out with
+// the games so far. A single elimination knockout has a winners bracket
+// only. In a double elimination one the losers of the winners bracket drop
+// into the losers bracket, whose winner meets the winners bracket's in the
+// final.
+func Knockout(seeds []Player, double bool, tiebreak Tiebreak, pairings []Pairing) *Bracket {
+	b := &Bracket{Double: double, Tiebreak: tiebreak, seeds: seeds}
+	k := bracketRounds(len(seeds))
+	size := 1 << k
+
+	add := func(bracket string, round int, a, c slot) *Match {
+		m := &Match{Bracket: bracket, Round: round, slots: [2]slot{a, c}, eliminates: bracket != WinnersBracket || !double}
+		b.Matches = append(b.Matches, m)
+		return m
+	}
+
+	order := seedOrder(size)
+	winners := make([][]*Match, k+1)
+	for i := 0; i < size/2; i++ {
+		winners[1] = append(winners[1], add(WinnersBracket, 1, slot{seed: order[2*i]}, slot{seed: order[2*i+1]}))
+	}
+	for r := 2; r <= k; r++ {
+		for i := 0; i < size>>r; i++ {
+			winners[r] = append(winners[r], add(WinnersBracket, r, slot{from: winners[r-1][2*i]}, slot{from: winners[r-1][2*i+1]}))
+		}
+	}
+
+	if double {
+		// Losers round i is played in round i+1. Odd ones pair the
+		// survivors among themselves, even ones play them against the
+		// losers dropping from the winners bracket, in reverse order
+		// every other time to put off rematches.
+		losers := make([][]*Match, 2*k-1)
+		champion := slot{from: winners[1][0], loser: true}
+		if k >= 2 {
+			for i := 0; i < size/4; i++ {
+				losers[1] = append(losers[1], add(LosersBracket, 2, slot{from: winners[1][2*i], loser: true}, slot{from: winners[1][2*i+1], loser: true}))
+			}
+			for j := 1; j <= k-1; j++ {
+				count := size >> (j + 1)
+				for i := 0; i < count; i++ {
+					drop := i
+					if j%2 == 1 {
+						drop = count - 1 - i
+					}
+					losers[2*j] = append(losers[2*j], add(LosersBracket, 2*j+1, slot{from: losers[2*j-1][i]}, slot{from: winners[j+1][drop], loser: true}))
+				}
+				if j <= k-2 {
+					for i := 0; i < size>>(j+2); i++ {
+						losers[2*j+1] = append(losers[2*j+1], add(LosersBracket, 2*j+2, slot{from: losers[2*j][2*i]}, slot{from: losers[2*j][2*i+1]}))
+					}
+				}
+			}
+			champion = slot{from: losers[2*k-2][0]}
+		}
+		final := add(FinalBracket, 2*k, slot{from: winners[k][0]}, champion)
+		reset := add(FinalBracket, 2*k+1, slot{from: final}, slot{from: final, loser: true})
+		reset.reset = true
+	}
+
+	sort.SliceStable(b.Matches, func(i, j int) bool { return b.Matches[i].Round < b.Matches[j].Round })
+	board := 0
+	for i, m := range b.Matches {
+		if i == 0 || b.Matches[i-1].Round != m.Round {
+			board = 0
+		}
+		board++
+		m.Board = board
+	}
+
+	b.play(pairings)
+	return b
+}
+
+// play decides the matches whose players are known from their games.
+// Matches come after those they depend on.
+func (b *Bracket) play(pairings []Pairing) {
+	games := make(map[[2]int][]Pairing)
+	for _, p := range pairings {
+		key := [2]int{p.Round, p.Board}
+		games[key] = append(games[key], p)
+	}
+
+	var final *Match
+	played := b.Matches[:0]
+	for _, m := range b.Matches {
+		if m.reset {
+			if !final.Decided || final.Winner != final.Player2 || final.Player1 == "" {
+				continue
+			}
+			m.ready, m.Player1, m.Player2 = true, final.Player1, final.Player2
+		} else {
+			m.ready = true
+			players := [2]string{}
+			for i, s := range m.slots {
+				switch {
+				case s.from == nil:
+					if s.seed <= len(b.seeds) {
+						players[i] = b.seeds[s.seed-1].ID
+					}
+				case !s.from.Decided:
+					m.ready = false
+				case s.loser:
+					players[i] = s.from.loser
+				default:
+					players[i] = s.from.Winner
+				}
+			}
+			m.Player1, m.Player2 = players[0], players[1]
+		}
+		played = append(played, m)
+		if m.Bracket == FinalBracket {
+			final = m
+		}
+		if !m.ready {
+			continue
+		}
+
+		m.Games = games[[2]int{m.Round, m.Board}]
+		sort.SliceStable(m.Games, func(i, j int) bool { return m.Games[i].Game < m.Games[j].Game })
+		if m.Player1 == "" || m.Player2 == "" {
+			m.Decided, m.Winner = true, m.Player1+m.Player2
+			continue
+		}
+		if winner, _ := b.Tiebreak.settle(m.Player1, m.Player2, m.Games); winner != "" {
+			m.Decided, m.Winner = true, winner
+			m.loser = m.Player1
+			if winner == m.Player1 {
+				m.loser = m.Player2
+			}
+			if m.Bracket == FinalBracket && !m.reset {
+				// Losing the first final only knocks out the player from
+				// the losers bracket.
+				m.eliminates = m.loser == m.Player2
+			}
+		}
+	}
+	b.Matches = played
+
+	last := b.Matches[len(b.Matches)-1]
+	if last.Decided && (last.reset || last.eliminates || !b.Double) {
+		b.Champion = last.Winner
+	}
+}
+
+// Finished reports whether the knockout has its champion.
+func (b *Bracket) Finished() bool {
+	return b.Champion != ""
+}
+
+// RoundDone reports whether every match of round is decided.
+func (b *Bracket) RoundDone(round int) bool {
+	for _, m := range b.Matches {
+		if m.Round == round && !m.Decided {
+			return false
+		}
+	}
+	return true
+}
+
+// NextGames are the games to start in round: the first game of every match
+// whose players are known, and the next tiebreak of every match whose last
+// game was drawn. The first player has White in odd games and Black in
+// even ones, so has draw odds in armageddon.
+func (b *Bracket) NextGames(round int) []Pairing {
+	var next []Pairing
+	for _, m := range b.Matches {
+		if m.Round != round || !m.ready || m.Decided {
+			continue
+		}
+		_, game := b.Tiebreak.settle(m.Player1, m.Player2, m.Games)
+		if game == 0 {
+			continue
+		}
+		white, black := m.Player1, m.Player2
+		if game%2 == 0 {
+			white, black = black, white
+		}
+		next = append(next, Pairing{Round: m.Round, Board: m.Board, Game: game, White: white, Black: black, Result: Pending})
+	}
+	return next
+}
+
+// KnockoutStanding is a player's place in a knockout: those knocked out
+// later rank higher, and players knocked out in the same round share a
+// rank.
+type KnockoutStanding struct {
+	Rank int `json:"rank"`
+	Player
+	Seed int `json:"seed"`
+	// Wins counts the matches won, byes aside.
+	Wins int `json:"wins"`
+	// EliminatedIn is the round the player was knocked out in, 0 while
+	// they are still in.
+	EliminatedIn int `json:"eliminated_in,omitempty"`
+}
+
+// Standings ranks the players of the bracket.
+func (b *Bracket) Standings() []KnockoutStanding {
+	standings := make([]KnockoutStanding, len(b.seeds))
+	index := make(map[string]int, len(b.seeds))
+	for i, p := range b.seeds {
+		standings[i] = KnockoutStanding{Player: p, Seed: i + 1}
+		index[p.ID] = i
+	}
+	for _, m := range b.Matches {
+		if !m.Decided || m.loser == "" {
+			continue
+		}
+		if i, ok := index[m.Winner]; ok {
+			standings[i].Wins++
+		}
+		if i, ok := index[m.loser]; ok && m.eliminates {
+			standings[i].EliminatedIn = m.Round
+		}
+	}
+
+	// still is how far a player got, those still in being ahead of all.
+	still := func(s KnockoutStanding) int {
+		if s.EliminatedIn == 0 {
+			return len(b.Matches) + 1
+		}
+		return s.EliminatedIn
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, c := standings[i], standings[j]
+		switch {
+		case still(a) != still(c):
+			return still(a) > still(c)
+		case a.Wins != c.Wins:
+			return a.Wins > c.Wins
+		default:
+			return a.Seed < c.Seed
+		}
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if i > 0 && still(standings[i]) == still(standings[i-1]) {
+			standings[i].Rank = standings[i-1].Rank
+		}
+	}
+	return standings
+}
@@ -0,0 +1,178 @@
+package tournament
+
+// matching is a perfect matching of a graph kept up to date while pairs are
+// fixed one by one. Edmonds' blossom algorithm finds augmenting paths in any
+// graph, so whether the players left can still all be paired is decided
+// exactly, in polynomial time, rather than by searching.
+type matching struct {
+	n   int
+	adj [][]bool
+	// match is each vertex's partner, or -1.
+	match []int
+	// fixed vertices have their partner settled and take no further part.
+	fixed []bool
+
+	// Scratch space of augment.
+	parent, base    []int
+	used, inBlossom []bool
+	queue           []int
+}
+
+func newMatching(n int) *matching {
+	m := &matching{
+		n:         n,
+		adj:       make([][]bool, n),
+		match:     make([]int, n),
+		fixed:     make([]bool, n),
+		parent:    make([]int, n),
+		base:      make([]int, n),
+		used:      make([]bool, n),
+		inBlossom: make([]bool, n),
+	}
+	for i := range m.adj {
+		m.adj[i] = make([]bool, n)
+		m.match[i] = -1
+	}
+	return m
+}
+
+func (m *matching) connect(a, b int) {
+	m.adj[a][b] = true
+	m.adj[b][a] = true
+}
+
+// perfect matches as many vertices as it can and reports whether that is
+// all of them.
+func (m *matching) perfect() bool {
+	for v := 0; v < m.n; v++ {
+		if m.match[v] == -1 && !m.augment(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// fix pairs a with b for good if the rest of the matching can be mended
+// around them, and reports whether it could. The matching must be perfect.
+func (m *matching) fix(a, b int) bool {
+	if !m.adj[a][b] {
+		return false
+	}
+	if m.match[a] == b {
+		m.fixed[a], m.fixed[b] = true, true
+		return true
+	}
+
+	saved := append([]int(nil), m.match...)
+	pa, pb := m.match[a], m.match[b]
+	m.match[pa], m.match[pb] = -1, -1
+	m.match[a], m.match[b] = b, a
+	m.fixed[a], m.fixed[b] = true, true
+	// The old partners are the only vertices left unmatched, so a path
+	// from one of them can only end at the other.
+	if m.augment(pa) {
+		return true
+	}
+	copy(m.match, saved)
+	m.fixed[a], m.fixed[b] = false, false
+	return false
+}
+
+// augment looks for an augmenting path from the unmatched vertex root and
+// flips it, reporting whether there was one.
+func (m *matching) augment(root int) bool {
+	for i := 0; i < m.n; i++ {
+		m.parent[i] = -1
+		m.base[i] = i
+		m.used[i] = false
+	}
+	m.used[root] = true
+	m.queue = append(m.queue[:0], root)
+
+	for len(m.queue) > 0 {
+		v := m.queue[0]
+		m.queue = m.queue[1:]
+		for to := 0; to < m.n; to++ {
+			if !m.adj[v][to] || m.fixed[to] || m.base[v] == m.base[to] || m.match[v] == to {
+				continue
+			}
+			if to == root || (m.match[to] != -1 && m.parent[m.match[to]] != -1) {
+				m.contract(v, to)
+				continue
+			}
+			if m.parent[to] != -1 {
+				continue
+			}
+			m.parent[to] = v
+			if m.match[to] == -1 {
+				m.flip(to)
+				return true
+			}
+			m.used[m.match[to]] = true
+			m.queue = append(m.queue, m.match[to])
+		}
+	}
+	return false
+}
+
+// contract shrinks the odd cycle closed by the edge from v to to into its
+// base, queueing the vertices it brings into the tree.
+func (m *matching) contract(v, to int) {
+	b := m.commonBase(v, to)
+	for i := range m.inBlossom {
+		m.inBlossom[i] = false
+	}
+	m.markPath(v, b, to)
+	m.markPath(to, b, v)
+	for i := 0; i < m.n; i++ {
+		if m.inBlossom[m.base[i]] {
+			m.base[i] = b
+			if !m.used[i] {
+				m.used[i] = true
+				m.queue = append(m.queue, i)
+			}
+		}
+	}
+}
+
+// commonBase is the base of the blossom where the tree paths of a and b
+// meet.
+func (m *matching) commonBase(a, b int) int {
+	seen := make([]bool, m.n)
+	for {
+		a = m.base[a]
+		seen[a] = true
+		if m.match[a] == -1 {
+			break
+		}
+		a = m.parent[m.match[a]]
+	}
+	for {
+		b = m.base[b]
+		if seen[b] {
+			return b
+		}
+		b = m.parent[m.match[b]]
+	}
+}
+
+func (m *matching) markPath(v, b, child int) {
+	for m.base[v] != b {
+		m.inBlossom[m.base[v]] = true
+		m.inBlossom[m.base[m.match[v]]] = true
+		m.parent[v] = child
+		child = m.match[v]
+		v = m.parent[m.match[v]]
+	}
+}
+
+// flip swaps matched and unmatched edges along the path ending at v.
+func (m *matching) flip(v int) {
+	for v != -1 {
+		pv := m.parent[v]
+		next := m.match[pv]
+		m.match[v] = pv
+		m.match[pv] = v
+		v = next
+	}
+}
@@ -0,0 +1,71 @@
+package tournament
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// perfectByHand reports whether the vertices left in g can all be paired,
+// by trying every partner of the first one.
+func perfectByHand(adj [][]bool, left []int) bool {
+	if len(left) == 0 {
+		return true
+	}
+	for i := 1; i < len(left); i++ {
+		if !adj[left[0]][left[i]] {
+			continue
+		}
+		rest := append(append([]int(nil), left[1:i]...), left[i+1:]...)
+		if perfectByHand(adj, rest) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMatchingPerfect(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 2000; trial++ {
+		n := 2 * (1 + rng.Intn(6))
+		m := newMatching(n)
+		density := rng.Float64()
+		for a := 0; a < n; a++ {
+			for b := a + 1; b < n; b++ {
+				if rng.Float64() < density {
+					m.connect(a, b)
+				}
+			}
+		}
+		left := make([]int, n)
+		for i := range left {
+			left[i] = i
+		}
+		want := perfectByHand(m.adj, left)
+		if got := m.perfect(); got != want {
+			t.Fatalf("trial %d: perfect() = %v, want %v", trial, got, want)
+		}
+		if !want {
+			continue
+		}
+		for v, u := range m.match {
+			if u == -1 || m.match[u] != v || !m.adj[v][u] {
+				t.Fatalf("trial %d: %d is matched to %d", trial, v, u)
+			}
+		}
+
+		// Fixing a pair must succeed exactly when the rest can be paired.
+		a, b := rng.Intn(n), rng.Intn(n)
+		if a == b || !m.adj[a][b] {
+			continue
+		}
+		var rest []int
+		for v := 0; v < n; v++ {
+			if v != a && v != b {
+				rest = append(rest, v)
+			}
+		}
+		if got, want := m.fix(a, b), perfectByHand(m.adj, rest); got != want {
+			t.Fatalf("trial %d: fix(%d, %d) = %v, want %v", trial, a, b, got, want)
+		}
+	}
+}
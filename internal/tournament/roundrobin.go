@@ -0,0 +1,119 @@
+package tournament
+
+// RoundRobinRounds is how many rounds it takes n players to all meet.
+func RoundRobinRounds(n int) int {
+	if n%2 == 1 {
+		n++
+	}
+	return n - 1
+}
+
+// RoundRobin pairs round of a round robin by the Berger tables. field is
+// every player in seed order. With an odd number of players the one who
+// would meet the missing last seed sits the round out.
+//
+// The last seed stays put while the others rotate: in round r it meets the
+// seed i with 2i = r+1 modulo n-1, Black in odd rounds and White in even
+// ones, and the other boards pair i+k with i-k. Between two seeds other
+// than the last the lower one has White when their sum is odd.
+func RoundRobin(round int, field []Player) []Pairing {
+	n := len(field)
+	if n%2 == 1 {
+		n++
+	}
+	m := n - 1
+	wrap := func(seed int) int {
+		return ((seed-1)%m+m)%m + 1
+	}
+
+	fixed := 1
+	for seed := 1; seed <= m; seed++ {
+		if (2*seed)%m == (round+1)%m {
+			fixed = seed
+			break
+		}
+	}
+
+	boards := [][2]int{{fixed, n}}
+	if round%2 == 0 {
+		boards[0] = [2]int{n, fixed}
+	}
+	for k := 1; k < n/2; k++ {
+		a, b := wrap(fixed+k), wrap(fixed-k)
+		w, bl := min(a, b), max(a, b)
+		if (a+b)%2 == 0 {
+			w, bl = bl, w
+		}
+		boards = append(boards, [2]int{w, bl})
+	}
+
+	pairings := make([]Pairing, 0, len(boards))
+	for _, seeds := range boards {
+		if seeds[0] > len(field) || seeds[1] > len(field) {
+			continue
+		}
+		pairings = append(pairings, Pairing{
+			Round:  round,
+			Board:  len(pairings) + 1,
+			Game:   1,
+			White:  field[seeds[0]-1].ID,
+			Black:  field[seeds[1]-1].ID,
+			Result: Pending,
+		})
+	}
+	return pairings
+}
+
+// CrosstableRow is a player's line of a round robin crosstable. Results has
+// their result against the player of each row: "1", "½" or "0" for a game,
+// "+" or "-" for a forfeit, "*" while the game is played, "" before they
+// have met and "x" against themselves.
+type CrosstableRow struct {
+	Standing
+	Results []string `json:"results"`
+}
+
+// Crosstable lays out a round robin in the order of its standings.
+func Crosstable(field []Player, pairings []Pairing) []CrosstableRow {
+	standings := Standings(field, pairings)
+	rows := make([]CrosstableRow, len(standings))
+	index := make(map[string]int, len(standings))
+	for i, s := range standings {
+		index[s.ID] = i
+		rows[i] = CrosstableRow{Standing: s, Results: make([]string, len(standings))}
+		rows[i].Results[i] = "x"
+	}
+
+	for i := range pairings {
+		p := &pairings[i]
+		w, okW := index[p.White]
+		b, okB := index[p.Black]
+		if !okW || !okB {
+			continue
+		}
+		rows[w].Results[b] = p.cell(p.White)
+		rows[b].Results[w] = p.cell(p.Black)
+	}
+	return rows
+}
+
+func (p *Pairing) cell(playerID string) string {
+	if !p.Finished() {
+		return "*"
+	}
+	points := p.Points(playerID)
+	if p.GameID == "" || p.Result == DoubleForfeit {
+		if points == 1 {
+			return "+"
+		}
+		return "-"
+	}
+	switch points {
+	case 1:
+		return "1"
+	case 0.5:
+		return "½"
+	default:
+		return "0"
+	}
+}
@@ -0,0 +1,65 @@
+package tournament
+
+import "sort"
+
+// Standing is a player's place in a tournament.
+type Standing struct {
+	Rank int `json:"rank"`
+	Player
+	Score           float64 `json:"score"`
+	Buchholz        float64 `json:"buchholz"`
+	SonnebornBerger float64 `json:"sonneborn_berger"`
+	Played          int     `json:"played"`
+}
+
+// Standings ranks players by score, then Buchholz (the sum of their
+// opponents' scores), then Sonneborn-Berger (the scores of the opponents
+// they beat plus half the scores of those they drew), then rating. Only
+// finished pairings count, and byes and double forfeits add nothing to either
+// tie-break.
+func Standings(players []Player, pairings []Pairing) []Standing {
+	scores := Scores(pairings)
+
+	standings := make([]Standing, 0, len(players))
+	index := make(map[string]int, len(players))
+	for _, p := range players {
+		index[p.ID] = len(standings)
+		standings = append(standings, Standing{Player: p, Score: scores[p.ID]})
+	}
+
+	for i := range pairings {
+		p := &pairings[i]
+		if !p.Finished() || p.Result == Bye || p.Result == DoubleForfeit {
+			continue
+		}
+		for _, id := range []string{p.White, p.Black} {
+			j, ok := index[id]
+			if !ok {
+				continue
+			}
+			s := &standings[j]
+			opponentScore := scores[p.Opponent(id)]
+			s.Played++
+			s.Buchholz += opponentScore
+			s.SonnebornBerger += p.Points(id) * opponentScore
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.Buchholz != b.Buchholz:
+			return a.Buchholz > b.Buchholz
+		case a.SonnebornBerger != b.SonnebornBerger:
+			return a.SonnebornBerger > b.SonnebornBerger
+		default:
+			return a.Rating > b.Rating
+		}
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
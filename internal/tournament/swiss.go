@@ -0,0 +1,302 @@
+package tournament
+
+import "sort"
+
+const (
+	white = 1
+	black = -1
+)
+
+// entrant is a player together with their record in the tournament so far.
+type entrant struct {
+	Player
+	score     float64
+	opponents map[string]bool
+	// colors are the colors the player had, oldest first. Byes have none.
+	colors []int
+	hadBye bool
+	// due and strength cache colorDue once the record is complete.
+	due, strength int
+}
+
+// colorDue is the color e should get next and how strongly: 2 when it is
+// absolute (two more games with one color than the other, or the same color
+// twice running), 1 when e has had the other color more often and 0 when e
+// only alternates. Before e's first game there is no color due.
+func (e *entrant) colorDue() (color, strength int) {
+	n := len(e.colors)
+	if n == 0 {
+		return 0, 0
+	}
+	diff := 0
+	for _, c := range e.colors {
+		diff += c
+	}
+	last := e.colors[n-1]
+	switch {
+	case diff >= 2:
+		return black, 2
+	case diff <= -2:
+		return white, 2
+	case n >= 2 && e.colors[n-2] == last:
+		return -last, 2
+	case diff > 0:
+		return black, 1
+	case diff < 0:
+		return white, 1
+	default:
+		return -last, 0
+	}
+}
+
+// PairSwiss pairs round of a Swiss tournament by the Dutch system. players
+// are the players taking part in the round and history every pairing of the
+// earlier rounds.
+//
+// Players are ranked by score and then rating. Each score group is split in
+// half and its top half paired in order against its bottom half; players
+// who cannot be paired in their group float down to the next one. Nobody
+// meets the same opponent twice and nobody gets a color they must not have,
+// unless the round cannot be paired otherwise: colors give way first, and
+// only then are there rematches, each player's other candidates coming
+// before them. With an odd number of players the lowest ranked player who
+// has not had a bye gets one. Boards are numbered from the top, the bye
+// last.
+func PairSwiss(round int, players []Player, history []Pairing) []Pairing {
+	bye, pairs := pairRanked(rank(players, history))
+
+	result := make([]Pairing, 0, len(pairs)+1)
+	for i, pair := range pairs {
+		board := i + 1
+		w, b := allocateColors(pair[0], pair[1], board)
+		result = append(result, Pairing{Round: round, Board: board, Game: 1, White: w.ID, Black: b.ID, Result: Pending})
+	}
+	if bye != nil {
+		result = append(result, Pairing{Round: round, Board: len(result) + 1, Game: 1, White: bye.ID, Result: Bye})
+	}
+	return result
+}
+
+// rank builds the players' records from history and sorts them by score,
+// then rating.
+func rank(players []Player, history []Pairing) []*entrant {
+	ranked := make([]*entrant, 0, len(players))
+	byID := make(map[string]*entrant, len(players))
+	for _, p := range players {
+		e := &entrant{Player: p, opponents: make(map[string]bool)}
+		ranked = append(ranked, e)
+		byID[p.ID] = e
+	}
+
+	for i := range history {
+		p := &history[i]
+		// Players who both failed to turn up did not meet and had no colors.
+		if !p.Finished() || p.Result == DoubleForfeit {
+			continue
+		}
+		if p.Result == Bye {
+			if e := byID[p.White]; e != nil {
+				e.hadBye = true
+				e.score += p.Points(p.White)
+			}
+			continue
+		}
+		for _, side := range []struct {
+			id, opponent string
+			color        int
+		}{{p.White, p.Black, white}, {p.Black, p.White, black}} {
+			if e := byID[side.id]; e != nil {
+				e.opponents[side.opponent] = true
+				e.colors = append(e.colors, side.color)
+				e.score += p.Points(side.id)
+			}
+		}
+	}
+
+	for _, e := range ranked {
+		e.due, e.strength = e.colorDue()
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		return a.ID < b.ID
+	})
+	return ranked
+}
+
+// pairingRules are the restrictions on a round's pairing, from the
+// strictest down to the last resort, which pairs anyone.
+var pairingRules = []struct{ colors, rematches bool }{
+	{colors: true},
+	{},
+	{colors: true, rematches: true},
+	{rematches: true},
+}
+
+// pairRanked pairs ranked under the strictest rules it can, first choosing
+// who sits out if their number is odd: the lowest ranked player without a
+// bye for whom the others can still be paired, or failing that the lowest
+// ranked player at all.
+func pairRanked(ranked []*entrant) (*entrant, [][2]*entrant) {
+	for _, rules := range pairingRules {
+		for _, secondBye := range []bool{false, true} {
+			if secondBye && len(ranked)%2 == 0 {
+				break
+			}
+			m := buildMatching(ranked, rules.colors, rules.rematches, secondBye)
+			if m.perfect() {
+				return extractPairs(ranked, m, rules.rematches)
+			}
+		}
+	}
+	// The last rules link everyone to everyone, so this is not reached.
+	return nil, nil
+}
+
+// buildMatching links every two players who may meet, and, if their number
+// is odd, every player who may have the bye to an extra vertex standing for
+// it.
+func buildMatching(ranked []*entrant, colors, rematches, secondBye bool) *matching {
+	n := len(ranked)
+	m := newMatching(n + n%2)
+	for i, a := range ranked {
+		for j := i + 1; j < n; j++ {
+			b := ranked[j]
+			if (rematches || !a.opponents[b.ID]) && (!colors || clash(a, b) < 3) {
+				m.connect(i, j)
+			}
+		}
+		if n%2 == 1 && (secondBye || !a.hadBye) {
+			m.connect(i, n)
+		}
+	}
+	return m
+}
+
+// extractPairs reads the Dutch pairing off a perfect matching of ranked:
+// the bye first, from the bottom up, and then each top ranked player with
+// the first of its candidates that leaves the rest pairable. Where
+// rematches are allowed, candidates who would not be one still come first.
+func extractPairs(ranked []*entrant, m *matching, rematches bool) (*entrant, [][2]*entrant) {
+	n := len(ranked)
+	var bye *entrant
+	if n%2 == 1 {
+		for i := n - 1; i >= 0; i-- {
+			if m.fix(i, n) {
+				bye = ranked[i]
+				break
+			}
+		}
+	}
+
+	var pairs [][2]*entrant
+	for u := 0; u < n; u++ {
+		if m.fixed[u] {
+			continue
+		}
+		var rest []*entrant
+		var index []int
+		for i := u; i < n; i++ {
+			if !m.fixed[i] {
+				rest = append(rest, ranked[i])
+				index = append(index, i)
+			}
+		}
+		order := candidates(rest)
+		if rematches {
+			top := ranked[u]
+			sort.SliceStable(order, func(i, j int) bool {
+				return !top.opponents[rest[order[i]].ID] && top.opponents[rest[order[j]].ID]
+			})
+		}
+		for _, c := range order {
+			if m.fix(u, index[c]) {
+				pairs = append(pairs, [2]*entrant{ranked[u], rest[c]})
+				break
+			}
+		}
+	}
+	return bye, pairs
+}
+
+// candidates orders the possible opponents of the top ranked player the
+// Dutch way. Its natural opponent is the first player of the bottom half
+// of its score group, followed by the rest of the bottom half, then the
+// top half from the bottom up, and finally everyone in the groups below.
+// Within each score group, players whose colors fit come first.
+func candidates(ranked []*entrant) []int {
+	group := 1
+	for group < len(ranked) && ranked[group].score == ranked[0].score {
+		group++
+	}
+	half := group / 2
+
+	order := make([]int, 0, len(ranked)-1)
+	if half > 0 {
+		for i := half; i < group; i++ {
+			order = append(order, i)
+		}
+		for i := half - 1; i >= 1; i-- {
+			order = append(order, i)
+		}
+	}
+	for i := group; i < len(ranked); i++ {
+		order = append(order, i)
+	}
+
+	top := ranked[0]
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := ranked[order[i]], ranked[order[j]]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return clash(top, a) < clash(top, b)
+	})
+	return order
+}
+
+// clash is how badly the colors of a and b fit together: 0 when they are
+// due different colors or either has none due, and otherwise one more than
+// the weaker of their claims, so 3 when both must have the same color.
+func clash(a, b *entrant) int {
+	if a.due == 0 || a.due != b.due {
+		return 0
+	}
+	return 1 + min(a.strength, b.strength)
+}
+
+// allocateColors decides which of a and b plays White, a being the higher
+// ranked. Each gets the color due if they can; otherwise the stronger claim
+// wins, and between equal claims the higher ranked player. In the first
+// round the top player of each odd board has White.
+func allocateColors(a, b *entrant, board int) (w, bl *entrant) {
+	ca, sa := a.due, a.strength
+	cb, sb := b.due, b.strength
+	switch {
+	case ca == 0 && cb == 0:
+		if board%2 == 1 {
+			return a, b
+		}
+		return b, a
+	case ca != cb:
+		if ca == white || cb == black {
+			return a, b
+		}
+		return b, a
+	}
+
+	winner, loser := a, b
+	if sb > sa {
+		winner, loser = b, a
+	}
+	if ca == white {
+		return winner, loser
+	}
+	return loser, winner
+}
@@ -0,0 +1,351 @@
+package tournament
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func players(n int) []Player {
+	ps := make([]Player, n)
+	for i := range ps {
+		ps[i] = Player{ID: fmt.Sprintf("p%02d", i+1), Rating: float64(2000 - 10*i)}
+	}
+	return ps
+}
+
+func game(round int, w, b string, result Result) Pairing {
+	return Pairing{Round: round, Game: 1, White: w, Black: b, Result: result}
+}
+
+func find(pairings []Pairing, playerID string) *Pairing {
+	for i := range pairings {
+		if pairings[i].White == playerID || pairings[i].Black == playerID {
+			return &pairings[i]
+		}
+	}
+	return nil
+}
+
+func TestPairSwissColors(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []Pairing
+		// white and black are the colors expected for some of the players.
+		white, black []string
+	}{
+		{
+			name:  "first round alternates by board",
+			white: []string{"p01", "p04"},
+			black: []string{"p02", "p03"},
+		},
+		{
+			name: "colors alternate",
+			history: []Pairing{
+				game(1, "p01", "p03", Drawn),
+				game(1, "p04", "p02", Drawn),
+			},
+			white: []string{"p02", "p03"},
+			black: []string{"p01", "p04"},
+		},
+		{
+			name: "no third white running",
+			history: []Pairing{
+				game(1, "p01", "p02", WhiteWon),
+				game(1, "p03", "p04", WhiteWon),
+				game(2, "p01", "p03", Drawn),
+				game(2, "p04", "p02", Drawn),
+			},
+			black: []string{"p01"},
+			white: []string{"p04"},
+		},
+		{
+			name: "absolute colors keep players apart",
+			// p01 and p02 both had White twice and lead the field, but
+			// must not meet: one of them would get White a third time, and
+			// the round can be paired without that or a rematch.
+			history: []Pairing{
+				game(1, "p01", "p05", WhiteWon),
+				game(1, "p02", "p06", WhiteWon),
+				game(1, "p07", "p03", Drawn),
+				game(1, "p08", "p04", Drawn),
+				game(2, "p01", "p07", WhiteWon),
+				game(2, "p02", "p08", WhiteWon),
+				game(2, "p05", "p03", Drawn),
+				game(2, "p06", "p04", Drawn),
+			},
+			black: []string{"p01", "p02"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 4
+			if len(tt.history) > 4 {
+				n = 8
+			}
+			pairings := PairSwiss(len(tt.history)/2+1, players(n), tt.history)
+			for _, id := range tt.white {
+				if p := find(pairings, id); p == nil || p.White != id {
+					t.Errorf("%s: got %+v, want White", id, p)
+				}
+			}
+			for _, id := range tt.black {
+				if p := find(pairings, id); p == nil || p.Black != id {
+					t.Errorf("%s: got %+v, want Black", id, p)
+				}
+			}
+		})
+	}
+}
+
+func TestPairSwissBye(t *testing.T) {
+	tests := []struct {
+		name    string
+		players int
+		history []Pairing
+		want    string
+	}{
+		{
+			name:    "lowest ranked player",
+			players: 5,
+			want:    "p05",
+		},
+		{
+			name:    "no second bye",
+			players: 5,
+			history: []Pairing{
+				game(1, "p01", "p02", Drawn),
+				game(1, "p03", "p04", Drawn),
+				{Round: 1, Game: 1, White: "p05", Result: Bye},
+			},
+			want: "p04",
+		},
+		{
+			name:    "lowest score before lowest rating",
+			players: 5,
+			history: []Pairing{
+				game(1, "p01", "p02", Drawn),
+				game(1, "p04", "p03", WhiteWon),
+				{Round: 1, Game: 1, White: "p05", Result: Bye},
+			},
+			want: "p03",
+		},
+		{
+			name:    "even field",
+			players: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairings := PairSwiss(2, players(tt.players), tt.history)
+			got := ""
+			for _, p := range pairings {
+				if p.Result == Bye {
+					if got != "" {
+						t.Fatalf("two byes: %+v", pairings)
+					}
+					got = p.White
+				}
+			}
+			if got != tt.want {
+				t.Errorf("bye went to %q, want %q", got, tt.want)
+			}
+			if want := (tt.players + 1) / 2; len(pairings) != want {
+				t.Errorf("got %d boards, want %d", len(pairings), want)
+			}
+		})
+	}
+}
+
+func TestPairSwissNoRepeats(t *testing.T) {
+	tests := []struct {
+		name    string
+		players int
+		history []Pairing
+	}{
+		{
+			name:    "natural opponents already met",
+			players: 4,
+			history: []Pairing{
+				game(1, "p01", "p03", Drawn),
+				game(1, "p04", "p02", Drawn),
+			},
+		},
+		{
+			name:    "leaders already met",
+			players: 6,
+			history: []Pairing{
+				game(1, "p01", "p04", WhiteWon),
+				game(1, "p05", "p02", BlackWon),
+				game(1, "p03", "p06", Drawn),
+				game(2, "p02", "p01", Drawn),
+				game(2, "p04", "p06", WhiteWon),
+				game(2, "p03", "p05", Drawn),
+			},
+		},
+		{
+			name: "absolute colors give way",
+			// Meeting again is the only way to keep p01 and p02, who both
+			// had White twice, apart.
+			players: 4,
+			history: []Pairing{
+				game(1, "p01", "p03", WhiteWon),
+				game(1, "p02", "p04", WhiteWon),
+				game(2, "p01", "p04", WhiteWon),
+				game(2, "p02", "p03", WhiteWon),
+			},
+		},
+		{
+			name:    "bye player floats",
+			players: 5,
+			history: []Pairing{
+				game(1, "p01", "p03", WhiteWon),
+				game(1, "p04", "p02", BlackWon),
+				{Round: 1, Game: 1, White: "p05", Result: Bye},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			met := make(map[[2]string]bool)
+			for _, p := range tt.history {
+				met[[2]string{p.White, p.Black}] = true
+				met[[2]string{p.Black, p.White}] = true
+			}
+			for _, p := range PairSwiss(3, players(tt.players), tt.history) {
+				if p.Result != Bye && met[[2]string{p.White, p.Black}] {
+					t.Errorf("%s and %s meet again", p.White, p.Black)
+				}
+			}
+		})
+	}
+}
+
+// TestPairSwissEvent plays whole events with random results and checks
+// that every round is paired without rematches and without anyone getting
+// a color they must not have.
+func TestPairSwissEvent(t *testing.T) {
+	tests := []struct {
+		players, rounds int
+	}{
+		{8, 5},
+		{15, 6},
+		{40, 9},
+		{101, 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players %d rounds", tt.players, tt.rounds), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(int64(tt.players)))
+			field := players(tt.players)
+			var history []Pairing
+			for round := 1; round <= tt.rounds; round++ {
+				pairings := PairSwiss(round, field, history)
+				for i := range pairings {
+					p := &pairings[i]
+					if p.Result == Bye {
+						continue
+					}
+					switch r := rng.Float64(); {
+					case r < 0.4:
+						p.Result = WhiteWon
+					case r < 0.7:
+						p.Result = BlackWon
+					default:
+						p.Result = Drawn
+					}
+				}
+				history = append(history, pairings...)
+			}
+
+			met := make(map[[2]string]bool)
+			byes := make(map[string]int)
+			colors := make(map[string][]int)
+			for _, p := range history {
+				if p.Result == Bye {
+					byes[p.White]++
+					continue
+				}
+				if met[[2]string{p.White, p.Black}] {
+					t.Errorf("round %d: %s and %s meet again", p.Round, p.White, p.Black)
+				}
+				met[[2]string{p.White, p.Black}] = true
+				met[[2]string{p.Black, p.White}] = true
+				colors[p.White] = append(colors[p.White], white)
+				colors[p.Black] = append(colors[p.Black], black)
+			}
+			for id, n := range byes {
+				if n > 1 {
+					t.Errorf("%s had %d byes", id, n)
+				}
+			}
+			for id, cs := range colors {
+				diff := 0
+				for i, c := range cs {
+					diff += c
+					if diff > 2 || diff < -2 {
+						t.Errorf("%s is %d games off color balance after %v", id, diff, cs[:i+1])
+						break
+					}
+					if i >= 2 && cs[i] == cs[i-1] && cs[i] == cs[i-2] {
+						t.Errorf("%s had the same color three times running: %v", id, cs[:i+1])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPairSwissLongEvent plays more rounds than an odd field has opponents,
+// so that later rounds need rematches and second byes, and checks that each
+// round is still paired quickly and completely.
+func TestPairSwissLongEvent(t *testing.T) {
+	for _, n := range []int{17, 19, 21} {
+		t.Run(fmt.Sprintf("%d players", n), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(int64(n)))
+			field := players(n)
+			var history []Pairing
+			for round := 1; round <= 30; round++ {
+				start := time.Now()
+				pairings := PairSwiss(round, field, history)
+				if elapsed := time.Since(start); elapsed > time.Second {
+					t.Fatalf("round %d took %s", round, elapsed)
+				}
+
+				seen := make(map[string]bool)
+				for i := range pairings {
+					p := &pairings[i]
+					for _, id := range []string{p.White, p.Black} {
+						if id == "" {
+							continue
+						}
+						if seen[id] {
+							t.Fatalf("round %d: %s paired twice", round, id)
+						}
+						seen[id] = true
+					}
+					if p.Result == Bye {
+						continue
+					}
+					switch r := rng.Float64(); {
+					case r < 0.4:
+						p.Result = WhiteWon
+					case r < 0.7:
+						p.Result = BlackWon
+					default:
+						p.Result = Drawn
+					}
+				}
+				if len(seen) != n {
+					t.Fatalf("round %d: %d of %d players paired", round, len(seen), n)
+				}
+				history = append(history, pairings...)
+			}
+		})
+	}
+}
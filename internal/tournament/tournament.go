@@ -0,0 +1,95 @@
+package tournament
+
+// Result is the result of a pairing, from White's side.
+type Result string
+
+const (
+	WhiteWon Result = "1-0"
+	BlackWon Result = "0-1"
+	Drawn    Result = "1/2-1/2"
+	Pending  Result = "*"
+
+	// Bye is the result of a player left without an opponent. It is worth
+	// a win.
+	Bye Result = "bye"
+
+	// DoubleForfeit is the result of a game neither player turned up for.
+	// It is worth nothing to either.
+	DoubleForfeit Result = "0-0"
+)
+
+// Player is an entrant as pairing and standings see them. Rating is their
+// rating when they registered and seeds them.
+type Player struct {
+	ID     string  `json:"user_id"`
+	Rating float64 `json:"rating"`
+}
+
+// Pairing is one board of a round. Black is empty for a bye. In an arena
+// every game is a board of a single round, numbered in the order the games
+// started, and either player may have gone berserk. A knockout match is one
+// board, and Game numbers its games, the tiebreaks after the first. A
+// forfeit has a result but no GameID.
+type Pairing struct {
+	Round        int    `json:"round"`
+	Board        int    `json:"board"`
+	Game         int    `json:"game"`
+	White        string `json:"white_user_id"`
+	Black        string `json:"black_user_id,omitempty"`
+	GameID       string `json:"game_id,omitempty"`
+	Result       Result `json:"result"`
+	WhiteBerserk bool   `json:"white_berserk,omitempty"`
+	BlackBerserk bool   `json:"black_berserk,omitempty"`
+}
+
+// Finished reports whether the pairing has a result.
+func (p *Pairing) Finished() bool {
+	return p.Result != Pending && p.Result != ""
+}
+
+// Points is what the pairing scored for playerID.
+func (p *Pairing) Points(playerID string) float64 {
+	switch {
+	case p.Result == Bye:
+		return 1
+	case p.Result == Drawn:
+		return 0.5
+	case p.Result == WhiteWon && playerID == p.White,
+		p.Result == BlackWon && playerID == p.Black:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Berserked reports whether playerID went berserk in the pairing.
+func (p *Pairing) Berserked(playerID string) bool {
+	if playerID == p.White {
+		return p.WhiteBerserk
+	}
+	return playerID == p.Black && p.BlackBerserk
+}
+
+// Opponent is who playerID played in the pairing, or "" for a bye.
+func (p *Pairing) Opponent(playerID string) string {
+	if playerID == p.White {
+		return p.Black
+	}
+	return p.White
+}
+
+// Scores totals every finished pairing by player.
+func Scores(pairings []Pairing) map[string]float64 {
+	scores := make(map[string]float64)
+	for i := range pairings {
+		p := &pairings[i]
+		if !p.Finished() {
+			continue
+		}
+		scores[p.White] += p.Points(p.White)
+		if p.Black != "" {
+			scores[p.Black] += p.Points(p.Black)
+		}
+	}
+	return scores
+}
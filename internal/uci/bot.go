@@ -0,0 +1,45 @@
+package uci
+
+import (
+	"context"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// Bot plays moves chosen by a pooled engine. It satisfies the GameManager's
+// Bot interface.
+type Bot struct {
+	pool   *Pool
+	limits Limits
+}
+
+func NewBot(pool *Pool, limits Limits) *Bot {
+	return &Bot{pool: pool, limits: limits}
+}
+
+func (b *Bot) BestMove(ctx context.Context, board *chess.Game) (*chess.Move, error) {
+	limits := b.limits
+	// Leave the engine room to answer stop before the caller gives up.
+	if deadline, ok := ctx.Deadline(); ok {
+		if budget := time.Until(deadline) - stopGrace/2; limits.MoveTime == 0 || budget < limits.MoveTime {
+			limits.MoveTime = max(budget, time.Millisecond)
+		}
+	}
+
+	result, err := b.pool.Search(ctx, PositionOf(board), limits)
+	if err != nil {
+		return nil, err
+	}
+	return chess.UCINotation{}.Decode(board.Position(), result.BestMove)
+}
+
+// PositionOf describes the current position of board as its starting FEN
+// followed by the moves played.
+func PositionOf(board *chess.Game) Position {
+	pos := Position{FEN: board.Positions()[0].String()}
+	for _, mv := range board.Moves() {
+		pos.Moves = append(pos.Moves, mv.String())
+	}
+	return pos
+}
@@ -0,0 +1,281 @@
+// Package uci runs external chess engines that speak the Universal Chess
+// Interface over stdin and stdout.
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEngineExited = errors.New("engine process exited")
+	ErrTimeout      = errors.New("engine did not answer in time")
+)
+
+const (
+	// handshakeTimeout bounds uci/uciok and isready/readyok exchanges.
+	handshakeTimeout = 10 * time.Second
+	// stopGrace is how long an engine gets to answer stop with bestmove
+	// before it is considered hung and killed.
+	stopGrace = time.Second
+)
+
+// Position is what the engine should search: a FEN, or the standard start
+// position when FEN is empty, followed by moves in UCI notation.
+type Position struct {
+	FEN   string
+	Moves []string
+}
+
+// Limits bounds a search. Zero fields are not sent; with no limits at all the
+// search runs until the context is done.
+type Limits struct {
+	MoveTime time.Duration
+	Depth    int
+	Nodes    int
+}
+
+type Result struct {
+	BestMove string
+	Ponder   string
+	// Info is the last info line with a score and principal variation.
+	Info Info
+}
+
+// Engine is one running engine process. It is not safe for concurrent use;
+// Pool hands each engine to one caller at a time.
+type Engine struct {
+	Name string
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+
+	// broken is set when the process can no longer be trusted, e.g. it died
+	// or ignored stop. Pool discards broken engines.
+	broken bool
+}
+
+// Start launches the engine at path, completes the UCI handshake and applies
+// options with setoption.
+func Start(path string, args []string, options map[string]string) (*Engine, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start engine: %w", err)
+	}
+
+	e := &Engine{
+		cmd:   cmd,
+		stdin: stdin,
+		lines: make(chan string, 64),
+	}
+	go e.readLines(stdout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	if err := e.send("uci"); err != nil {
+		e.Close()
+		return nil, err
+	}
+	for {
+		line, err := e.readLine(ctx)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			e.Name = strings.TrimSpace(name)
+		}
+		if line == "uciok" {
+			break
+		}
+	}
+
+	for name, value := range options {
+		if err := e.send("setoption name " + name + " value " + value); err != nil {
+			e.Close()
+			return nil, err
+		}
+	}
+	if err := e.ready(ctx); err != nil {
+		e.Close()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// NewGame tells the engine that the next search belongs to a different game.
+func (e *Engine) NewGame() error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	return e.ready(ctx)
+}
+
+// Search runs go on pos until the engine reports bestmove. If ctx is done
+// first the engine is told to stop and its best move so far is returned.
+func (e *Engine) Search(ctx context.Context, pos Position, limits Limits) (*Result, error) {
+	if err := e.send(positionCommand(pos)); err != nil {
+		return nil, err
+	}
+	if err := e.send(goCommand(limits)); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	stopped := false
+	for {
+		line, err := e.readLine(ctx)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			if stopped {
+				e.broken = true
+				return nil, ErrTimeout
+			}
+			// Give the engine a moment to answer stop with its best move.
+			stopped = true
+			if err := e.send("stop"); err != nil {
+				return nil, err
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(context.Background(), stopGrace)
+			defer cancel()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "info":
+			if info, ok := parseInfo(fields[1:]); ok {
+				result.Info = info
+			}
+		case "bestmove":
+			if len(fields) > 1 {
+				result.BestMove = fields[1]
+			}
+			if len(fields) > 3 && fields[2] == "ponder" {
+				result.Ponder = fields[3]
+			}
+			return result, nil
+		}
+	}
+}
+
+// Close quits the engine and, if it does not exit promptly, kills it.
+func (e *Engine) Close() error {
+	e.broken = true
+	e.send("quit")
+	e.stdin.Close()
+
+	// Drain output nobody is waiting for so the reader can finish.
+	go func() {
+		for range e.lines {
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- e.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(stopGrace):
+		e.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+func (e *Engine) ready(ctx context.Context) error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	for {
+		line, err := e.readLine(ctx)
+		if err != nil {
+			return err
+		}
+		if line == "readyok" {
+			return nil
+		}
+	}
+}
+
+func (e *Engine) send(command string) error {
+	if _, err := io.WriteString(e.stdin, command+"\n"); err != nil {
+		e.broken = true
+		return ErrEngineExited
+	}
+	return nil
+}
+
+func (e *Engine) readLine(ctx context.Context) (string, error) {
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			e.broken = true
+			return "", ErrEngineExited
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (e *Engine) readLines(r io.Reader) {
+	defer close(e.lines)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.lines <- strings.TrimSpace(scanner.Text())
+	}
+}
+
+func positionCommand(pos Position) string {
+	cmd := "position startpos"
+	if pos.FEN != "" {
+		cmd = "position fen " + pos.FEN
+	}
+	if len(pos.Moves) > 0 {
+		cmd += " moves " + strings.Join(pos.Moves, " ")
+	}
+	return cmd
+}
+
+func goCommand(limits Limits) string {
+	cmd := "go"
+	if limits.MoveTime > 0 {
+		cmd += " movetime " + strconv.FormatInt(limits.MoveTime.Milliseconds(), 10)
+	}
+	if limits.Depth > 0 {
+		cmd += " depth " + strconv.Itoa(limits.Depth)
+	}
+	if limits.Nodes > 0 {
+		cmd += " nodes " + strconv.Itoa(limits.Nodes)
+	}
+	if cmd == "go" {
+		cmd += " infinite"
+	}
+	return cmd
+}
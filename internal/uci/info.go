@@ -0,0 +1,74 @@
+package uci
+
+import (
+	"strconv"
+)
+
+// Score is the engine's evaluation from the side to move's point of view:
+// either centipawns or, when Mate is non-zero, moves to mate (negative when
+// the side to move is getting mated).
+type Score struct {
+	CP   int `json:"cp"`
+	Mate int `json:"mate,omitempty"`
+}
+
+// Info is one "info" line of search output.
+type Info struct {
+	Depth    int      `json:"depth"`
+	SelDepth int      `json:"seldepth,omitempty"`
+	MultiPV  int      `json:"multipv,omitempty"`
+	Score    Score    `json:"score"`
+	Nodes    int64    `json:"nodes,omitempty"`
+	NPS      int64    `json:"nps,omitempty"`
+	TimeMs   int64    `json:"time_ms,omitempty"`
+	PV       []string `json:"pv,omitempty"`
+}
+
+// parseInfo parses the fields after "info". Lines without a score, such as
+// currmove updates and strings, are reported as not ok.
+func parseInfo(fields []string) (Info, bool) {
+	var info Info
+	hasScore := false
+
+	for i := 0; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch fields[i] {
+		case "depth":
+			info.Depth, _ = strconv.Atoi(next())
+		case "seldepth":
+			info.SelDepth, _ = strconv.Atoi(next())
+		case "multipv":
+			info.MultiPV, _ = strconv.Atoi(next())
+		case "nodes":
+			info.Nodes, _ = strconv.ParseInt(next(), 10, 64)
+		case "nps":
+			info.NPS, _ = strconv.ParseInt(next(), 10, 64)
+		case "time":
+			info.TimeMs, _ = strconv.ParseInt(next(), 10, 64)
+		case "score":
+			switch next() {
+			case "cp":
+				info.Score.CP, _ = strconv.Atoi(next())
+				hasScore = true
+			case "mate":
+				info.Score.Mate, _ = strconv.Atoi(next())
+				hasScore = true
+			}
+		case "pv":
+			info.PV = append([]string(nil), fields[i+1:]...)
+			i = len(fields)
+		case "string":
+			// The rest of the line is free text.
+			return info, false
+		}
+	}
+
+	return info, hasScore
+}
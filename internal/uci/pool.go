@@ -0,0 +1,136 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("engine pool is closed")
+
+type Config struct {
+	Path    string
+	Args    []string
+	Options map[string]string
+	// Size is the most engine processes run at once.
+	Size int
+}
+
+// Pool starts engine processes on demand, up to Config.Size, and lends them
+// out one caller at a time. Engines that misbehave are killed and replaced.
+type Pool struct {
+	cfg Config
+
+	// slots holds one token per process that may run; idle holds the
+	// engines that are running but not lent out.
+	slots chan struct{}
+	idle  chan *Engine
+	// done is closed by Close to wake callers waiting in Acquire.
+	done chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewPool(cfg Config) *Pool {
+	if cfg.Size < 1 {
+		cfg.Size = 1
+	}
+	return &Pool{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.Size),
+		idle:  make(chan *Engine, cfg.Size),
+		done:  make(chan struct{}),
+	}
+}
+
+// Acquire returns an idle engine, starting one if the pool is not full, or
+// waits for one to be released. It fails with ErrPoolClosed once the pool
+// is closed, waking callers that are waiting.
+func (p *Pool) Acquire(ctx context.Context) (*Engine, error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+
+	select {
+	case e := <-p.idle:
+		return e, nil
+	default:
+	}
+
+	select {
+	case e := <-p.idle:
+		return e, nil
+	case p.slots <- struct{}{}:
+		e, err := Start(p.cfg.Path, p.cfg.Args, p.cfg.Options)
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		return e, nil
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release returns an engine to the pool, or shuts it down if it is broken or
+// the pool has been closed.
+func (p *Pool) Release(e *Engine) {
+	p.mu.Lock()
+	if !e.broken && !p.closed {
+		// Never blocks: there are no more engines than idle has room for.
+		// Holding mu keeps Close from missing the engine.
+		p.idle <- e
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	if err := e.Close(); err != nil {
+		log.Printf("Engine exited with error: %v", err)
+	}
+	<-p.slots
+}
+
+// Search runs one search on a pooled engine.
+func (p *Pool) Search(ctx context.Context, pos Position, limits Limits) (*Result, error) {
+	e, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Release(e)
+
+	if err := e.NewGame(); err != nil {
+		return nil, err
+	}
+	return e.Search(ctx, pos, limits)
+}
+
+// Close shuts down the idle engines and fails the callers waiting for one.
+// Engines still lent out are shut down when they are released.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	for {
+		select {
+		case e := <-p.idle:
+			e.Close()
+			<-p.slots
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Command fakeengine is a scripted UCI engine for the uci package's tests.
+//
+// It answers go with a fixed info line and a best move, which is the value
+// of the BestMove option if one was set. An infinite search waits for stop.
+// With -hang it never answers stop, and a search of "position fen crash"
+// makes it exit.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	hang := flag.Bool("hang", false, "ignore stop")
+	flag.Parse()
+
+	bestMove := "e2e4"
+	position := ""
+	searching := false
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("id author chess tests")
+			fmt.Println("option name BestMove type string default e2e4")
+			fmt.Println("uciok")
+		case "setoption":
+			if len(fields) == 5 && fields[2] == "BestMove" {
+				bestMove = fields[4]
+			}
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			position = strings.Join(fields, " ")
+		case "go":
+			if position == "position fen crash" {
+				os.Exit(1)
+			}
+			fmt.Println("info depth 1 currmove e2e4")
+			fmt.Println("info depth 12 seldepth 18 multipv 1 score cp 34 nodes 12000 nps 600000 time 20 pv " + bestMove + " e7e5")
+			fmt.Println("info string searching")
+			if len(fields) > 1 && fields[1] == "infinite" {
+				searching = true
+				continue
+			}
+			fmt.Println("bestmove " + bestMove + " ponder e7e5")
+		case "stop":
+			if searching && !*hang {
+				searching = false
+				fmt.Println("bestmove " + bestMove + " ponder e7e5")
+			}
+		case "quit":
+			if !*hang {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeEngine is the path of testdata/fakeengine, built by TestMain.
+var fakeEngine string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "uci-test")
+	if err != nil {
+		fmt.Println("Error creating temp dir:", err)
+		os.Exit(1)
+	}
+	fakeEngine = filepath.Join(dir, "fakeengine")
+	build := exec.Command("go", "build", "-o", fakeEngine, "./testdata/fakeengine")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Println("Error building fake engine:", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startFake(t *testing.T, args []string, options map[string]string) *Engine {
+	t.Helper()
+	e, err := Start(fakeEngine, args, options)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestHandshake(t *testing.T) {
+	e := startFake(t, nil, map[string]string{"BestMove": "d2d4"})
+	if e.Name != "Fake Engine" {
+		t.Errorf("Name = %q, want %q", e.Name, "Fake Engine")
+	}
+	if err := e.NewGame(); err != nil {
+		t.Fatalf("NewGame: %v", err)
+	}
+
+	result, err := e.Search(context.Background(), Position{Moves: []string{"e2e4"}}, Limits{Depth: 12})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if result.BestMove != "d2d4" || result.Ponder != "e7e5" {
+		t.Errorf("bestmove %s ponder %s, want d2d4 ponder e7e5", result.BestMove, result.Ponder)
+	}
+}
+
+func TestStartMissingEngine(t *testing.T) {
+	if _, err := Start(filepath.Join(t.TempDir(), "missing"), nil, nil); err == nil {
+		t.Fatal("Start of a missing engine succeeded")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line   []string
+		want   Info
+		scored bool
+	}{
+		{
+			line:   []string{"depth", "12", "seldepth", "18", "multipv", "1", "score", "cp", "34", "nodes", "12000", "nps", "600000", "time", "20", "pv", "e2e4", "e7e5"},
+			want:   Info{Depth: 12, SelDepth: 18, MultiPV: 1, Score: Score{CP: 34}, Nodes: 12000, NPS: 600000, TimeMs: 20, PV: []string{"e2e4", "e7e5"}},
+			scored: true,
+		},
+		{
+			line:   []string{"depth", "5", "score", "mate", "-3", "pv", "g1f3"},
+			want:   Info{Depth: 5, Score: Score{Mate: -3}, PV: []string{"g1f3"}},
+			scored: true,
+		},
+		{
+			line: []string{"depth", "1", "currmove", "e2e4"},
+			want: Info{Depth: 1},
+		},
+		{
+			line: []string{"string", "score", "cp", "10"},
+		},
+	}
+
+	for _, tt := range tests {
+		info, scored := parseInfo(tt.line)
+		if scored != tt.scored {
+			t.Errorf("parseInfo(%v) scored = %v, want %v", tt.line, scored, tt.scored)
+		}
+		if tt.scored && !reflect.DeepEqual(info, tt.want) {
+			t.Errorf("parseInfo(%v) = %+v, want %+v", tt.line, info, tt.want)
+		}
+	}
+}
+
+func TestSearchKeepsScoredInfo(t *testing.T) {
+	e := startFake(t, nil, nil)
+
+	result, err := e.Search(context.Background(), Position{}, Limits{MoveTime: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := Info{Depth: 12, SelDepth: 18, MultiPV: 1, Score: Score{CP: 34}, Nodes: 12000, NPS: 600000, TimeMs: 20, PV: []string{"e2e4", "e7e5"}}
+	if !reflect.DeepEqual(result.Info, want) {
+		t.Errorf("Info = %+v, want %+v", result.Info, want)
+	}
+}
+
+func TestSearchStopsOnCancel(t *testing.T) {
+	e := startFake(t, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := e.Search(ctx, Position{}, Limits{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if result.BestMove != "e2e4" {
+		t.Errorf("BestMove = %q, want e2e4", result.BestMove)
+	}
+	if e.broken {
+		t.Error("engine that answered stop was marked broken")
+	}
+}
+
+func TestSearchTimesOutHungEngine(t *testing.T) {
+	e := startFake(t, []string{"-hang"}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := e.Search(ctx, Position{}, Limits{}); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Search error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond+stopGrace+time.Second {
+		t.Errorf("Search took %v to give up", elapsed)
+	}
+	if !e.broken {
+		t.Error("engine that ignored stop was not marked broken")
+	}
+}
+
+func TestPoolReplacesCrashedEngine(t *testing.T) {
+	pool := NewPool(Config{Path: fakeEngine, Size: 1})
+	defer pool.Close()
+	ctx := context.Background()
+
+	crashed, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := crashed.Search(ctx, Position{FEN: "crash"}, Limits{Depth: 1}); !errors.Is(err, ErrEngineExited) {
+		t.Fatalf("Search error = %v, want ErrEngineExited", err)
+	}
+	pool.Release(crashed)
+
+	replacement, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire after crash: %v", err)
+	}
+	if replacement == crashed {
+		t.Fatal("pool lent out the crashed engine again")
+	}
+	pool.Release(replacement)
+
+	result, err := pool.Search(ctx, Position{}, Limits{Depth: 1})
+	if err != nil {
+		t.Fatalf("Search on replacement: %v", err)
+	}
+	if result.BestMove != "e2e4" {
+		t.Errorf("BestMove = %q, want e2e4", result.BestMove)
+	}
+}
+
+func TestPoolReusesIdleEngine(t *testing.T) {
+	pool := NewPool(Config{Path: fakeEngine, Size: 1})
+	defer pool.Close()
+
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	pool.Release(first)
+	second, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer pool.Release(second)
+	if second != first {
+		t.Error("pool started a new engine instead of reusing the idle one")
+	}
+}
+
+func TestPoolCloseWakesWaiters(t *testing.T) {
+	pool := NewPool(Config{Path: fakeEngine, Size: 1})
+	lent, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := pool.Acquire(context.Background())
+		waiting <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	pool.Close()
+
+	select {
+	case err := <-waiting:
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("Acquire error = %v, want ErrPoolClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire still blocked after Close")
+	}
+
+	pool.Release(lent)
+	if !lent.broken {
+		t.Error("engine released after Close was not shut down")
+	}
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Acquire after Close error = %v, want ErrPoolClosed", err)
+	}
+}
@@ -0,0 +1,89 @@
+package variant
+
+import (
+	"github.com/notnil/chess"
+)
+
+var (
+	knightJumps = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps   = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	straightRay = [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	diagonalRay = [][2]int{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+)
+
+// attacked reports whether any piece of color by attacks sq on board. notnil
+// keeps its attack tables private, so rules it does not know about have to
+// look for themselves.
+func attacked(board map[chess.Square]chess.Piece, sq chess.Square, by chess.Color) bool {
+	file, rank := int(sq.File()), int(sq.Rank())
+	at := func(df, dr int) (chess.Piece, bool) {
+		f, r := file+df, rank+dr
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoPiece, false
+		}
+		return board[chess.NewSquare(chess.File(f), chess.Rank(r))], true
+	}
+	is := func(p chess.Piece, types ...chess.PieceType) bool {
+		if p == chess.NoPiece || p.Color() != by {
+			return false
+		}
+		for _, t := range types {
+			if p.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Pawns attack diagonally forward, so look one rank behind sq from the
+	// attacker's side.
+	pawnRank := -1
+	if by == chess.Black {
+		pawnRank = 1
+	}
+	for _, df := range []int{-1, 1} {
+		if p, ok := at(df, pawnRank); ok && is(p, chess.Pawn) {
+			return true
+		}
+	}
+	for _, d := range knightJumps {
+		if p, ok := at(d[0], d[1]); ok && is(p, chess.Knight) {
+			return true
+		}
+	}
+	for _, d := range kingSteps {
+		if p, ok := at(d[0], d[1]); ok && is(p, chess.King) {
+			return true
+		}
+	}
+
+	slides := func(rays [][2]int, types ...chess.PieceType) bool {
+		for _, d := range rays {
+			for i := 1; ; i++ {
+				p, ok := at(d[0]*i, d[1]*i)
+				if !ok {
+					break
+				}
+				if p == chess.NoPiece {
+					continue
+				}
+				if is(p, types...) {
+					return true
+				}
+				break
+			}
+		}
+		return false
+	}
+	return slides(straightRay, chess.Rook, chess.Queen) || slides(diagonalRay, chess.Bishop, chess.Queen)
+}
+
+// kingSquare finds the king of color c, or chess.NoSquare if there is none.
+func kingSquare(board map[chess.Square]chess.Piece, c chess.Color) chess.Square {
+	for sq, p := range board {
+		if p == chess.NewPiece(chess.King, c) {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
@@ -0,0 +1,294 @@
+package variant
+
+import (
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Board is a game in progress under the rules of its variant. Moves go in
+// and come out in UCI notation; Chess960 castles are written as the king
+// taking its own rook, e.g. "b1a1".
+type Board struct {
+	variant  Variant
+	startFEN string
+
+	// game holds the moves since the last Chess960 castle, which notnil
+	// cannot play itself. In every other game it holds all of them.
+	game *chess.Game
+
+	moves []string
+	sans  []string
+
+	castling castling
+
+	// outcome and method are how the game ended. notnil's own verdict does
+	// not know the variants' extra ways to win, so judge decides after every
+	// move.
+	outcome chess.Outcome
+	method  string
+
+	// checks counts the checks each side has given in Three-check.
+	checks [2]int
+}
+
+// New sets up a game of v. Standard games and the rule variants take no FEN
+// (or their initial one), Chess960 games pick a random starting position
+// unless given one, and games from a position need one.
+func New(v Variant, fen string) (*Board, error) {
+	b := &Board{variant: v, castling: noCastling(), outcome: chess.NoOutcome}
+
+	if initial, ok := initialFENs[v]; ok {
+		if fen != "" && fen != initial {
+			return nil, ErrFENNotAllowed
+		}
+		opt, err := chess.FEN(initial)
+		if err != nil {
+			return nil, err
+		}
+		b.game = chess.NewGame(opt)
+		b.startFEN = b.game.FEN()
+		return b, nil
+	}
+
+	switch v {
+	case Chess960:
+		if fen == "" {
+			fen = randomChess960()
+		}
+	case FromPosition:
+		if fen == "" {
+			return nil, ErrFENRequired
+		}
+	default:
+		return nil, ErrUnknownVariant
+	}
+
+	fields := strings.Fields(fen)
+	if len(fields) == 4 {
+		fields = append(fields, "0", "1")
+	}
+	if len(fields) != 6 {
+		return nil, ErrInvalidFEN
+	}
+
+	board, err := chess.FEN(fields[0] + " w - - 0 1")
+	if err != nil {
+		return nil, ErrInvalidFEN
+	}
+	squares := chess.NewGame(board).Position().Board().SquareMap()
+	if v == Chess960 {
+		rights, ok := parseCastling(fields[2], squares)
+		if !ok {
+			return nil, ErrInvalidFEN
+		}
+		b.castling = rights
+		fields[2] = "-"
+	} else if !standardCastling(fields[2], squares) {
+		return nil, ErrInvalidFEN
+	}
+
+	opt, err := chess.FEN(strings.Join(fields, " "))
+	if err != nil {
+		return nil, ErrInvalidFEN
+	}
+	b.game = chess.NewGame(opt)
+	if !legalPosition(b.game.Position()) {
+		return nil, ErrInvalidFEN
+	}
+	b.startFEN = b.FEN()
+	return b, nil
+}
+
+// Replay sets up a game of v from fen and plays moves on it.
+func Replay(v Variant, fen string, moves []string) (*Board, error) {
+	b, err := New(v, fen)
+	if err != nil {
+		return nil, err
+	}
+	for _, mv := range moves {
+		if err := b.Move(mv); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// standardCastling checks that every castling right in field has its king
+// and rook on their standard squares, which is all notnil can castle from.
+func standardCastling(field string, squares map[chess.Square]chess.Piece) bool {
+	if field == "-" {
+		return true
+	}
+	for _, ch := range field {
+		var king, rook chess.Square
+		color := chess.White
+		switch ch {
+		case 'K':
+			king, rook = chess.E1, chess.H1
+		case 'Q':
+			king, rook = chess.E1, chess.A1
+		case 'k':
+			king, rook, color = chess.E8, chess.H8, chess.Black
+		case 'q':
+			king, rook, color = chess.E8, chess.A8, chess.Black
+		default:
+			return false
+		}
+		if squares[king] != chess.NewPiece(chess.King, color) || squares[rook] != chess.NewPiece(chess.Rook, color) {
+			return false
+		}
+	}
+	return true
+}
+
+// legalPosition rejects positions no game could reach or continue from:
+// a missing or extra king, pawns on the back ranks, the side not to move in
+// check, or no legal moves at all.
+func legalPosition(pos *chess.Position) bool {
+	squares := pos.Board().SquareMap()
+	kings := map[chess.Color]int{}
+	for sq, p := range squares {
+		switch p.Type() {
+		case chess.King:
+			kings[p.Color()]++
+		case chess.Pawn:
+			if sq.Rank() == chess.Rank1 || sq.Rank() == chess.Rank8 {
+				return false
+			}
+		}
+	}
+	if kings[chess.White] != 1 || kings[chess.Black] != 1 {
+		return false
+	}
+	waiting := pos.Turn().Other()
+	if attacked(squares, kingSquare(squares, waiting), pos.Turn()) {
+		return false
+	}
+	return len(pos.ValidMoves()) > 0
+}
+
+func (b *Board) Variant() Variant {
+	return b.variant
+}
+
+// StartFEN is the position the game started from.
+func (b *Board) StartFEN() string {
+	return b.startFEN
+}
+
+// Position is the current position. In Chess960 its castling rights are
+// always empty; FEN has the real ones.
+func (b *Board) Position() *chess.Position {
+	return b.game.Position()
+}
+
+// FEN describes the current position, with Chess960 castling rights in
+// Shredder-FEN.
+func (b *Board) FEN() string {
+	fen := b.game.FEN()
+	if b.variant != Chess960 {
+		return fen
+	}
+	fields := strings.Fields(fen)
+	fields[2] = b.castling.String()
+	return strings.Join(fields, " ")
+}
+
+// Moves lists the moves played in UCI notation.
+func (b *Board) Moves() []string {
+	return append([]string(nil), b.moves...)
+}
+
+// SAN lists the moves played in standard algebraic notation.
+func (b *Board) SAN() []string {
+	return append([]string(nil), b.sans...)
+}
+
+// Game is the notnil game since the last Chess960 castle, for engines to
+// search. Its positions have no Chess960 castling rights, so engines never
+// suggest a castle there.
+func (b *Board) Game() *chess.Game {
+	return b.game
+}
+
+// Move plays a move given in UCI notation and decides whether it ended the
+// game.
+func (b *Board) Move(move string) error {
+	if err := b.play(move); err != nil {
+		return err
+	}
+	b.judge()
+	return nil
+}
+
+func (b *Board) play(move string) error {
+	switch b.variant {
+	case Chess960:
+		if ok, err := b.castle(move); ok {
+			return err
+		}
+	case Horde:
+		if ok, err := b.hordePush(move); ok {
+			return err
+		}
+	}
+
+	pos := b.game.Position()
+	mv, err := chess.UCINotation{}.Decode(pos, move)
+	if err != nil || !b.allowed(pos, mv) {
+		return ErrIllegalMove
+	}
+	piece := pos.Board().Piece(mv.S1())
+	if err := b.game.Move(mv); err != nil {
+		return ErrIllegalMove
+	}
+	moves := b.game.Moves()
+	played := moves[len(moves)-1]
+
+	b.castling.update(pos.Turn(), piece, played.S1(), played.S2())
+	b.moves = append(b.moves, played.String())
+	b.sans = append(b.sans, chess.AlgebraicNotation{}.Encode(pos, played))
+	return nil
+}
+
+func (b *Board) Outcome() chess.Outcome {
+	return b.outcome
+}
+
+// Method is how the game ended: a chess.Method name, or one of the variant
+// methods such as MethodKingOfTheHill.
+func (b *Board) Method() string {
+	return b.method
+}
+
+// Resign ends the game as a loss for color, unless it is already over.
+func (b *Board) Resign(color chess.Color) {
+	if b.outcome != chess.NoOutcome {
+		return
+	}
+	b.end(winFor(color.Other()), chess.Resignation.String())
+}
+
+// Draw ends the game drawn by method, which must be a draw offer or one of
+// the EligibleDraws.
+func (b *Board) Draw(method chess.Method) error {
+	if err := b.game.Draw(method); err != nil {
+		return err
+	}
+	b.end(chess.Draw, method.String())
+	return nil
+}
+
+func (b *Board) EligibleDraws() []chess.Method {
+	return b.game.EligibleDraws()
+}
+
+// Clone copies the board so the copy can be played on independently.
+func (b *Board) Clone() *Board {
+	c := *b
+	c.game = b.game.Clone()
+	c.moves = b.Moves()
+	c.sans = b.SAN()
+	return &c
+}
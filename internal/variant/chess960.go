@@ -0,0 +1,274 @@
+package variant
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// castling holds the rooks each side may still castle with in Chess960,
+// indexed by color (White first) and side. notnil only knows the standard
+// corner rooks, so in Chess960 it is given no castling rights at all and
+// Board castles by itself.
+type castling [2][2]chess.Square
+
+func noCastling() castling {
+	return castling{{chess.NoSquare, chess.NoSquare}, {chess.NoSquare, chess.NoSquare}}
+}
+
+func colorIndex(c chess.Color) int {
+	if c == chess.Black {
+		return 1
+	}
+	return 0
+}
+
+func backRank(c chess.Color) chess.Rank {
+	if c == chess.Black {
+		return chess.Rank8
+	}
+	return chess.Rank1
+}
+
+// parseCastling reads the castling field of a FEN in either Shredder-FEN
+// (rook files, e.g. "HAha") or X-FEN ("KQkq", meaning the outermost rooks).
+func parseCastling(field string, board map[chess.Square]chess.Piece) (castling, bool) {
+	rights := noCastling()
+	if field == "-" {
+		return rights, true
+	}
+
+	for _, ch := range field {
+		color := chess.White
+		if ch >= 'a' && ch <= 'z' {
+			color = chess.Black
+		}
+		rank := backRank(color)
+		king := kingSquare(board, color)
+		if king == chess.NoSquare || king.Rank() != rank {
+			return rights, false
+		}
+		rook := chess.NewPiece(chess.Rook, color)
+
+		var rookFile chess.File
+		switch upper := ch &^ 0x20; {
+		case upper == 'K' || upper == 'Q':
+			// The outermost rook on that side of the king.
+			found := false
+			for f := chess.FileH; f >= chess.FileA; f-- {
+				file := f
+				if upper == 'Q' {
+					file = chess.FileH - f
+				}
+				if (upper == 'K') != (file > king.File()) {
+					continue
+				}
+				if board[chess.NewSquare(file, rank)] == rook {
+					rookFile, found = file, true
+					break
+				}
+			}
+			if !found {
+				return rights, false
+			}
+		case upper >= 'A' && upper <= 'H':
+			rookFile = chess.File(upper - 'A')
+		default:
+			return rights, false
+		}
+
+		sq := chess.NewSquare(rookFile, rank)
+		if board[sq] != rook || rookFile == king.File() {
+			return rights, false
+		}
+		side := queenSide
+		if rookFile > king.File() {
+			side = kingSide
+		}
+		rights[colorIndex(color)][side] = sq
+	}
+	return rights, true
+}
+
+// String renders the rights in Shredder-FEN.
+func (c castling) String() string {
+	var b strings.Builder
+	for i, color := range []chess.Color{chess.White, chess.Black} {
+		for _, sq := range c[i] {
+			if sq == chess.NoSquare {
+				continue
+			}
+			letter := "ABCDEFGH"[sq.File()]
+			if color == chess.Black {
+				letter += 'a' - 'A'
+			}
+			b.WriteByte(letter)
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// update drops the rights a move gives up: moving the king loses both, and
+// moving or capturing a rook loses that rook's.
+func (c *castling) update(mover chess.Color, piece chess.Piece, from, to chess.Square) {
+	if piece.Type() == chess.King {
+		c[colorIndex(mover)] = [2]chess.Square{chess.NoSquare, chess.NoSquare}
+	}
+	for i := range c {
+		for side, sq := range c[i] {
+			if sq == from || sq == to {
+				c[i][side] = chess.NoSquare
+			}
+		}
+	}
+}
+
+// randomChess960 returns the FEN of one of the 960 starting positions,
+// numbered as in Scharnagl's scheme.
+func randomChess960() string {
+	return chess960FEN(rand.Intn(960))
+}
+
+func chess960FEN(n int) string {
+	var rank [8]byte
+	place := func(piece byte, nth int) {
+		for i := range rank {
+			if rank[i] != 0 {
+				continue
+			}
+			if nth == 0 {
+				rank[i] = piece
+				return
+			}
+			nth--
+		}
+	}
+
+	rank[2*(n%4)+1] = 'b'
+	n /= 4
+	rank[2*(n%4)] = 'b'
+	n /= 4
+	place('q', n%6)
+	n /= 6
+	knights := [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}[n]
+	place('n', knights[1])
+	place('n', knights[0])
+	place('r', 0)
+	place('k', 0)
+	place('r', 0)
+
+	black := string(rank[:])
+	white := strings.ToUpper(black)
+	return black + "/pppppppp/8/8/8/8/PPPPPPPP/" + white + " w KQkq - 0 1"
+}
+
+// castle plays move if it is a Chess960 castle, written as the king taking
+// its own rook. ok is false for any other move.
+func (b *Board) castle(move string) (ok bool, err error) {
+	if len(move) != 4 {
+		return false, nil
+	}
+	pos := b.game.Position()
+	squares := pos.Board().SquareMap()
+	turn := pos.Turn()
+
+	from, to, valid := parseSquares(move)
+	if !valid || squares[from] != chess.NewPiece(chess.King, turn) || squares[to] != chess.NewPiece(chess.Rook, turn) {
+		return false, nil
+	}
+
+	side := -1
+	for s, rook := range b.castling[colorIndex(turn)] {
+		if rook == to {
+			side = s
+		}
+	}
+	if side < 0 {
+		return true, ErrIllegalMove
+	}
+
+	rank := backRank(turn)
+	kingTo, rookTo := chess.NewSquare(chess.FileG, rank), chess.NewSquare(chess.FileF, rank)
+	if side == queenSide {
+		kingTo, rookTo = chess.NewSquare(chess.FileC, rank), chess.NewSquare(chess.FileD, rank)
+	}
+
+	// Every square either piece crosses must be empty but for the two of
+	// them, and the king may not start on, cross or land on an attacked
+	// square. The pieces are lifted first so neither shields the king.
+	delete(squares, from)
+	delete(squares, to)
+	lo := min(from.File(), to.File(), kingTo.File(), rookTo.File())
+	hi := max(from.File(), to.File(), kingTo.File(), rookTo.File())
+	for f := lo; f <= hi; f++ {
+		if squares[chess.NewSquare(f, rank)] != chess.NoPiece {
+			return true, ErrIllegalMove
+		}
+	}
+	step := chess.File(1)
+	if kingTo.File() < from.File() {
+		step = -1
+	}
+	for f := from.File(); ; f += step {
+		if attacked(squares, chess.NewSquare(f, rank), turn.Other()) {
+			return true, ErrIllegalMove
+		}
+		if f == kingTo.File() {
+			break
+		}
+	}
+
+	squares[kingTo] = chess.NewPiece(chess.King, turn)
+	squares[rookTo] = chess.NewPiece(chess.Rook, turn)
+	b.castling[colorIndex(turn)] = [2]chess.Square{chess.NoSquare, chess.NoSquare}
+
+	fields := strings.Fields(pos.String())
+	fullMove, _ := strconv.Atoi(fields[5])
+	if turn == chess.Black {
+		fullMove++
+	}
+	fen := chess.NewBoard(squares).String() + " " + turn.Other().String() + " - - " +
+		strconv.Itoa(pos.HalfMoveClock()+1) + " " + strconv.Itoa(fullMove)
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return true, err
+	}
+	// Castling can never be undone, so no earlier position can repeat and
+	// the game carries on from the new one.
+	b.game = chess.NewGame(opt)
+
+	san := "O-O"
+	if side == queenSide {
+		san = "O-O-O"
+	}
+	if b.game.Position().Status() == chess.Checkmate {
+		san += "#"
+	} else if attacked(squares, kingSquare(squares, turn.Other()), turn) {
+		san += "+"
+	}
+	b.moves = append(b.moves, move)
+	b.sans = append(b.sans, san)
+	return true, nil
+}
+
+func parseSquares(move string) (from, to chess.Square, ok bool) {
+	parse := func(s string) (chess.Square, bool) {
+		if s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+			return chess.NoSquare, false
+		}
+		return chess.NewSquare(chess.File(s[0]-'a'), chess.Rank(s[1]-'1')), true
+	}
+	from, okFrom := parse(move[0:2])
+	to, okTo := parse(move[2:4])
+	return from, to, okFrom && okTo
+}
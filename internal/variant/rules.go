@@ -0,0 +1,236 @@
+package variant
+
+import (
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// initialFENs are the fixed starting positions. The rule variants always
+// start from theirs.
+var initialFENs = map[Variant]string{
+	Standard:      chess.StartingPosition().String(),
+	KingOfTheHill: chess.StartingPosition().String(),
+	ThreeCheck:    chess.StartingPosition().String(),
+	RacingKings:   "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1",
+	Horde:         "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1",
+}
+
+// hill is the centre a King of the Hill king wins by reaching.
+var hill = map[chess.Square]bool{chess.D4: true, chess.E4: true, chess.D5: true, chess.E5: true}
+
+// judge decides whether the move just played ended the game. The variants'
+// own wins come first; after that checkmate and stalemate are as notnil sees
+// them.
+func (b *Board) judge() {
+	pos := b.game.Position()
+	squares := pos.Board().SquareMap()
+	mover := pos.Turn().Other()
+
+	switch b.variant {
+	case KingOfTheHill:
+		if hill[kingSquare(squares, mover)] {
+			b.end(winFor(mover), MethodKingOfTheHill)
+			return
+		}
+	case ThreeCheck:
+		if inCheck(squares, pos.Turn()) {
+			b.checks[colorIndex(mover)]++
+			if b.checks[colorIndex(mover)] >= 3 {
+				b.end(winFor(mover), MethodThreeChecks)
+				return
+			}
+		}
+	case RacingKings:
+		if b.judgeRace(pos, squares) {
+			return
+		}
+	case Horde:
+		if !hasPieces(squares, chess.White) {
+			b.end(chess.BlackWon, MethodHordeDestroyed)
+			return
+		}
+	}
+
+	switch b.game.Method() {
+	case chess.Checkmate, chess.Stalemate:
+		b.end(b.game.Outcome(), b.game.Method().String())
+		return
+	}
+	if b.variant.StandardRules() {
+		if b.game.Outcome() != chess.NoOutcome {
+			b.end(b.game.Outcome(), b.game.Method().String())
+		}
+		return
+	}
+
+	// notnil judges insufficient material by standard chess, where a lone
+	// king cannot win, and stops looking for automatic draws once it has
+	// found one, so the rule variants look for themselves.
+	switch {
+	case b.repetitions() >= 5:
+		b.end(chess.Draw, chess.FivefoldRepetition.String())
+	case pos.HalfMoveClock() >= 150:
+		b.end(chess.Draw, chess.SeventyFiveMoveRule.String())
+	case b.variant == ThreeCheck && !hasPieces(squares, chess.White, chess.King) && !hasPieces(squares, chess.Black, chess.King):
+		b.end(chess.Draw, chess.InsufficientMaterial.String())
+	}
+}
+
+// judgeRace ends a Racing Kings game once a king is on the eighth rank.
+// White moves first, so when White gets there Black has one move left to
+// draw by getting there too. It reports whether the game is over.
+func (b *Board) judgeRace(pos *chess.Position, squares map[chess.Square]chess.Piece) bool {
+	white := kingSquare(squares, chess.White).Rank() == chess.Rank8
+	black := kingSquare(squares, chess.Black).Rank() == chess.Rank8
+
+	switch {
+	case white && black:
+		b.end(chess.Draw, MethodRaceFinished)
+	case black:
+		b.end(chess.BlackWon, MethodRaceFinished)
+	case white && (pos.Turn() == chess.White || !b.canFinish(pos)):
+		b.end(chess.WhiteWon, MethodRaceFinished)
+	case len(b.legalMoves(pos)) == 0:
+		b.end(chess.Draw, chess.Stalemate.String())
+	default:
+		return false
+	}
+	return true
+}
+
+// canFinish reports whether the side to move can put its king on the
+// eighth rank.
+func (b *Board) canFinish(pos *chess.Position) bool {
+	for _, m := range b.legalMoves(pos) {
+		if pos.Board().Piece(m.S1()).Type() == chess.King && m.S2().Rank() == chess.Rank8 {
+			return true
+		}
+	}
+	return false
+}
+
+// legalMoves are notnil's moves less those the variant forbids: in Racing
+// Kings nobody may give check.
+func (b *Board) legalMoves(pos *chess.Position) []*chess.Move {
+	moves := pos.ValidMoves()
+	if b.variant != RacingKings {
+		return moves
+	}
+	legal := moves[:0]
+	for _, m := range moves {
+		if !m.HasTag(chess.Check) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// allowed reports whether mv, decoded from UCI, is among the legal moves.
+func (b *Board) allowed(pos *chess.Position, mv *chess.Move) bool {
+	for _, m := range b.legalMoves(pos) {
+		if m.S1() == mv.S1() && m.S2() == mv.S2() && m.Promo() == mv.Promo() {
+			return true
+		}
+	}
+	return false
+}
+
+// hordePush plays move if it is a Horde pawn stepping two squares from the
+// first rank, which notnil does not generate. ok is false for any other
+// move. Such a step cannot be taken en passant.
+func (b *Board) hordePush(move string) (ok bool, err error) {
+	if len(move) != 4 {
+		return false, nil
+	}
+	pos := b.game.Position()
+	from, to, valid := parseSquares(move)
+	if !valid || pos.Turn() != chess.White || from.Rank() != chess.Rank1 || to.Rank() != chess.Rank3 || from.File() != to.File() {
+		return false, nil
+	}
+	squares := pos.Board().SquareMap()
+	if squares[from] != chess.WhitePawn {
+		return false, nil
+	}
+	if squares[chess.NewSquare(from.File(), chess.Rank2)] != chess.NoPiece || squares[to] != chess.NoPiece {
+		return true, ErrIllegalMove
+	}
+
+	// White has no king, so no pawn move can leave one in check.
+	delete(squares, from)
+	squares[to] = chess.WhitePawn
+	rights := pos.CastleRights().String()
+	if rights == "" {
+		rights = "-"
+	}
+	fields := strings.Fields(pos.String())
+	opt, err := chess.FEN(chess.NewBoard(squares).String() + " b " + rights + " - 0 " + fields[5])
+	if err != nil {
+		return true, err
+	}
+	// A pawn move can never be undone, so the game carries on from the new
+	// position just as after a Chess960 castle.
+	b.game = chess.NewGame(opt)
+
+	san := to.String()
+	if b.game.Position().Status() == chess.Checkmate {
+		san += "#"
+	} else if inCheck(squares, chess.Black) {
+		san += "+"
+	}
+	b.moves = append(b.moves, move)
+	b.sans = append(b.sans, san)
+	return true, nil
+}
+
+// repetitions counts how often the current position has occurred since the
+// last irreversible move that restarted notnil's game.
+func (b *Board) repetitions() int {
+	key := func(pos *chess.Position) string {
+		return strings.Join(strings.Fields(pos.String())[:4], " ")
+	}
+	current := key(b.game.Position())
+	n := 0
+	for _, pos := range b.game.Positions() {
+		if key(pos) == current {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *Board) end(outcome chess.Outcome, method string) {
+	b.outcome = outcome
+	b.method = method
+}
+
+func winFor(c chess.Color) chess.Outcome {
+	if c == chess.Black {
+		return chess.BlackWon
+	}
+	return chess.WhiteWon
+}
+
+func inCheck(squares map[chess.Square]chess.Piece, c chess.Color) bool {
+	king := kingSquare(squares, c)
+	return king != chess.NoSquare && attacked(squares, king, c.Other())
+}
+
+// hasPieces reports whether c has any piece other than the types excluded.
+func hasPieces(squares map[chess.Square]chess.Piece, c chess.Color, excluded ...chess.PieceType) bool {
+	for _, p := range squares {
+		if p.Color() != c {
+			continue
+		}
+		counted := true
+		for _, t := range excluded {
+			if p.Type() == t {
+				counted = false
+			}
+		}
+		if counted {
+			return true
+		}
+	}
+	return false
+}
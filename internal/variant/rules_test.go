@@ -0,0 +1,176 @@
+package variant
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// setUp starts a game of v from fen, or from its initial position if fen is
+// empty. New only lets the rule variants start from their initial position.
+func setUp(t *testing.T, v Variant, fen string) *Board {
+	t.Helper()
+	if fen == "" {
+		b, err := New(v, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Board{variant: v, castling: noCastling(), outcome: chess.NoOutcome, game: chess.NewGame(opt)}
+	b.startFEN = b.FEN()
+	return b
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		moves   []string
+		// err is the error of the last move, if any.
+		err     error
+		outcome chess.Outcome
+		method  string
+	}{
+		{
+			name:    "king of the hill: king reaches the centre",
+			variant: KingOfTheHill,
+			moves:   []string{"e2e3", "e7e6", "e1e2", "e8e7", "e2d3", "e7d6", "d3e4"},
+			outcome: chess.WhiteWon,
+			method:  MethodKingOfTheHill,
+		},
+		{
+			name:    "king of the hill: next to the centre",
+			variant: KingOfTheHill,
+			moves:   []string{"e2e3", "e7e6", "e1e2", "e8e7", "e2d3", "e7d6"},
+			outcome: chess.NoOutcome,
+		},
+		{
+			name:    "king of the hill: lone kings play on",
+			variant: KingOfTheHill,
+			fen:     "4k3/8/8/8/8/8/3q4/4K3 w - - 0 1",
+			moves:   []string{"e1d2"},
+			outcome: chess.NoOutcome,
+		},
+		{
+			name:    "three-check: third check wins",
+			variant: ThreeCheck,
+			moves:   []string{"e2e4", "e7e5", "f1c4", "d7d6", "c4f7", "e8f7", "d1h5", "g7g6", "h5g6"},
+			outcome: chess.WhiteWon,
+			method:  MethodThreeChecks,
+		},
+		{
+			name:    "three-check: two checks",
+			variant: ThreeCheck,
+			moves:   []string{"e2e4", "e7e5", "f1c4", "d7d6", "c4f7", "e8f7", "d1h5", "g7g6"},
+			outcome: chess.NoOutcome,
+		},
+		{
+			name:    "three-check: lone kings draw",
+			variant: ThreeCheck,
+			fen:     "4k3/8/8/8/8/8/3q4/4K3 w - - 0 1",
+			moves:   []string{"e1d2"},
+			outcome: chess.Draw,
+			method:  chess.InsufficientMaterial.String(),
+		},
+		{
+			name:    "racing kings: no checks",
+			variant: RacingKings,
+			fen:     "8/8/8/8/8/8/k7/6RK w - - 0 1",
+			moves:   []string{"g1g2"},
+			err:     ErrIllegalMove,
+			outcome: chess.NoOutcome,
+		},
+		{
+			name:    "racing kings: White finishes and Black cannot follow",
+			variant: RacingKings,
+			fen:     "8/6K1/8/8/8/8/k7/8 w - - 0 1",
+			moves:   []string{"g7g8"},
+			outcome: chess.WhiteWon,
+			method:  MethodRaceFinished,
+		},
+		{
+			name:    "racing kings: Black may still follow",
+			variant: RacingKings,
+			fen:     "8/k5K1/8/8/8/8/8/8 w - - 0 1",
+			moves:   []string{"g7g8"},
+			outcome: chess.NoOutcome,
+		},
+		{
+			name:    "racing kings: Black follows for a draw",
+			variant: RacingKings,
+			fen:     "8/k5K1/8/8/8/8/8/8 w - - 0 1",
+			moves:   []string{"g7g8", "a7a8"},
+			outcome: chess.Draw,
+			method:  MethodRaceFinished,
+		},
+		{
+			name:    "racing kings: Black finishes first",
+			variant: RacingKings,
+			fen:     "8/k7/8/8/8/8/8/6K1 b - - 0 1",
+			moves:   []string{"a7a8"},
+			outcome: chess.BlackWon,
+			method:  MethodRaceFinished,
+		},
+		{
+			name:    "horde: last white piece taken",
+			variant: Horde,
+			fen:     "4k3/8/8/8/8/8/3p4/4P3 b - - 0 1",
+			moves:   []string{"d2e1q"},
+			outcome: chess.BlackWon,
+			method:  MethodHordeDestroyed,
+		},
+		{
+			name:    "horde: double step from the first rank",
+			variant: Horde,
+			fen:     "4k3/8/8/8/8/8/8/P7 w - - 0 1",
+			moves:   []string{"a1a3", "e8d7"},
+			outcome: chess.NoOutcome,
+		},
+		{
+			name:    "horde: blocked double step",
+			variant: Horde,
+			fen:     "4k3/8/8/8/8/8/P7/P7 w - - 0 1",
+			moves:   []string{"a1a3"},
+			err:     ErrIllegalMove,
+			outcome: chess.NoOutcome,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := setUp(t, tt.variant, tt.fen)
+			for i, mv := range tt.moves {
+				err := b.Move(mv)
+				if i < len(tt.moves)-1 && err != nil {
+					t.Fatalf("%s: %v", mv, err)
+				}
+				if i == len(tt.moves)-1 && !errors.Is(err, tt.err) {
+					t.Fatalf("%s: got error %v, want %v", mv, err, tt.err)
+				}
+			}
+			if b.Outcome() != tt.outcome || b.Method() != tt.method {
+				t.Errorf("game ended %s by %q, want %s by %q", b.Outcome(), b.Method(), tt.outcome, tt.method)
+			}
+		})
+	}
+}
+
+func TestHordeDoubleStepSAN(t *testing.T) {
+	b := setUp(t, Horde, "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	if err := b.Move("a1a3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.SAN(); len(got) != 1 || got[0] != "a3" {
+		t.Errorf("SAN %v, want [a3]", got)
+	}
+	if got := b.Position().Turn(); got != chess.Black {
+		t.Errorf("%s to move, want Black", got)
+	}
+}
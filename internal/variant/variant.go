@@ -0,0 +1,80 @@
+// Package variant holds the rules of the games the server plays. Ordinary
+// moves are left to notnil/chess, which only knows standard chess; the rest,
+// such as Chess960 castling or the extra ways to win a King of the Hill game,
+// is layered on top by Board.
+package variant
+
+import (
+	"errors"
+)
+
+type Variant string
+
+const (
+	Standard     Variant = "standard"
+	Chess960     Variant = "chess960"
+	FromPosition Variant = "from_position"
+
+	KingOfTheHill Variant = "king_of_the_hill"
+	ThreeCheck    Variant = "three_check"
+	RacingKings   Variant = "racing_kings"
+	Horde         Variant = "horde"
+)
+
+// Ways a game can end that notnil has no chess.Method for.
+const (
+	MethodKingOfTheHill  = "KingOfTheHill"
+	MethodThreeChecks    = "ThreeChecks"
+	MethodRaceFinished   = "RaceFinished"
+	MethodHordeDestroyed = "HordeDestroyed"
+)
+
+var (
+	ErrUnknownVariant = errors.New("unknown variant")
+	ErrInvalidFEN     = errors.New("invalid starting position")
+	ErrFENRequired    = errors.New("a starting FEN is required for this variant")
+	ErrFENNotAllowed  = errors.New("this variant always starts from its initial position")
+	ErrIllegalMove    = errors.New("illegal move")
+)
+
+// Parse reads a variant name. An empty name is standard chess.
+func Parse(s string) (Variant, error) {
+	switch v := Variant(s); v {
+	case "":
+		return Standard, nil
+	case Standard, Chess960, FromPosition, KingOfTheHill, ThreeCheck, RacingKings, Horde:
+		return v, nil
+	}
+	return "", ErrUnknownVariant
+}
+
+// Name is the variant's name as written in PGN Variant headers.
+func (v Variant) Name() string {
+	switch v {
+	case Chess960:
+		return "Chess960"
+	case FromPosition:
+		return "From Position"
+	case KingOfTheHill:
+		return "King of the Hill"
+	case ThreeCheck:
+		return "Three-check"
+	case RacingKings:
+		return "Racing Kings"
+	case Horde:
+		return "Horde"
+	}
+	return "Standard"
+}
+
+// StandardRules reports whether games of v are won and lost as in standard
+// chess, which is all engines and analysis understand.
+func (v Variant) StandardRules() bool {
+	return v == Standard || v == Chess960 || v == FromPosition
+}
+
+// Validate checks that a game of v can start from fen, as New would.
+func Validate(v Variant, fen string) error {
+	_, err := New(v, fen)
+	return err
+}
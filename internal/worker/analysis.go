@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/Adi-ty/chess/internal/analysis"
+	"github.com/Adi-ty/chess/internal/queue"
+	"github.com/Adi-ty/chess/internal/store"
+	"github.com/Adi-ty/chess/internal/variant"
+	"github.com/redis/go-redis/v9"
+)
+
+// analysisTimeout bounds the review of a single game.
+const analysisTimeout = 10 * time.Minute
+
+// AnalysisWorker reviews finished games queued by queue.EnqueueAnalysis.
+type AnalysisWorker struct {
+	rdb           *redis.Client
+	analysisStore store.AnalysisStore
+	evaluator     analysis.Evaluator
+}
+
+func NewAnalysisWorker(rdb *redis.Client, analysisStore store.AnalysisStore, evaluator analysis.Evaluator) *AnalysisWorker {
+	return &AnalysisWorker{
+		rdb:           rdb,
+		analysisStore: analysisStore,
+		evaluator:     evaluator,
+	}
+}
+
+func (w *AnalysisWorker) Start() {
+	for {
+		result, err := w.rdb.BRPop(context.Background(), 0, "analysis_queue").Result()
+		if err != nil {
+			log.Printf("Analysis worker dequeue error: %v", err)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		var payload queue.AnalysisPayload
+		if err := json.Unmarshal([]byte(result[1]), &payload); err != nil {
+			log.Printf("Analysis worker unmarshal error: %v", err)
+			continue
+		}
+
+		w.analyse(payload)
+	}
+}
+
+func (w *AnalysisWorker) analyse(payload queue.AnalysisPayload) {
+	ctx, cancel := context.WithTimeout(context.Background(), analysisTimeout)
+	defer cancel()
+
+	v, err := variant.Parse(payload.Variant)
+	var result *store.GameAnalysis
+	if err == nil {
+		result, err = analysis.Analyse(ctx, w.evaluator, v, payload.FEN, payload.Moves)
+	}
+	if err != nil {
+		log.Printf("Failed to analyse game %s: %v", payload.GameID, err)
+		if err := w.analysisStore.SetAnalysisStatus(context.Background(), payload.GameID, store.AnalysisFailed); err != nil {
+			log.Printf("Failed to mark analysis of game %s failed: %v", payload.GameID, err)
+		}
+		return
+	}
+
+	result.GameID = payload.GameID
+	if err := w.analysisStore.SaveGameAnalysis(context.Background(), result); err != nil {
+		log.Printf("Failed to store analysis of game %s: %v", payload.GameID, err)
+	}
+}
@@ -39,6 +39,13 @@ func (w *Worker) Start() {
             continue
         }
 
+		if payload.Truncate {
+			if err := w.gameStore.DeleteMovesAfter(context.Background(), payload.GameID, payload.MoveNumber); err != nil {
+				log.Printf("Worker takeback error: %v", err)
+			}
+			continue
+		}
+
 		if err := w.gameStore.InsertMove(context.Background(), payload); err != nil {
             log.Printf("Worker insert error: %v", err)
             // TODO: re-enqueue or handle failure
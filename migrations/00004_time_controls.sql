@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games
+    ADD COLUMN base_seconds INT NOT NULL DEFAULT 600,
+    ADD COLUMN increment_seconds INT NOT NULL DEFAULT 0;
+
+ALTER TABLE moves
+    ADD COLUMN white_time_ms BIGINT,
+    ADD COLUMN black_time_ms BIGINT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE moves
+    DROP COLUMN IF EXISTS white_time_ms,
+    DROP COLUMN IF EXISTS black_time_ms;
+
+ALTER TABLE games
+    DROP COLUMN IF EXISTS base_seconds,
+    DROP COLUMN IF EXISTS increment_seconds;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games DROP CONSTRAINT valid_status;
+ALTER TABLE games ADD CONSTRAINT valid_status CHECK (status IN ('in_progress', 'completed', 'abandoned', 'aborted'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE games SET status = 'abandoned' WHERE status = 'aborted';
+ALTER TABLE games DROP CONSTRAINT valid_status;
+ALTER TABLE games ADD CONSTRAINT valid_status CHECK (status IN ('in_progress', 'completed', 'abandoned'));
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS ratings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(20) NOT NULL,
+    rating DOUBLE PRECISION NOT NULL DEFAULT 1500,
+    deviation DOUBLE PRECISION NOT NULL DEFAULT 350,
+    volatility DOUBLE PRECISION NOT NULL DEFAULT 0.06,
+    games INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, category)
+);
+
+CREATE INDEX idx_ratings_category_rating ON ratings(category, rating DESC);
+
+CREATE TABLE IF NOT EXISTS rating_history (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(20) NOT NULL,
+    game_id UUID REFERENCES games(id) ON DELETE SET NULL,
+    rating DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_rating_history_user ON rating_history(user_id, category, created_at);
+
+ALTER TABLE games
+    ADD COLUMN rating_category VARCHAR(20),
+    ADD COLUMN white_rating_diff INT,
+    ADD COLUMN black_rating_diff INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games
+    DROP COLUMN IF EXISTS rating_category,
+    DROP COLUMN IF EXISTS white_rating_diff,
+    DROP COLUMN IF EXISTS black_rating_diff;
+
+DROP TABLE IF EXISTS rating_history;
+DROP TABLE IF EXISTS ratings;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games
+    ADD COLUMN series_id UUID,
+    ADD COLUMN rematch_of UUID REFERENCES games(id) ON DELETE SET NULL;
+
+UPDATE games SET series_id = id WHERE series_id IS NULL;
+
+CREATE INDEX idx_games_series ON games(series_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games
+    DROP COLUMN IF EXISTS rematch_of,
+    DROP COLUMN IF EXISTS series_id;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id SERIAL PRIMARY KEY,
+    game_id UUID NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    room VARCHAR(20) NOT NULL,
+    body TEXT NOT NULL,
+    blocked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_room CHECK (room IN ('player', 'spectator'))
+);
+
+CREATE INDEX idx_chat_messages_game_id ON chat_messages(game_id, created_at);
+CREATE INDEX idx_chat_messages_user_id ON chat_messages(user_id);
+
+CREATE TABLE IF NOT EXISTS chat_mutes (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS chat_mutes;
+DROP TABLE IF EXISTS chat_messages;
+-- +goose StatementEnd
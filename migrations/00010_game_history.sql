@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_games_white_started ON games(white_user_id, started_at DESC, id DESC);
+CREATE INDEX idx_games_black_started ON games(black_user_id, started_at DESC, id DESC);
+
+DROP INDEX IF EXISTS idx_games_white_user;
+DROP INDEX IF EXISTS idx_games_black_user;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE INDEX idx_games_white_user ON games(white_user_id);
+CREATE INDEX idx_games_black_user ON games(black_user_id);
+
+DROP INDEX IF EXISTS idx_games_black_started;
+DROP INDEX IF EXISTS idx_games_white_started;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games
+    ADD COLUMN ply_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN eco VARCHAR(3),
+    ADD COLUMN opening TEXT;
+
+UPDATE games SET ply_count = (SELECT COUNT(*) FROM moves WHERE moves.game_id = games.id);
+
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    stats JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_stats;
+
+ALTER TABLE games
+    DROP COLUMN IF EXISTS opening,
+    DROP COLUMN IF EXISTS eco,
+    DROP COLUMN IF EXISTS ply_count;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO users (id, email, display_name, provider, provider_id)
+SELECT
+    ('00000000-0000-0000-0000-' || lpad(level::text, 12, '0'))::uuid,
+    'computer-level-' || level || '@bots.local',
+    'Computer (level ' || level || ')',
+    'bot',
+    'level-' || level
+FROM generate_series(1, 8) AS level
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM users WHERE provider = 'bot';
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS game_analysis (
+    game_id UUID PRIMARY KEY REFERENCES games(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    white_acpl INT,
+    black_acpl INT,
+    white_accuracy REAL,
+    black_accuracy REAL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT valid_analysis_status CHECK (status IN ('pending', 'done', 'failed'))
+);
+
+CREATE TABLE IF NOT EXISTS move_analysis (
+    game_id UUID NOT NULL REFERENCES game_analysis(game_id) ON DELETE CASCADE,
+    ply INT NOT NULL,
+    color VARCHAR(5) NOT NULL,
+    move VARCHAR(10) NOT NULL,
+    eval_cp INT NOT NULL,
+    eval_mate INT NOT NULL DEFAULT 0,
+    best_move VARCHAR(10) NOT NULL,
+    cp_loss INT NOT NULL,
+    classification VARCHAR(20) NOT NULL,
+    PRIMARY KEY (game_id, ply),
+
+    CONSTRAINT valid_color CHECK (color IN ('white', 'black'))
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS move_analysis;
+DROP TABLE IF EXISTS game_analysis;
+-- +goose StatementEnd
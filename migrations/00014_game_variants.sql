@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games
+    ADD COLUMN variant VARCHAR(20) NOT NULL DEFAULT 'standard',
+    ADD COLUMN initial_fen TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games
+    DROP COLUMN IF EXISTS initial_fen,
+    DROP COLUMN IF EXISTS variant;
+-- +goose StatementEnd
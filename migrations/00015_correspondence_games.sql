@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games
+    ADD COLUMN days_per_move INT NOT NULL DEFAULT 0,
+    ADD COLUMN move_deadline TIMESTAMPTZ;
+
+CREATE INDEX idx_games_move_deadline ON games(move_deadline) WHERE status = 'in_progress';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_games_move_deadline;
+
+ALTER TABLE games
+    DROP COLUMN IF EXISTS move_deadline,
+    DROP COLUMN IF EXISTS days_per_move;
+-- +goose StatementEnd
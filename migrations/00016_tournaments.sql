@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tournaments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'created',
+    creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    time_control VARCHAR(20) NOT NULL,
+    rated BOOLEAN NOT NULL DEFAULT FALSE,
+    variant VARCHAR(20) NOT NULL DEFAULT 'standard',
+    rounds INT NOT NULL,
+    current_round INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT valid_tournament_format CHECK (format IN ('swiss')),
+    CONSTRAINT valid_tournament_status CHECK (status IN ('created', 'in_progress', 'finished'))
+);
+
+CREATE INDEX idx_tournaments_status ON tournaments(status, starts_at);
+
+CREATE TABLE IF NOT EXISTS tournament_players (
+    tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating REAL NOT NULL,
+    withdrawn BOOLEAN NOT NULL DEFAULT FALSE,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tournament_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS tournament_rounds (
+    tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    round INT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (tournament_id, round)
+);
+
+CREATE TABLE IF NOT EXISTS tournament_pairings (
+    tournament_id UUID NOT NULL,
+    round INT NOT NULL,
+    board INT NOT NULL,
+    white_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    black_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    game_id UUID REFERENCES games(id) ON DELETE SET NULL,
+    result VARCHAR(10) NOT NULL DEFAULT '*',
+    PRIMARY KEY (tournament_id, round, board),
+    FOREIGN KEY (tournament_id, round) REFERENCES tournament_rounds(tournament_id, round) ON DELETE CASCADE,
+
+    CONSTRAINT valid_pairing_result CHECK (result IN ('1-0', '0-1', '1/2-1/2', '*', 'bye'))
+);
+
+CREATE UNIQUE INDEX idx_tournament_pairings_game ON tournament_pairings(game_id);
+
+ALTER TABLE games
+    ADD COLUMN tournament_id UUID REFERENCES tournaments(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games
+    DROP COLUMN IF EXISTS tournament_id;
+
+DROP TABLE IF EXISTS tournament_pairings;
+DROP TABLE IF EXISTS tournament_rounds;
+DROP TABLE IF EXISTS tournament_players;
+DROP TABLE IF EXISTS tournaments;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tournaments
+    DROP CONSTRAINT valid_tournament_format,
+    ADD CONSTRAINT valid_tournament_format CHECK (format IN ('swiss', 'arena')),
+    ADD COLUMN duration_minutes INT NOT NULL DEFAULT 0;
+
+ALTER TABLE tournament_pairings
+    ADD COLUMN white_berserk BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN black_berserk BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE tournament_players
+    ADD COLUMN score REAL,
+    ADD COLUMN rank INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tournament_players
+    DROP COLUMN IF EXISTS rank,
+    DROP COLUMN IF EXISTS score;
+
+ALTER TABLE tournament_pairings
+    DROP COLUMN IF EXISTS black_berserk,
+    DROP COLUMN IF EXISTS white_berserk;
+
+DELETE FROM tournaments WHERE format = 'arena';
+
+ALTER TABLE tournaments
+    DROP COLUMN IF EXISTS duration_minutes,
+    DROP CONSTRAINT valid_tournament_format,
+    ADD CONSTRAINT valid_tournament_format CHECK (format IN ('swiss'));
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tournaments
+    DROP CONSTRAINT valid_tournament_format,
+    ADD CONSTRAINT valid_tournament_format
+        CHECK (format IN ('swiss', 'arena', 'round_robin', 'single_elimination', 'double_elimination')),
+    ADD COLUMN tiebreak VARCHAR(20) NOT NULL DEFAULT '';
+
+ALTER TABLE tournament_players
+    ADD COLUMN seed INT;
+
+ALTER TABLE tournament_pairings
+    ADD COLUMN game INT NOT NULL DEFAULT 1,
+    DROP CONSTRAINT tournament_pairings_pkey,
+    ADD PRIMARY KEY (tournament_id, round, board, game);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM tournaments WHERE format IN ('round_robin', 'single_elimination', 'double_elimination');
+
+ALTER TABLE tournament_pairings
+    DROP CONSTRAINT tournament_pairings_pkey,
+    DROP COLUMN IF EXISTS game,
+    ADD PRIMARY KEY (tournament_id, round, board);
+
+ALTER TABLE tournament_players
+    DROP COLUMN IF EXISTS seed;
+
+ALTER TABLE tournaments
+    DROP COLUMN IF EXISTS tiebreak,
+    DROP CONSTRAINT valid_tournament_format,
+    ADD CONSTRAINT valid_tournament_format CHECK (format IN ('swiss', 'arena'));
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS puzzles (
+    id VARCHAR(20) PRIMARY KEY,
+    fen TEXT NOT NULL,
+    moves TEXT NOT NULL,
+    rating DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL DEFAULT 75,
+    themes TEXT[] NOT NULL DEFAULT '{}',
+    plays INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_puzzles_rating ON puzzles(rating);
+CREATE INDEX idx_puzzles_themes ON puzzles USING GIN (themes);
+
+CREATE TABLE IF NOT EXISTS puzzle_attempts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    puzzle_id VARCHAR(20) NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    solved BOOLEAN NOT NULL,
+    rating_diff INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, puzzle_id)
+);
+
+CREATE INDEX idx_puzzle_attempts_user ON puzzle_attempts(user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS puzzle_attempts;
+DROP TABLE IF EXISTS puzzles;
+-- +goose StatementEnd
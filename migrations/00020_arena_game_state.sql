@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE games
+    ADD COLUMN arena BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN white_berserk BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN black_berserk BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE games
+    DROP COLUMN IF EXISTS black_berserk,
+    DROP COLUMN IF EXISTS white_berserk,
+    DROP COLUMN IF EXISTS arena;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE puzzle_attempts
+    ADD COLUMN finished BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE INDEX idx_puzzle_attempts_served ON puzzle_attempts(user_id) WHERE NOT finished;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM puzzle_attempts WHERE NOT finished;
+
+ALTER TABLE puzzle_attempts
+    DROP COLUMN IF EXISTS finished;
+-- +goose StatementEnd
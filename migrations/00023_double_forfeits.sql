@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tournament_pairings
+    DROP CONSTRAINT valid_pairing_result,
+    ADD CONSTRAINT valid_pairing_result CHECK (result IN ('1-0', '0-1', '1/2-1/2', '0-0', '*', 'bye'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE tournament_pairings SET result = '0-1' WHERE result = '0-0';
+
+ALTER TABLE tournament_pairings
+    DROP CONSTRAINT valid_pairing_result,
+    ADD CONSTRAINT valid_pairing_result CHECK (result IN ('1-0', '0-1', '1/2-1/2', '*', 'bye'));
+-- +goose StatementEnd